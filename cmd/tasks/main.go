@@ -16,7 +16,7 @@ func main() {
 
 	app := cli.NewApp()
 	if err := app.Run(); err != nil {
-		log.Printf("Error: %v\n", err)
-		os.Exit(1)
+		log.Printf("Error: %s\n", cli.ErrorMessage(err))
+		os.Exit(cli.ExitCode(err))
 	}
 }
@@ -1,26 +1,43 @@
 package cli
 
 import (
+	"context"
+	"errors"
 	"fmt"
 	"log"
 	"os"
+	"os/signal"
+	"os/user"
 	"path/filepath"
+	"syscall"
+	"time"
 
 	"github.com/ncfex/tasks/internal/config"
+	"github.com/ncfex/tasks/internal/storage/audit"
 	"github.com/ncfex/tasks/internal/storage/csv"
+	"github.com/ncfex/tasks/internal/storage/journal"
 	"github.com/ncfex/tasks/internal/storage/json"
 	"github.com/ncfex/tasks/internal/storage/sql"
+	"github.com/ncfex/tasks/internal/storage/sqlite"
 	"github.com/ncfex/tasks/internal/task"
 	"github.com/spf13/cobra"
 )
 
 type App struct {
-	rootCmd *cobra.Command
-	service task.TaskService
-	format  string
-	cfg     *config.Config
+	rootCmd     *cobra.Command
+	service     task.TaskService
+	repository  task.Repository
+	migrator    *sql.Migrator
+	storageDir  string
+	storagePath string
+	format      string
+	timeout     time.Duration
+	cfg         *config.Config
 }
 
+// defaultTimeout bounds each storage operation of a command.
+const defaultTimeout = 30 * time.Second
+
 func NewApp() *App {
 	app := &App{}
 	app.cfg = &config.Config{}
@@ -34,18 +51,33 @@ func NewApp() *App {
 		Use:   "tasks",
 		Short: "Simple CLI todo app",
 		Long:  "Simple CLI application for managing your todos",
+
+		SilenceErrors: true,
 		PersistentPreRunE: func(cmd *cobra.Command, args []string) error {
-			return app.initializeService()
+			app.prepareCommand(cmd)
+			return app.initializeService(cmd.Context(), !app.cfg.DisableAutoMigrate)
 		},
 	}
 
-	app.rootCmd.PersistentFlags().StringVarP(&app.format, "format", "m", string(app.cfg.ServiceMode), "Storage format (json or csv)")
+	app.rootCmd.PersistentFlags().StringVarP(&app.format, "format", "m", string(app.cfg.ServiceMode), "Storage format (json, csv, sql or sqlite)")
+	app.rootCmd.PersistentFlags().DurationVar(&app.timeout, "timeout", defaultTimeout, "Maximum time for each storage operation (0 for no limit); import, export and migrate are only limited when it is set")
 
 	app.setupCommands()
 	return app
 }
 
-func (a *App) initializeService() error {
+// storage is an opened storage backend.
+type storage struct {
+	path       string
+	repository task.Repository
+	journal    task.Journal
+	auditLog   task.AuditLog
+	migrator   *sql.Migrator
+}
+
+// openStorage opens the storage backend for format. SQL backends apply
+// pending schema migrations first when autoMigrate is set.
+func (a *App) openStorage(ctx context.Context, format string, autoMigrate bool) (*storage, error) {
 	homeDir, err := os.UserHomeDir()
 	if err != nil {
 		log.Fatalf("Failed to get user home directory: %v", err)
@@ -55,34 +87,139 @@ func (a *App) initializeService() error {
 	if err := os.MkdirAll(storageDir, 0755); err != nil {
 		log.Fatalf("Failed to create storage directory: %v", err)
 	}
+	a.storageDir = storageDir
 
-	var repository task.Repository
-	switch a.format {
+	st := &storage{}
+	switch format {
 	case "json":
-		storagePath := filepath.Join(storageDir, "tasks.json")
-		repository = json.NewRepository(storagePath)
+		st.path = filepath.Join(storageDir, "tasks.json")
+		st.repository = json.NewRepository(st.path)
+		st.journal = journal.NewJournal(filepath.Join(storageDir, "journal", "json.json"))
+		st.auditLog = audit.NewAuditLog(filepath.Join(storageDir, "history", "json.jsonl"))
 	case "csv":
-		storagePath := filepath.Join(storageDir, "tasks.csv")
-		repository = csv.NewRepository(storagePath)
+		st.path = filepath.Join(storageDir, "tasks.csv")
+		var opts []csv.Option
+		if a.cfg.LenientCSV {
+			opts = append(opts, csv.WithLenientParsing())
+		}
+		st.repository = csv.NewRepository(st.path, opts...)
+		st.journal = journal.NewJournal(filepath.Join(storageDir, "journal", "csv.json"))
+		st.auditLog = audit.NewAuditLog(filepath.Join(storageDir, "history", "csv.jsonl"))
 	case "sql":
 		dbURL := os.Getenv("DB_URL")
 		if dbURL == "" {
 			log.Fatalf("Failed to get DB string")
 		}
-		repository, err = sql.NewRepository(dbURL)
+		db, err := sql.Connect(dbURL)
+		if err != nil {
+			return nil, fmt.Errorf("%w", err)
+		}
+		st.migrator, err = sql.NewMigrator(db)
+		if err != nil {
+			return nil, fmt.Errorf("%w", err)
+		}
+		st.repository = sql.NewRepositoryWithDB(db)
+		st.journal = sql.NewJournal(db, sql.DialectPostgres, currentUser())
+		st.auditLog = sql.NewAuditLog(db)
+	case "sqlite":
+		st.path = filepath.Join(storageDir, "tasks.db")
+		db, err := sqlite.Connect(st.path)
 		if err != nil {
-			return fmt.Errorf("%w", err)
+			return nil, fmt.Errorf("%w", err)
 		}
+		st.migrator, err = sqlite.NewMigrator(db)
+		if err != nil {
+			return nil, fmt.Errorf("%w", err)
+		}
+		st.repository = sql.NewRepositoryWithDB(db)
+		st.journal = sql.NewJournal(db, sql.DialectSQLite, currentUser())
+		st.auditLog = sql.NewAuditLog(db)
 	default:
-		return fmt.Errorf("unsupported format: %s", a.format)
+		return nil, fmt.Errorf("unsupported format: %s", format)
+	}
+
+	if st.migrator != nil && autoMigrate {
+		ctx, cancel := a.withTimeout(ctx)
+		defer cancel()
+		if _, err := st.migrator.Migrate(ctx); err != nil {
+			return nil, fmt.Errorf("failed to migrate database: %w", err)
+		}
+	}
+
+	return st, nil
+}
+
+// initializeService wires the storage backend selected by --format.
+func (a *App) initializeService(ctx context.Context, autoMigrate bool) error {
+	st, err := a.openStorage(ctx, a.format, autoMigrate)
+	if err != nil {
+		return err
 	}
+	a.storagePath = st.path
+	a.migrator = st.migrator
+	a.repository = st.repository
 
-	a.service = task.NewService(repository)
+	opts := []task.ServiceOption{
+		task.WithJournal(st.journal),
+		task.WithAuditLog(st.auditLog, currentUser()),
+		task.WithTimeout(a.timeout),
+	}
+	if len(a.cfg.StatusTransitions) > 0 {
+		opts = append(opts, task.WithTransitions(a.cfg.StatusTransitions))
+	}
+
+	a.service = task.NewService(st.repository, opts...)
 	return nil
 }
 
+func currentUser() string {
+	if u, err := user.Current(); err == nil && u.Username != "" {
+		return u.Username
+	}
+	if name := os.Getenv("USER"); name != "" {
+		return name
+	}
+	return "unknown"
+}
+
+// prepareCommand runs once arguments have been validated, so later errors
+// are reported without the usage text.
+func (a *App) prepareCommand(cmd *cobra.Command) {
+	cmd.SilenceUsage = true
+}
+
+// withTimeout limits ctx to --timeout for a single storage operation.
+func (a *App) withTimeout(ctx context.Context) (context.Context, context.CancelFunc) {
+	if a.timeout <= 0 {
+		return ctx, func() {}
+	}
+	return context.WithTimeout(ctx, a.timeout)
+}
+
+// withStreamTimeout is withTimeout for commands that copy every task, such
+// as import and export. Those can take far longer than the default, so they
+// are only limited when --timeout is given.
+func (a *App) withStreamTimeout(ctx context.Context) (context.Context, context.CancelFunc) {
+	if !a.rootCmd.PersistentFlags().Changed("timeout") {
+		return ctx, func() {}
+	}
+	return a.withTimeout(ctx)
+}
+
+// Run executes the command line. Interrupting the process cancels the
+// context passed to the storage backends.
 func (a *App) Run() error {
-	return a.rootCmd.Execute()
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	err := a.rootCmd.ExecuteContext(ctx)
+	switch {
+	case errors.Is(err, context.DeadlineExceeded):
+		return fmt.Errorf("timed out after %s (see --timeout): %w", a.timeout, err)
+	case errors.Is(err, context.Canceled):
+		return fmt.Errorf("interrupted: %w", err)
+	}
+	return err
 }
 
 func (a *App) setupCommands() {
@@ -90,7 +227,18 @@ func (a *App) setupCommands() {
 		newAddCommand(a),
 		newListCommand(a),
 		newCompleteCommand(a),
+		newStatusCommand(a),
 		newDeleteCommand(a),
+		newTrashCommand(a),
+		newUndoCommand(a),
+		newRedoCommand(a),
+		newHistoryCommand(a),
+		newDBCommand(a),
+		newDoctorCommand(a),
+		newMigrateCommand(a),
+		newImportCommand(a),
+		newExportCommand(a),
 		newUpdateServiceModeCommand(a),
+		newReportCommand(a),
 	)
 }
@@ -0,0 +1,160 @@
+package cli
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/ncfex/tasks/internal/task"
+	"github.com/ncfex/tasks/internal/utils"
+	"github.com/spf13/cobra"
+)
+
+// defaultConfirmThreshold is how many tasks a bulk command may change
+// without asking first.
+const defaultConfirmThreshold = 5
+
+// bulkFlags selects the tasks of a command that accepts several of them.
+type bulkFlags struct {
+	where []string
+	yes   bool
+}
+
+func (f *bulkFlags) register(cmd *cobra.Command) {
+	cmd.Flags().StringArrayVarP(&f.where, "where", "w", nil, "Select tasks by condition (status=todo,waiting, description~text, due<tomorrow, due>\"2 days ago\")")
+	cmd.Flags().BoolVarP(&f.yes, "yes", "y", false, "Change many tasks without asking for confirmation")
+}
+
+// isBulk reports whether the command should go through Batch rather than
+// the single-task path.
+func (f *bulkFlags) isBulk(refs []string) bool {
+	if len(refs) != 1 || len(f.where) > 0 {
+		return true
+	}
+	_, _, isRange := task.ParseNumRange(refs[0])
+	return isRange
+}
+
+// runBulk applies action to the tasks named by refs and --where, asking for
+// confirmation when there are more than the configured threshold.
+func (a *App) runBulk(cmd *cobra.Command, refs []string, flags *bulkFlags, action task.BatchAction, verb string) error {
+	filter, err := parseWhere(flags.where)
+	if err != nil {
+		return err
+	}
+	if len(refs) == 0 && filter == nil {
+		return fmt.Errorf("no tasks given: pass task IDs, numbers or --where")
+	}
+
+	tasks, err := a.service.Select(cmd.Context(), refs, filter)
+	if err != nil {
+		return fmt.Errorf("failed to select tasks: %w", err)
+	}
+	if len(tasks) == 0 {
+		fmt.Println("No tasks matched.")
+		return nil
+	}
+
+	threshold := a.cfg.ConfirmThreshold
+	if threshold <= 0 {
+		threshold = defaultConfirmThreshold
+	}
+	if len(tasks) > threshold && !flags.yes {
+		if !isTerminal(os.Stdin) {
+			return fmt.Errorf("refusing to %s %d tasks without confirmation: pass --yes", verb, len(tasks))
+		}
+		if !confirmBulk(os.Stdin, os.Stderr, verb, tasks) {
+			fmt.Println("Aborted.")
+			return nil
+		}
+	}
+
+	ids := make([]uuid.UUID, len(tasks))
+	for i := range tasks {
+		ids[i] = tasks[i].ID
+	}
+
+	var result *task.BatchResult
+	err = withConflictRetry(func() error {
+		var err error
+		result, err = a.service.Batch(cmd.Context(), ids, action)
+		return err
+	})
+	if err != nil {
+		return fmt.Errorf("failed to %s tasks: %w", verb, err)
+	}
+
+	for _, t := range result.Changed {
+		fmt.Printf("%s  %s  %s\n", task.FormatNum(t.Num), t.ID.String()[0:8], t.Description)
+	}
+	fmt.Printf("%d task(s) updated\n", len(result.Changed))
+	if len(result.Unchanged) > 0 {
+		fmt.Printf("%d task(s) already %s, left unchanged\n", len(result.Unchanged), action.Status)
+	}
+	return nil
+}
+
+func confirmBulk(in io.Reader, out io.Writer, verb string, tasks []task.Task) bool {
+	for _, t := range tasks {
+		fmt.Fprintf(out, "  %s  %s  %s\n", task.FormatNum(t.Num), t.ID.String()[0:8], t.Description)
+	}
+	return confirm(in, out, fmt.Sprintf("%s %d tasks?", strings.ToUpper(verb[:1])+verb[1:], len(tasks)))
+}
+
+// parseWhere turns --where conditions into a filter. Conditions are
+// combined with AND; nil means no conditions were given.
+func parseWhere(conditions []string) (*task.TaskFilter, error) {
+	if len(conditions) == 0 {
+		return nil, nil
+	}
+
+	filter := task.NewTaskFilter()
+	for _, condition := range conditions {
+		i := strings.IndexAny(condition, "=~<>")
+		if i <= 0 {
+			return nil, fmt.Errorf("invalid condition %q: expected <field><op><value>", condition)
+		}
+		field, op, value := strings.TrimSpace(condition[:i]), condition[i], strings.TrimSpace(condition[i+1:])
+
+		switch {
+		case field == "status" && op == '=':
+			for _, name := range strings.Split(value, ",") {
+				status, err := task.ParseStatus(name)
+				if err != nil {
+					return nil, err
+				}
+				filter.Statuses = append(filter.Statuses, status)
+			}
+		case field == "description" && op == '~':
+			filter.Search = value
+		case field == "due" && (op == '<' || op == '>'):
+			due, err := parseWhereTime(value)
+			if err != nil {
+				return nil, fmt.Errorf("invalid condition %q: %w", condition, err)
+			}
+			if op == '<' {
+				filter.DueBefore = due
+			} else {
+				filter.DueAfter = due
+			}
+		default:
+			return nil, fmt.Errorf("unsupported condition %q", condition)
+		}
+	}
+
+	return filter, nil
+}
+
+func parseWhereTime(value string) (time.Time, error) {
+	if strings.ToLower(value) == "now" {
+		return time.Now(), nil
+	}
+
+	if t, err := time.ParseInLocation("2006-01-02", value, time.Local); err == nil {
+		return t, nil
+	}
+	return utils.ParseHumanToTime(value)
+}
@@ -1,10 +1,14 @@
 package cli
 
 import (
+	"context"
 	"fmt"
+	"io"
 	"os"
+	"sort"
 	"strings"
 	"text/tabwriter"
+	"time"
 
 	"github.com/ncfex/tasks/internal/config"
 	"github.com/ncfex/tasks/internal/task"
@@ -26,6 +30,13 @@ var columns = map[string]Column{
 			return t.ID.String()[0:8]
 		},
 	},
+	string(task.TaskFieldNum): {
+		Header: strings.ToUpper(string(task.TaskFieldNum)),
+		Field:  task.TaskFieldNum,
+		Formatter: func(t task.Task) string {
+			return task.FormatNum(t.Num)
+		},
+	},
 	string(task.TaskFieldDescription): {
 		Header: strings.ToUpper(string(task.TaskFieldDescription)),
 		Field:  task.TaskFieldDescription,
@@ -37,12 +48,19 @@ var columns = map[string]Column{
 		Header: strings.ToUpper(string(task.TaskFieldIsCompleted)),
 		Field:  task.TaskFieldIsCompleted,
 		Formatter: func(t task.Task) string {
-			if t.IsCompleted {
+			if t.IsCompleted() {
 				return "OK"
 			}
 			return "-"
 		},
 	},
+	string(task.TaskFieldStatus): {
+		Header: strings.ToUpper(string(task.TaskFieldStatus)),
+		Field:  task.TaskFieldStatus,
+		Formatter: func(t task.Task) string {
+			return string(t.Status)
+		},
+	},
 	string(task.TaskFieldCreatedAt): {
 		Header: strings.ToUpper(string(task.TaskFieldCreatedAt)),
 		Field:  task.TaskFieldCreatedAt,
@@ -50,6 +68,13 @@ var columns = map[string]Column{
 			return utils.FormatTimeToHuman(t.CreatedAt)
 		},
 	},
+	string(task.TaskFieldUpdatedAt): {
+		Header: strings.ToUpper(string(task.TaskFieldUpdatedAt)),
+		Field:  task.TaskFieldUpdatedAt,
+		Formatter: func(t task.Task) string {
+			return utils.FormatTimeToHuman(t.UpdatedAt)
+		},
+	},
 	string(task.TaskFieldDueDate): {
 		Header: strings.ToUpper(string(task.TaskFieldDueDate)),
 		Field:  task.TaskFieldDueDate,
@@ -57,34 +82,82 @@ var columns = map[string]Column{
 			return utils.FormatTimeToHuman(t.DueDate)
 		},
 	},
+	string(task.TaskFieldEstimate): {
+		Header: strings.ToUpper(string(task.TaskFieldEstimate)),
+		Field:  task.TaskFieldEstimate,
+		Formatter: func(t task.Task) string {
+			return utils.FormatDuration(t.Estimate)
+		},
+	},
+	string(task.TaskFieldPriority): {
+		Header: strings.ToUpper(string(task.TaskFieldPriority)),
+		Field:  task.TaskFieldPriority,
+		Formatter: func(t task.Task) string {
+			return orDash(t.Priority)
+		},
+	},
+	string(task.TaskFieldProject): {
+		Header: strings.ToUpper(string(task.TaskFieldProject)),
+		Field:  task.TaskFieldProject,
+		Formatter: func(t task.Task) string {
+			return orDash(t.Project)
+		},
+	},
+	string(task.TaskFieldTags): {
+		Header: strings.ToUpper(string(task.TaskFieldTags)),
+		Field:  task.TaskFieldTags,
+		Formatter: func(t task.Task) string {
+			return orDash(strings.Join(t.Tags, ","))
+		},
+	},
+	string(task.TaskFieldDeletedAt): {
+		Header: strings.ToUpper(string(task.TaskFieldDeletedAt)),
+		Field:  task.TaskFieldDeletedAt,
+		Formatter: func(t task.Task) string {
+			if !t.IsDeleted() {
+				return "-"
+			}
+			return utils.FormatTimeToHuman(t.DeletedAt)
+		},
+	},
+}
+
+func orDash(s string) string {
+	if s == "" {
+		return "-"
+	}
+	return s
 }
 
 var defaultColumns = []task.TaskField{
+	task.TaskFieldNum,
 	task.TaskFieldID,
 	task.TaskFieldDescription,
 	task.TaskFieldCreatedAt,
 	task.TaskFieldDueDate,
-	task.TaskFieldIsCompleted,
+	task.TaskFieldStatus,
 }
 
 func newAddCommand(a *App) *cobra.Command {
 	var dueDateString string
+	var estimateString string
 
 	cmd := &cobra.Command{
 		Use:   "add [description]",
 		Short: "Add a new task",
 		Args:  cobra.MinimumNArgs(1),
 		RunE: func(cmd *cobra.Command, args []string) error {
-			return runAdd(a.service, args[0], dueDateString)
+			return runAdd(cmd.Context(), a.service, args[0], dueDateString, estimateString)
 		},
 	}
 
 	cmd.Flags().StringVarP(&dueDateString, "due", "d", "", "Due date for the task")
+	cmd.Flags().StringVarP(&estimateString, "estimate", "e", "", "Estimated effort for the task (e.g. 30m, 2h, 1d)")
 
 	return cmd
 }
 
-func runAdd(service task.TaskService, description string, dueDate string) error {
+func runAdd(ctx context.Context, service task.TaskService, description string, dueDate string, estimate string) error {
 	if dueDate == "" {
 		dueDate = "tomorrow"
 	}
@@ -94,12 +167,20 @@ func runAdd(service task.TaskService, description string, dueDate string) error
 		return fmt.Errorf("failed to create parse date: %w", err)
 	}
 
-	task, err := service.Create(description, dueDateTime)
+	var estimateDuration time.Duration
+	if estimate != "" {
+		estimateDuration, err = utils.ParseDuration(estimate)
+		if err != nil {
+			return fmt.Errorf("failed to parse estimate: %w", err)
+		}
+	}
+
+	created, err := service.Create(ctx, description, dueDateTime, estimateDuration)
 	if err != nil {
 		return fmt.Errorf("failed to create task: %w", err)
 	}
 
-	fmt.Printf("Task created with ID: %s\n", task.ID.String()[0:8])
+	fmt.Printf("Task %s created with ID: %s\n", task.FormatNum(created.Num), created.ID.String()[0:8])
 	return nil
 }
 
@@ -107,6 +188,9 @@ func newListCommand(a *App) *cobra.Command {
 	var showAll bool
 	var selectedColumns []string
 	var saveColumns bool
+	var statusNames []string
+	var groupBy string
+	var changedSince string
 
 	cmd := &cobra.Command{
 		Use:   "list",
@@ -139,7 +223,30 @@ func newListCommand(a *App) *cobra.Command {
 				}
 			}
 
-			return runList(a.service, showAll, columnsToUse)
+			statuses := make([]task.Status, 0, len(statusNames))
+			for _, name := range statusNames {
+				status, err := task.ParseStatus(name)
+				if err != nil {
+					return err
+				}
+				statuses = append(statuses, status)
+			}
+
+			if groupBy != "" && groupBy != string(task.TaskFieldStatus) {
+				return fmt.Errorf("unsupported group-by field: %s. Must be: status", groupBy)
+			}
+
+			filter := &task.TaskFilter{IncludeCompleted: showAll, Statuses: statuses}
+			if changedSince != "" {
+				since, err := utils.ParseHumanToTime(changedSince)
+				if err != nil {
+					return fmt.Errorf("failed to parse --changed-since: %w", err)
+				}
+				// Recently completed tasks are part of recent activity.
+				filter.ChangedSince = since
+				filter.IncludeCompleted = true
+			}
+			return runList(cmd.Context(), a.service, filter, columnsToUse, groupBy != "", a.cfg.ShortIDLength)
 		},
 	}
 
@@ -151,11 +258,14 @@ func newListCommand(a *App) *cobra.Command {
 	cmd.Flags().BoolVarP(&showAll, "all", "a", false, "Show all tasks (including completed)")
 	cmd.Flags().StringSliceVarP(&selectedColumns, "columns", "c", displayColumnsString, "Columns to display")
 	cmd.Flags().BoolVarP(&saveColumns, "save", "s", false, "Save selected columns to config")
+	cmd.Flags().StringSliceVarP(&statusNames, "status", "S", nil, "Only show tasks with these statuses")
+	cmd.Flags().StringVarP(&groupBy, "group-by", "g", "", "Group tasks by field (status)")
+	cmd.Flags().StringVar(&changedSince, "changed-since", "", "Only show tasks changed since a time (e.g. \"2 days ago\"), most recent first")
 
 	return cmd
 }
 
-func runList(service task.TaskService, showAll bool, selectedColumns []string) error {
+func runList(ctx context.Context, service task.TaskService, filter *task.TaskFilter, selectedColumns []string, groupByStatus bool, shortIDLength int) error {
 	displayColumns := make([]Column, 0, len(selectedColumns))
 	selectedFields := make([]task.TaskField, 0, len(selectedColumns))
 
@@ -179,9 +289,8 @@ func runList(service task.TaskService, showAll bool, selectedColumns []string) e
 	}
 
 	selector := task.NewTaskSelector(selectedFields...)
-	filter := &task.TaskFilter{IncludeCompleted: showAll}
 
-	tasks, err := service.List(selector, filter)
+	tasks, err := service.List(ctx, selector, filter)
 	if err != nil {
 		return fmt.Errorf("failed to list tasks: %w", err)
 	}
@@ -191,9 +300,70 @@ func runList(service task.TaskService, showAll bool, selectedColumns []string) e
 		return nil
 	}
 
+	if !filter.ChangedSince.IsZero() {
+		sort.SliceStable(tasks, func(i, j int) bool {
+			return tasks[i].UpdatedAt.After(tasks[j].UpdatedAt)
+		})
+	}
+
+	if selector.Fields[task.TaskFieldID] {
+		if err := useShortIDs(ctx, service, displayColumns, shortIDLength); err != nil {
+			return err
+		}
+	}
+
 	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', tabwriter.TabIndent)
 	defer w.Flush()
 
+	if !groupByStatus {
+		writeTable(w, displayColumns, tasks)
+		return nil
+	}
+
+	groups := make(map[task.Status][]task.Task)
+	for _, t := range tasks {
+		groups[t.Status] = append(groups[t.Status], t)
+	}
+
+	first := true
+	for _, status := range task.Statuses {
+		if len(groups[status]) == 0 {
+			continue
+		}
+		if !first {
+			fmt.Fprintln(w)
+		}
+		first = false
+
+		fmt.Fprintf(w, "%s (%d)\n", strings.ToUpper(string(status)), len(groups[status]))
+		writeTable(w, displayColumns, groups[status])
+	}
+
+	return nil
+}
+
+// useShortIDs makes the ID column show the shortest prefix that is unique
+// across every stored task, including completed and deleted ones, so that
+// any ID shown can be passed back to a command.
+func useShortIDs(ctx context.Context, service task.TaskService, displayColumns []Column, minLength int) error {
+	all, err := service.List(ctx, task.NewTaskSelector(), &task.TaskFilter{IncludeCompleted: true, Trash: task.TrashInclude})
+	if err != nil {
+		return fmt.Errorf("failed to list tasks: %w", err)
+	}
+
+	prefixes := task.ShortIDs(all, minLength)
+	for i := range displayColumns {
+		if displayColumns[i].Field == task.TaskFieldID {
+			displayColumns[i].Formatter = func(t task.Task) string {
+				return prefixes[t.ID]
+			}
+		}
+	}
+
+	return nil
+}
+
+func writeTable(w io.Writer, displayColumns []Column, tasks []task.Task) {
 	headers := make([]string, 0, len(displayColumns))
 	for _, col := range displayColumns {
 		headers = append(headers, col.Header)
@@ -208,39 +378,148 @@ func runList(service task.TaskService, showAll bool, selectedColumns []string) e
 		}
 		fmt.Fprintln(w, strings.Join(row, "\t"))
 	}
-
-	return nil
 }
 
 func newCompleteCommand(a *App) *cobra.Command {
+	var bulk bulkFlags
+
+	cmd := &cobra.Command{
+		Use:   "complete [task_id...]",
+		Short: "Mark tasks as completed",
+		Args:  cobra.ArbitraryArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if bulk.isBulk(args) {
+				return a.runBulk(cmd, args, &bulk, task.BatchAction{Status: task.StatusDone}, "complete")
+			}
+
+			return withTaskID(args[0], func(idString string) error {
+				var changed bool
+				err := withConflictRetry(func() error {
+					var err error
+					_, changed, err = a.service.Complete(cmd.Context(), idString)
+					return err
+				})
+				if err != nil {
+					return fmt.Errorf("failed to complete task: %w", err)
+				}
+
+				if !changed {
+					fmt.Printf("Task %s is already done\n", idString)
+					return nil
+				}
+				fmt.Printf("Task %s marked as completed\n", idString)
+				return nil
+			})
+		},
+	}
+
+	bulk.register(cmd)
+	return cmd
+}
+
+func newStatusCommand(a *App) *cobra.Command {
+	var bulk bulkFlags
+
+	cmd := &cobra.Command{
+		Use:   "status [task_id...] [status]",
+		Short: "Move tasks to another status",
+		Long:  "Move tasks to another status (todo, in_progress, waiting, done or cancelled)",
+		Args:  cobra.MinimumNArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			refs := args[:len(args)-1]
+			status, err := task.ParseStatus(args[len(args)-1])
+			if err != nil {
+				return err
+			}
+
+			if bulk.isBulk(refs) {
+				return a.runBulk(cmd, refs, &bulk, task.BatchAction{Status: status}, "update")
+			}
+
+			return withTaskID(refs[0], func(idString string) error {
+				var changed bool
+				err := withConflictRetry(func() error {
+					var err error
+					_, changed, err = a.service.SetStatus(cmd.Context(), idString, status)
+					return err
+				})
+				if err != nil {
+					return fmt.Errorf("failed to update task status: %w", err)
+				}
+
+				if !changed {
+					fmt.Printf("Task %s is already %s\n", idString, status)
+					return nil
+				}
+				fmt.Printf("Task %s moved to %s\n", idString, status)
+				return nil
+			})
+		},
+	}
+
+	bulk.register(cmd)
+	return cmd
+}
+
+func newDeleteCommand(a *App) *cobra.Command {
+	var bulk bulkFlags
+
+	cmd := &cobra.Command{
+		Use:   "delete [task_id...]",
+		Short: "Move tasks to the trash",
+		Args:  cobra.ArbitraryArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if bulk.isBulk(args) {
+				return a.runBulk(cmd, args, &bulk, task.BatchAction{Delete: true}, "delete")
+			}
+
+			return withTaskID(args[0], func(idString string) error {
+				err := withConflictRetry(func() error {
+					return a.service.Delete(cmd.Context(), idString)
+				})
+				if err != nil {
+					return fmt.Errorf("failed to delete task: %w", err)
+				}
+
+				fmt.Printf("Task %s moved to trash\n", idString)
+				return nil
+			})
+		},
+	}
+
+	bulk.register(cmd)
+	return cmd
+}
+
+func newUndoCommand(a *App) *cobra.Command {
 	return &cobra.Command{
-		Use:   "complete [task_id]",
-		Short: "Mark a task as completed",
-		Args:  cobra.ExactArgs(1),
+		Use:   "undo",
+		Short: "Undo the last change",
+		Args:  cobra.NoArgs,
 		RunE: func(cmd *cobra.Command, args []string) error {
-			idString := args[0]
-			if err := a.service.Complete(idString); err != nil {
-				return fmt.Errorf("failed to complete task: %w", err)
+			entry, err := a.service.Undo(cmd.Context())
+			if err != nil {
+				return fmt.Errorf("failed to undo: %w", err)
 			}
 
-			fmt.Printf("Task %s marked as completed\n", idString)
+			fmt.Printf("Undid: %s\n", entry.Summary)
 			return nil
 		},
 	}
 }
 
-func newDeleteCommand(a *App) *cobra.Command {
+func newRedoCommand(a *App) *cobra.Command {
 	return &cobra.Command{
-		Use:   "delete [task_id]",
-		Short: "Delete a task from TODOs",
-		Args:  cobra.ExactArgs(1),
+		Use:   "redo",
+		Short: "Redo the last undone change",
+		Args:  cobra.NoArgs,
 		RunE: func(cmd *cobra.Command, args []string) error {
-			idString := args[0]
-			if err := a.service.Delete(idString); err != nil {
-				return fmt.Errorf("failed to delete task: %w", err)
+			entry, err := a.service.Redo(cmd.Context())
+			if err != nil {
+				return fmt.Errorf("failed to redo: %w", err)
 			}
 
-			fmt.Printf("Task %s is deleted\n", idString)
+			fmt.Printf("Redid: %s\n", entry.Summary)
 			return nil
 		},
 	}
@@ -250,14 +529,14 @@ func newUpdateServiceModeCommand(a *App) *cobra.Command {
 	cmd := &cobra.Command{
 		Use:   "set-mode [mode]",
 		Short: "Update default storage mode",
-		Long:  "Update the default storage mode (sql, json, or csv) for the tasks application",
+		Long:  "Update the default storage mode (sql, sqlite, json, or csv) for the tasks application",
 		Args:  cobra.ExactArgs(1),
 		RunE: func(cmd *cobra.Command, args []string) error {
 			mode := config.ServiceMode(args[0])
 			switch mode {
-			case config.ServiceModeSQL, config.ServiceModeJSON, config.ServiceModeCSV:
+			case config.ServiceModeSQL, config.ServiceModeSQLite, config.ServiceModeJSON, config.ServiceModeCSV:
 			default:
-				return fmt.Errorf("invalid mode: %s. Must be one of: sql, json, csv", mode)
+				return fmt.Errorf("invalid mode: %s. Must be one of: sql, sqlite, json, csv", mode)
 			}
 
 			if err := a.cfg.UpdateServiceMode(mode); err != nil {
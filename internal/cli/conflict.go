@@ -0,0 +1,50 @@
+package cli
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"github.com/ncfex/tasks/internal/task"
+)
+
+// withConflictRetry calls fn, and if it fails because a task was changed by
+// someone else in the meantime, shows what was changed. When stdin is a
+// terminal, the user can then have fn run again against the current
+// version of the task.
+func withConflictRetry(fn func() error) error {
+	for {
+		err := fn()
+
+		var conflict *task.VersionConflictError
+		if !errors.As(err, &conflict) {
+			return err
+		}
+
+		printConflict(os.Stderr, conflict)
+		if !isTerminal(os.Stdin) || !confirm(os.Stdin, os.Stderr, "Retry with the current version?") {
+			return err
+		}
+	}
+}
+
+// printConflict lists the fields where the stored task differs from the
+// change that was about to be written.
+func printConflict(out io.Writer, conflict *task.VersionConflictError) {
+	fmt.Fprintf(out, "Task %s was changed by someone else while this change was being made:\n", conflict.Current.ID.String()[0:8])
+	for _, diff := range task.Diff(&conflict.Attempted, &conflict.Current) {
+		fmt.Fprintf(out, "  %s: %s (yours), %s (current)\n", diff.Field, formatDiffValue(diff.Before), formatDiffValue(diff.After))
+	}
+}
+
+// confirm asks a yes/no question, defaulting to no.
+func confirm(in io.Reader, out io.Writer, question string) bool {
+	fmt.Fprintf(out, "%s [y/N]: ", question)
+
+	line, _ := bufio.NewReader(in).ReadString('\n')
+	answer := strings.ToLower(strings.TrimSpace(line))
+	return answer == "y" || answer == "yes"
+}
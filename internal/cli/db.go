@@ -0,0 +1,116 @@
+package cli
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"text/tabwriter"
+
+	"github.com/ncfex/tasks/internal/storage/sql"
+	"github.com/spf13/cobra"
+)
+
+func newDBCommand(a *App) *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "db",
+		Short: "Manage the database schema of the sql and sqlite backends",
+		PersistentPreRunE: func(cmd *cobra.Command, args []string) error {
+			a.prepareCommand(cmd)
+			if err := a.initializeService(cmd.Context(), false); err != nil {
+				return err
+			}
+			if a.migrator == nil {
+				return fmt.Errorf("db commands require the sql or sqlite storage format, got: %s", a.format)
+			}
+			return nil
+		},
+	}
+
+	cmd.AddCommand(
+		newDBMigrateCommand(a),
+		newDBStatusCommand(a),
+		newDBRollbackCommand(a),
+	)
+
+	return cmd
+}
+
+func newDBMigrateCommand(a *App) *cobra.Command {
+	return &cobra.Command{
+		Use:   "migrate",
+		Short: "Apply pending schema migrations",
+		Args:  cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			ctx, cancel := a.withTimeout(cmd.Context())
+			defer cancel()
+
+			applied, err := a.migrator.Migrate(ctx)
+			for _, m := range applied {
+				fmt.Printf("Applied %03d_%s\n", m.Version, m.Name)
+			}
+			if err != nil {
+				return fmt.Errorf("failed to migrate: %w", err)
+			}
+
+			if len(applied) == 0 {
+				fmt.Println("Database is up to date.")
+			}
+			return nil
+		},
+	}
+}
+
+func newDBStatusCommand(a *App) *cobra.Command {
+	return &cobra.Command{
+		Use:   "status",
+		Short: "Show applied and pending schema migrations",
+		Args:  cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			ctx, cancel := a.withTimeout(cmd.Context())
+			defer cancel()
+
+			statuses, err := a.migrator.Status(ctx)
+			if err != nil {
+				return fmt.Errorf("failed to get migration status: %w", err)
+			}
+
+			w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', tabwriter.TabIndent)
+			defer w.Flush()
+
+			fmt.Fprintln(w, "VERSION\tNAME\tAPPLIED AT")
+			for _, s := range statuses {
+				appliedAt := "pending"
+				if s.Applied {
+					appliedAt = s.AppliedAt.Local().Format("2006-01-02 15:04:05")
+				}
+				fmt.Fprintf(w, "%03d\t%s\t%s\n", s.Version, s.Name, appliedAt)
+			}
+
+			return nil
+		},
+	}
+}
+
+func newDBRollbackCommand(a *App) *cobra.Command {
+	return &cobra.Command{
+		Use:   "rollback",
+		Short: "Roll back the most recently applied schema migration",
+		Args:  cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			ctx, cancel := a.withTimeout(cmd.Context())
+			defer cancel()
+
+			m, err := a.migrator.Rollback(ctx)
+			if errors.Is(err, sql.ErrNoMigrationsApplied) {
+				fmt.Println("No migrations to roll back.")
+				return nil
+			}
+			if err != nil {
+				return fmt.Errorf("failed to roll back: %w", err)
+			}
+
+			fmt.Printf("Rolled back %03d_%s\n", m.Version, m.Name)
+			return nil
+		},
+	}
+}
@@ -0,0 +1,222 @@
+package cli
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/ncfex/tasks/internal/storage/csv"
+	"github.com/ncfex/tasks/internal/storage/fileformat"
+	jsonstore "github.com/ncfex/tasks/internal/storage/json"
+	"github.com/ncfex/tasks/internal/task"
+	"github.com/spf13/cobra"
+)
+
+func newDoctorCommand(a *App) *cobra.Command {
+	var fix bool
+
+	cmd := &cobra.Command{
+		Use:   "doctor",
+		Short: "Check the task storage for problems",
+		Long: `Check the task storage for records that cannot be parsed, duplicate IDs,
+empty descriptions, invalid statuses and missing dates. With --fix, a backup
+is taken first and every problem that can be repaired safely is repaired.`,
+		Args: cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			problems, err := runDoctor(cmd.Context(), a, fix)
+			if err != nil {
+				return err
+			}
+			if problems > 0 {
+				return fmt.Errorf("found %d problem(s)", problems)
+			}
+
+			return nil
+		},
+	}
+
+	cmd.Flags().BoolVar(&fix, "fix", false, "Repair problems after backing up the storage")
+
+	return cmd
+}
+
+// runDoctor prints every problem it finds and returns how many are left
+// unrepaired.
+func runDoctor(ctx context.Context, a *App, fix bool) (int, error) {
+	unparsable, err := inspectStorage(ctx, a)
+	if err != nil {
+		return 0, err
+	}
+
+	var problems []task.Problem
+	if unparsable == 0 {
+		problems, err = a.service.Check(ctx)
+		if err != nil {
+			return 0, fmt.Errorf("failed to check tasks: %w", err)
+		}
+	}
+
+	if !fix {
+		printProblems(problems, "fix")
+		if unparsable > 0 {
+			fmt.Println("Tasks can be checked once the records above are fixed or quarantined (--fix).")
+		}
+		if unparsable+len(problems) == 0 {
+			fmt.Println("No problems found.")
+		}
+		return unparsable + len(problems), nil
+	}
+
+	if unparsable+len(problems) == 0 {
+		fmt.Println("No problems found.")
+		return 0, nil
+	}
+
+	backupPath, err := backupStorage(ctx, a)
+	if err != nil {
+		return 0, fmt.Errorf("failed to back up storage, nothing was changed: %w", err)
+	}
+	fmt.Printf("Backed up to %s\n", backupPath)
+
+	if unparsable > 0 {
+		if err := quarantineStorage(ctx, a); err != nil {
+			return 0, err
+		}
+	}
+
+	fixed, err := a.service.Repair(ctx)
+	if err != nil {
+		return 0, fmt.Errorf("failed to repair tasks: %w", err)
+	}
+	printProblems(fixed, "fixed")
+
+	return 0, nil
+}
+
+// inspectStorage reports records of the file backends that cannot be parsed
+// at all, which hide every task from the checks done by the service.
+func inspectStorage(ctx context.Context, a *App) (int, error) {
+	ctx, cancel := a.withTimeout(ctx)
+	defer cancel()
+
+	switch a.format {
+	case "json":
+		records, err := jsonstore.Inspect(ctx, a.storagePath)
+		if err != nil {
+			return 0, fmt.Errorf("failed to inspect %s: %w", a.storagePath, err)
+		}
+		if len(records) > 0 {
+			fmt.Printf("%s: %d record(s) cannot be parsed\n", a.storagePath, len(records))
+			for _, record := range records {
+				fmt.Printf("  record %d: %s\n", record.Index+1, record.Reason)
+			}
+		}
+
+		quarantined, err := jsonstore.Quarantined(a.storagePath)
+		if err != nil {
+			return 0, err
+		}
+		if len(quarantined) > 0 {
+			fmt.Printf("%s: %d record(s) quarantined\n", jsonstore.QuarantinePath(a.storagePath), len(quarantined))
+		}
+
+		return len(records), nil
+	case "csv":
+		rows, err := csv.Inspect(ctx, a.storagePath)
+		if err != nil {
+			return 0, fmt.Errorf("failed to inspect %s: %w", a.storagePath, err)
+		}
+		if len(rows) > 0 {
+			fmt.Printf("%s: %d row(s) cannot be parsed\n", a.storagePath, len(rows))
+			for _, row := range rows {
+				fmt.Printf("  line %d: %s\n", row.Line, row.Reason)
+			}
+		}
+
+		quarantined, err := csv.Quarantined(a.storagePath)
+		if err != nil {
+			return 0, err
+		}
+		if len(quarantined) > 0 {
+			fmt.Printf("%s: %d row(s) quarantined\n", csv.QuarantinePath(a.storagePath), len(quarantined))
+			for _, row := range quarantined {
+				text := row.Raw
+				if text == "" {
+					text = strings.Join(row.Record, ",")
+				}
+				fmt.Printf("  line %d: %s: %s\n", row.Line, row.Reason, text)
+			}
+		}
+
+		return len(rows), nil
+	}
+
+	return 0, nil
+}
+
+func quarantineStorage(ctx context.Context, a *App) error {
+	ctx, cancel := a.withTimeout(ctx)
+	defer cancel()
+
+	switch a.format {
+	case "json":
+		records, err := jsonstore.Quarantine(ctx, a.storagePath)
+		if err != nil {
+			return fmt.Errorf("failed to quarantine records: %w", err)
+		}
+		fmt.Printf("Moved %d record(s) to %s\n", len(records), jsonstore.QuarantinePath(a.storagePath))
+	case "csv":
+		rows, err := csv.Quarantine(ctx, a.storagePath)
+		if err != nil {
+			return fmt.Errorf("failed to quarantine rows: %w", err)
+		}
+		fmt.Printf("Moved %d row(s) to %s\n", len(rows), csv.QuarantinePath(a.storagePath))
+	}
+
+	return nil
+}
+
+// backupStorage copies the tasks file of the file backends as is. Databases
+// are dumped as a JSON array of tasks instead.
+func backupStorage(ctx context.Context, a *App) (string, error) {
+	stamp := time.Now().Format("20060102T150405")
+
+	switch a.format {
+	case "json", "csv":
+		data, err := os.ReadFile(a.storagePath)
+		if err != nil {
+			return "", err
+		}
+
+		backupPath := fmt.Sprintf("%s.doctor-%s.bak", a.storagePath, stamp)
+		return backupPath, fileformat.WriteFile(backupPath, data, 0644)
+	}
+
+	tasks, err := a.service.List(ctx, &task.TaskSelector{}, &task.TaskFilter{IncludeCompleted: true, Trash: task.TrashInclude})
+	if err != nil {
+		return "", err
+	}
+
+	data, err := json.MarshalIndent(tasks, "", "  ")
+	if err != nil {
+		return "", err
+	}
+
+	backupDir := filepath.Join(a.storageDir, "backups")
+	if err := os.MkdirAll(backupDir, 0755); err != nil {
+		return "", err
+	}
+
+	backupPath := filepath.Join(backupDir, fmt.Sprintf("%s-doctor-%s.json", a.format, stamp))
+	return backupPath, fileformat.WriteFile(backupPath, data, 0644)
+}
+
+func printProblems(problems []task.Problem, label string) {
+	for _, problem := range problems {
+		fmt.Printf("%s: %s (%s: %s)\n", problem.TaskID.String()[0:8], problem.Message, label, problem.Fix)
+	}
+}
@@ -0,0 +1,85 @@
+package cli
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/ncfex/tasks/internal/task"
+)
+
+// Exit codes returned by the tasks command, so scripts can tell failures
+// apart.
+const (
+	exitError       = 1
+	exitNotFound    = 3
+	exitAmbiguous   = 4
+	exitConflict    = 5
+	exitInvalid     = 6
+	exitTimeout     = 7
+	exitInterrupted = 130
+)
+
+// ExitCode returns the process exit status for an error returned by Run.
+func ExitCode(err error) int {
+	switch {
+	case err == nil:
+		return 0
+	case errors.Is(err, task.ErrTaskNotFound):
+		return exitNotFound
+	case errors.Is(err, task.ErrAmbiguousID):
+		return exitAmbiguous
+	case errors.Is(err, task.ErrConflict):
+		return exitConflict
+	case errors.Is(err, task.ErrInvalidTask), errors.Is(err, task.ErrInvalidTransition):
+		return exitInvalid
+	case errors.Is(err, context.DeadlineExceeded):
+		return exitTimeout
+	case errors.Is(err, context.Canceled):
+		return exitInterrupted
+	}
+	return exitError
+}
+
+// ErrorMessage describes an error returned by Run for the user. Domain
+// errors are reported on their own, without the chain of operations that
+// led to them.
+func ErrorMessage(err error) string {
+	var ambiguous *task.AmbiguousIDError
+	if errors.As(err, &ambiguous) {
+		var b strings.Builder
+		fmt.Fprintf(&b, "ID %q matches %d tasks, use a longer prefix:", ambiguous.Prefix, len(ambiguous.Candidates))
+		for _, candidate := range ambiguous.Candidates {
+			fmt.Fprintf(&b, "\n  %s  %s", candidate.ID, candidate.Description)
+		}
+		return b.String()
+	}
+
+	var notFound *task.NotFoundError
+	if errors.As(err, &notFound) {
+		return notFound.Error()
+	}
+
+	var conflict *task.ConflictError
+	if errors.As(err, &conflict) {
+		return conflict.Error()
+	}
+
+	var stale *task.VersionConflictError
+	if errors.As(err, &stale) {
+		return stale.Error()
+	}
+
+	var transition *task.TransitionError
+	if errors.As(err, &transition) {
+		return transition.Error()
+	}
+
+	var invalid *task.ValidationError
+	if errors.As(err, &invalid) {
+		return invalid.Error()
+	}
+
+	return err.Error()
+}
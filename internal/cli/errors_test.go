@@ -0,0 +1,91 @@
+package cli_test
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/ncfex/tasks/internal/cli"
+	"github.com/ncfex/tasks/internal/task"
+)
+
+// wrap wraps err the way commands do on its way out of Run.
+func wrap(err error) error {
+	return fmt.Errorf("failed to update task: %w", &task.Error{Op: "SetStatus", Err: err})
+}
+
+func TestExitCode(t *testing.T) {
+	id := uuid.MustParse("0f6a4b1e-2c1d-4d8e-9a3b-5c6d7e8f9a0b")
+	tests := []struct {
+		name string
+		err  error
+		want int
+	}{
+		{"success", nil, 0},
+		{"not found", &task.NotFoundError{ID: "abc"}, 3},
+		{"ambiguous", &task.AmbiguousIDError{Prefix: "a"}, 4},
+		{"conflict", &task.ConflictError{ID: id, Reason: "already exists"}, 5},
+		{"stale", &task.VersionConflictError{}, 5},
+		{"invalid", &task.ValidationError{Field: "description", Reason: "required"}, 6},
+		{"transition", &task.TransitionError{ID: "#1", From: task.StatusDone, To: task.StatusTodo}, 6},
+		{"timeout", context.DeadlineExceeded, 7},
+		{"interrupted", context.Canceled, 130},
+		{"other", errors.New("disk full"), 1},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := tt.err
+			if err != nil {
+				err = wrap(err)
+			}
+			if got := cli.ExitCode(err); got != tt.want {
+				t.Errorf("ExitCode(%v) = %d, want %d", err, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestErrorMessage(t *testing.T) {
+	id := uuid.MustParse("0f6a4b1e-2c1d-4d8e-9a3b-5c6d7e8f9a0b")
+	tests := []struct {
+		name string
+		err  error
+		want string
+	}{
+		{"not found", &task.NotFoundError{ID: "abc"}, `no task matches ID "abc"`},
+		{"conflict", &task.ConflictError{ID: id, Reason: "already exists"}, "task " + id.String() + ": already exists"},
+		{"stale", &task.VersionConflictError{Attempted: task.Task{ID: id, Version: 2}, Current: task.Task{ID: id, Version: 3}},
+			"task " + id.String() + " was changed by someone else (version 3, expected 2)"},
+		{"transition", &task.TransitionError{ID: "#1", From: task.StatusDone, To: task.StatusTodo},
+			"task #1 cannot move from done to todo (no moves allowed)"},
+		{"invalid", &task.ValidationError{Field: "description", Reason: "required"}, "invalid task description: required"},
+		{"other", errors.New("disk full"), "failed to update task: SetStatus: disk full"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := cli.ErrorMessage(wrap(tt.err)); got != tt.want {
+				t.Errorf("ErrorMessage() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestErrorMessageListsCandidates(t *testing.T) {
+	first := task.Task{ID: uuid.MustParse("aaaa1111-2c1d-4d8e-9a3b-5c6d7e8f9a0b"), Description: "pay rent"}
+	second := task.Task{ID: uuid.MustParse("aaaa2222-2c1d-4d8e-9a3b-5c6d7e8f9a0b"), Description: "pay bills"}
+
+	got := cli.ErrorMessage(wrap(&task.AmbiguousIDError{Prefix: "aaaa", Candidates: []task.Task{first, second}}))
+	for _, want := range []string{`ID "aaaa" matches 2 tasks`, first.ID.String() + "  pay rent", second.ID.String() + "  pay bills"} {
+		if !strings.Contains(got, want) {
+			t.Errorf("ErrorMessage() = %q, want it to contain %q", got, want)
+		}
+	}
+	if strings.Contains(got, "SetStatus") {
+		t.Errorf("ErrorMessage() = %q, want it without the operation chain", got)
+	}
+}
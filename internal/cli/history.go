@@ -0,0 +1,58 @@
+package cli
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/ncfex/tasks/internal/task"
+	"github.com/spf13/cobra"
+)
+
+func newHistoryCommand(a *App) *cobra.Command {
+	return &cobra.Command{
+		Use:   "history [task_id]",
+		Short: "Show the change history of a task",
+		Args:  cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return withTaskID(args[0], func(id string) error {
+				return runHistory(cmd.Context(), a.service, id)
+			})
+		},
+	}
+}
+
+func runHistory(ctx context.Context, service task.TaskService, id string) error {
+	events, err := service.History(ctx, id)
+	if err != nil {
+		return fmt.Errorf("failed to load history: %w", err)
+	}
+
+	if len(events) == 0 {
+		fmt.Println("No history found.")
+		return nil
+	}
+
+	for i, event := range events {
+		if i > 0 {
+			fmt.Println()
+		}
+		fmt.Printf("%s  %s  %s\n",
+			event.RecordedAt.Local().Format("2006-01-02 15:04:05"),
+			event.User,
+			strings.ToUpper(string(event.Action)),
+		)
+		for _, diff := range event.Diffs {
+			fmt.Printf("  %s: %s -> %s\n", diff.Field, formatDiffValue(diff.Before), formatDiffValue(diff.After))
+		}
+	}
+
+	return nil
+}
+
+func formatDiffValue(value string) string {
+	if value == "" {
+		return "-"
+	}
+	return fmt.Sprintf("%q", value)
+}
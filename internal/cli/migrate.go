@@ -0,0 +1,99 @@
+package cli
+
+import (
+	"fmt"
+
+	"github.com/ncfex/tasks/internal/task"
+	"github.com/spf13/cobra"
+)
+
+func newMigrateCommand(a *App) *cobra.Command {
+	var from, to, onConflict string
+	var dryRun bool
+
+	cmd := &cobra.Command{
+		Use:   "migrate",
+		Short: "Copy every task from one storage backend to another",
+		Long: `Copy every task, including completed and deleted ones, from one storage
+backend to another, keeping IDs and timestamps. Undo journals and task
+history are not copied.`,
+		Args: cobra.NoArgs,
+		PersistentPreRunE: func(cmd *cobra.Command, args []string) error {
+			a.prepareCommand(cmd)
+			return nil
+		},
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if from == "" {
+				from = a.format
+			}
+			if from == to {
+				return fmt.Errorf("source and target storage are both %s", from)
+			}
+
+			strategy := task.ConflictStrategy(onConflict)
+			if !strategy.IsValid() {
+				return fmt.Errorf("invalid --on-conflict value %q: must be fail, skip, overwrite or newest-wins", onConflict)
+			}
+
+			source, err := a.openStorage(cmd.Context(), from, !a.cfg.DisableAutoMigrate)
+			if err != nil {
+				return fmt.Errorf("failed to open %s storage: %w", from, err)
+			}
+			target, err := a.openStorage(cmd.Context(), to, !a.cfg.DisableAutoMigrate)
+			if err != nil {
+				return fmt.Errorf("failed to open %s storage: %w", to, err)
+			}
+
+			ctx, cancel := a.withStreamTimeout(cmd.Context())
+			defer cancel()
+
+			result, err := task.MigrateTasks(ctx, source.repository, target.repository, task.MigrateOptions{
+				OnConflict: strategy,
+				DryRun:     dryRun,
+			})
+			if result != nil {
+				printMigrateResult(from, to, result, dryRun)
+			}
+			if err != nil {
+				return fmt.Errorf("failed to migrate tasks: %w", err)
+			}
+
+			written := result.Created + result.Overwritten
+			if !dryRun && result.Verified != written {
+				return fmt.Errorf("only %d of %d written tasks match the source", result.Verified, written)
+			}
+
+			return nil
+		},
+	}
+
+	cmd.Flags().StringVar(&from, "from", "", "Storage to copy from (defaults to the current format)")
+	cmd.Flags().StringVar(&to, "to", "", "Storage to copy to (json, csv, sql or sqlite)")
+	cmd.Flags().StringVar(&onConflict, "on-conflict", string(task.ConflictFail), "What to do with tasks that already exist in the target (fail, skip, overwrite or newest-wins)")
+	cmd.Flags().BoolVarP(&dryRun, "dry-run", "n", false, "Show what would be copied without writing anything")
+	cmd.MarkFlagRequired("to")
+
+	return cmd
+}
+
+func printMigrateResult(from, to string, result *task.MigrateResult, dryRun bool) {
+	if dryRun {
+		fmt.Printf("Dry run, nothing was written to %s.\n", to)
+	}
+
+	fmt.Printf("Read:        %d task(s) from %s\n", result.Read, from)
+	fmt.Printf("Created:     %d\n", result.Created)
+	fmt.Printf("Overwritten: %d\n", result.Overwritten)
+	fmt.Printf("Skipped:     %d\n", result.Skipped)
+
+	if len(result.Conflicts) > 0 {
+		fmt.Printf("Conflicts:   %d task(s) already exist in %s\n", len(result.Conflicts), to)
+		for _, id := range result.Conflicts {
+			fmt.Printf("  %s\n", id)
+		}
+	}
+
+	if !dryRun {
+		fmt.Printf("Verified:    %d of %d\n", result.Verified, result.Created+result.Overwritten)
+	}
+}
@@ -0,0 +1,93 @@
+package cli
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"text/tabwriter"
+
+	"github.com/ncfex/tasks/internal/task"
+	"github.com/ncfex/tasks/internal/utils"
+	"github.com/spf13/cobra"
+)
+
+func newReportCommand(a *App) *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "report",
+		Short: "Show reports about your tasks",
+	}
+
+	cmd.AddCommand(newEstimatesReportCommand(a))
+
+	return cmd
+}
+
+func newEstimatesReportCommand(a *App) *cobra.Command {
+	return &cobra.Command{
+		Use:   "estimates",
+		Short: "Compare estimates against the time completed tasks actually took",
+		Args:  cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return runEstimatesReport(cmd.Context(), a.service)
+		},
+	}
+}
+
+func runEstimatesReport(ctx context.Context, service task.TaskService) error {
+	report, err := service.EstimateReport(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to build report: %w", err)
+	}
+
+	if len(report.Entries) == 0 {
+		fmt.Println("No completed tasks with estimates found.")
+		return nil
+	}
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', tabwriter.TabIndent)
+	fmt.Fprintln(w, "ID\tDESCRIPTION\tESTIMATE\tACTUAL\tOFF BY")
+	fromCreation := 0
+	for _, e := range report.Entries {
+		actual := utils.FormatDuration(e.Actual)
+		if e.FromCreation {
+			actual += "*"
+			fromCreation++
+		}
+		fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\n",
+			e.Task.ID.String()[0:8],
+			e.Task.Description,
+			utils.FormatDuration(e.Estimate),
+			actual,
+			formatRatio(e.Ratio()),
+		)
+	}
+	w.Flush()
+
+	fmt.Println()
+	fmt.Printf("Completed tasks with estimates: %d\n", len(report.Entries))
+	fmt.Printf("Total estimated: %s, total actual: %s\n",
+		utils.FormatDuration(report.TotalEstimate),
+		utils.FormatDuration(report.TotalActual),
+	)
+	fmt.Printf("Typically off by: %s\n", formatRatio(report.MedianRatio))
+	if fromCreation > 0 {
+		fmt.Printf("* Never marked in_progress, so measured from creation (lead time): %d\n", fromCreation)
+	}
+	if report.SkippedPending > 0 {
+		fmt.Printf("Open tasks with estimates (not included): %d\n", report.SkippedPending)
+	}
+
+	return nil
+}
+
+func formatRatio(ratio float64) string {
+	diff := (ratio - 1) * 100
+	switch {
+	case diff > 0.5:
+		return fmt.Sprintf("+%.0f%% (over)", diff)
+	case diff < -0.5:
+		return fmt.Sprintf("%.0f%% (under)", diff)
+	default:
+		return "on target"
+	}
+}
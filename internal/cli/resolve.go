@@ -0,0 +1,63 @@
+package cli
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+
+	"github.com/mattn/go-isatty"
+	"github.com/ncfex/tasks/internal/task"
+)
+
+// withTaskID calls fn with the ID given on the command line. When it is an
+// ambiguous prefix and stdin is a terminal, the user picks one of the
+// candidates and fn is called again with its full ID.
+func withTaskID(id string, fn func(id string) error) error {
+	err := fn(id)
+
+	var ambiguous *task.AmbiguousIDError
+	if !errors.As(err, &ambiguous) || !isTerminal(os.Stdin) {
+		return err
+	}
+
+	chosen, ok := chooseCandidate(os.Stdin, os.Stderr, ambiguous)
+	if !ok {
+		return err
+	}
+
+	return fn(chosen.ID.String())
+}
+
+// chooseCandidate lists the tasks matching an ambiguous prefix and reads
+// the number of the one to use. It reports false if no valid choice was
+// made.
+func chooseCandidate(in io.Reader, out io.Writer, ambiguous *task.AmbiguousIDError) (*task.Task, bool) {
+	candidates := ambiguous.Candidates
+	prefixes := task.ShortIDs(candidates, len(ambiguous.Prefix)+1)
+
+	fmt.Fprintf(out, "ID %q matches %d tasks:\n", ambiguous.Prefix, len(candidates))
+	for i := range candidates {
+		fmt.Fprintf(out, "  %d) %s  %s (%s)\n", i+1, prefixes[candidates[i].ID], candidates[i].Description, candidates[i].Status)
+	}
+	fmt.Fprintf(out, "Select a task [1-%d]: ", len(candidates))
+
+	line, err := bufio.NewReader(in).ReadString('\n')
+	if err != nil && line == "" {
+		return nil, false
+	}
+
+	choice, err := strconv.Atoi(strings.TrimSpace(line))
+	if err != nil || choice < 1 || choice > len(candidates) {
+		return nil, false
+	}
+
+	return &candidates[choice-1], true
+}
+
+func isTerminal(f *os.File) bool {
+	return isatty.IsTerminal(f.Fd()) || isatty.IsCygwinTerminal(f.Fd())
+}
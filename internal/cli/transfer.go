@@ -0,0 +1,155 @@
+package cli
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"github.com/ncfex/tasks/internal/exchange"
+	"github.com/ncfex/tasks/internal/storage/fileformat"
+	"github.com/ncfex/tasks/internal/task"
+	"github.com/spf13/cobra"
+)
+
+func fileFormatNames() string {
+	return strings.Join(exchange.Names(), ", ")
+}
+
+// storageFlag stands in for the global --format flag, which import and
+// export use for the file format instead.
+func storageFlag(cmd *cobra.Command, a *App) {
+	cmd.Flags().StringVarP(&a.format, "storage", "m", a.format, "Storage format (json, csv, sql or sqlite)")
+}
+
+func newImportCommand(a *App) *cobra.Command {
+	var format, onConflict string
+	var dryRun bool
+
+	cmd := &cobra.Command{
+		Use:   "import <file>",
+		Short: "Merge tasks from a file into the current storage",
+		Long: `Merge the tasks in a file, or "-" for standard input, into the current
+storage backend by task ID. Besides the formats of other tools, json and csv
+read dumps written by export, whichever backend they came from. Tasks that
+already exist are updated from json, csv and taskwarrior files if the file's
+copy was changed more recently. They are left alone by todotxt and ics
+imports, since those formats drop fields such as annotations. Use
+--on-conflict to choose otherwise. Imports are not recorded for undo or
+history.`,
+		Args: cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			fileFormat, err := exchange.Lookup(format)
+			if err != nil {
+				return err
+			}
+			strategy := fileFormat.OnConflict
+			if onConflict != "" {
+				strategy = task.ConflictStrategy(onConflict)
+			}
+			if !strategy.IsValid() {
+				return fmt.Errorf("invalid --on-conflict value %q: must be fail, skip, overwrite or newest-wins", onConflict)
+			}
+
+			in := io.Reader(os.Stdin)
+			if args[0] != "-" {
+				f, err := os.Open(args[0])
+				if err != nil {
+					return fmt.Errorf("failed to open %s: %w", args[0], err)
+				}
+				defer f.Close()
+				in = f
+			}
+
+			ctx, cancel := a.withStreamTimeout(cmd.Context())
+			defer cancel()
+
+			result, err := exchange.Import(ctx, a.repository, fileFormat.Importer.NewDecoder(in), task.MigrateOptions{
+				OnConflict: strategy,
+				DryRun:     dryRun,
+			})
+			if result != nil {
+				printMigrateResult(args[0], a.format, result, dryRun)
+			}
+			if err != nil {
+				return fmt.Errorf("failed to import %s: %w", args[0], err)
+			}
+
+			written := result.Created + result.Overwritten
+			if !dryRun && result.Verified != written {
+				return fmt.Errorf("only %d of %d written tasks match the file", result.Verified, written)
+			}
+
+			return nil
+		},
+	}
+
+	cmd.Flags().StringVarP(&format, "format", "f", "", "File format ("+fileFormatNames()+")")
+	cmd.Flags().StringVar(&onConflict, "on-conflict", "", "What to do with tasks that already exist (fail, skip, overwrite or newest-wins; defaults to newest-wins for json, csv and taskwarrior, and skip otherwise)")
+	cmd.Flags().BoolVarP(&dryRun, "dry-run", "n", false, "Show what would be imported without writing anything")
+	storageFlag(cmd, a)
+	cmd.MarkFlagRequired("format")
+
+	return cmd
+}
+
+func newExportCommand(a *App) *cobra.Command {
+	var format, output string
+	var events bool
+
+	cmd := &cobra.Command{
+		Use:   "export",
+		Short: "Write every task to a file",
+		Long: `Write every task, including completed ones, to a file, or to standard
+output if no --output is given. The json and csv formats are complete dumps,
+trashed tasks included, that import can merge into any backend; other
+formats leave the trash out.`,
+		Args: cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			fileFormat, err := exchange.Lookup(format)
+			if err != nil {
+				return err
+			}
+			exporter := fileFormat.Exporter
+			if events {
+				if format != "ics" {
+					return fmt.Errorf("--events only applies to --format ics")
+				}
+				exporter = exchange.ICSEvents
+			}
+
+			filter := &task.TaskFilter{IncludeCompleted: true}
+			if fileFormat.Native {
+				filter.Trash = task.TrashInclude
+			}
+
+			ctx, cancel := a.withStreamTimeout(cmd.Context())
+			defer cancel()
+
+			if output == "" || output == "-" {
+				_, err := exchange.Export(ctx, a.repository, filter, exporter.NewEncoder(os.Stdout))
+				return err
+			}
+
+			var count int
+			err = fileformat.WriteFileFunc(output, 0644, func(w io.Writer) error {
+				count, err = exchange.Export(ctx, a.repository, filter, exporter.NewEncoder(w))
+				return err
+			})
+			if err != nil {
+				return fmt.Errorf("failed to write %s: %w", output, err)
+			}
+
+			fmt.Printf("Exported %d task(s) to %s\n", count, output)
+			return nil
+		},
+	}
+
+	cmd.Flags().StringVarP(&format, "format", "f", "", "File format ("+fileFormatNames()+")")
+	cmd.Flags().StringVarP(&output, "output", "o", "", "File to write (defaults to standard output)")
+	cmd.Flags().BoolVar(&events, "events", false, "With --format ics, write all-day events on due dates instead of to-dos")
+	storageFlag(cmd, a)
+	cmd.MarkFlagRequired("format")
+
+	return cmd
+}
@@ -0,0 +1,101 @@
+package cli
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/ncfex/tasks/internal/task"
+	"github.com/ncfex/tasks/internal/utils"
+	"github.com/spf13/cobra"
+)
+
+var trashColumns = []string{
+	string(task.TaskFieldNum),
+	string(task.TaskFieldID),
+	string(task.TaskFieldDescription),
+	string(task.TaskFieldStatus),
+	string(task.TaskFieldDeletedAt),
+}
+
+func newTrashCommand(a *App) *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "trash",
+		Short: "Manage deleted tasks",
+	}
+
+	cmd.AddCommand(
+		newTrashListCommand(a),
+		newTrashRestoreCommand(a),
+		newTrashPurgeCommand(a),
+	)
+
+	return cmd
+}
+
+func newTrashListCommand(a *App) *cobra.Command {
+	return &cobra.Command{
+		Use:   "list",
+		Short: "List tasks in the trash",
+		Args:  cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			filter := &task.TaskFilter{IncludeCompleted: true, Trash: task.TrashOnly}
+			return runList(cmd.Context(), a.service, filter, trashColumns, false, a.cfg.ShortIDLength)
+		},
+	}
+}
+
+func newTrashRestoreCommand(a *App) *cobra.Command {
+	return &cobra.Command{
+		Use:   "restore [task_id]",
+		Short: "Restore a task from the trash",
+		Args:  cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return withTaskID(args[0], func(idString string) error {
+				err := withConflictRetry(func() error {
+					_, err := a.service.Restore(cmd.Context(), idString)
+					return err
+				})
+				if err != nil {
+					return fmt.Errorf("failed to restore task: %w", err)
+				}
+
+				fmt.Printf("Task %s restored\n", idString)
+				return nil
+			})
+		},
+	}
+}
+
+func newTrashPurgeCommand(a *App) *cobra.Command {
+	var olderThan string
+	var all bool
+
+	cmd := &cobra.Command{
+		Use:   "purge",
+		Short: "Permanently delete tasks from the trash",
+		Args:  cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			cutoff := time.Now()
+			if !all {
+				age, err := utils.ParseDuration(olderThan)
+				if err != nil {
+					return fmt.Errorf("failed to parse --older-than: %w", err)
+				}
+				cutoff = cutoff.Add(-age)
+			}
+
+			purged, err := a.service.Purge(cmd.Context(), cutoff)
+			if err != nil {
+				return fmt.Errorf("failed to purge trash: %w", err)
+			}
+
+			fmt.Printf("Purged %d task(s) from trash\n", purged)
+			return nil
+		},
+	}
+
+	cmd.Flags().StringVar(&olderThan, "older-than", "30 days", "Only purge tasks deleted longer ago than this")
+	cmd.Flags().BoolVar(&all, "all", false, "Purge every task in the trash")
+
+	return cmd
+}
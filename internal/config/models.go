@@ -5,13 +5,33 @@ import "github.com/ncfex/tasks/internal/task"
 type ServiceMode string
 
 const (
-	ServiceModeSQL  ServiceMode = "sql"
-	ServiceModeCSV  ServiceMode = "csv"
-	ServiceModeJSON ServiceMode = "json"
+	ServiceModeSQL    ServiceMode = "sql"
+	ServiceModeSQLite ServiceMode = "sqlite"
+	ServiceModeCSV    ServiceMode = "csv"
+	ServiceModeJSON   ServiceMode = "json"
 )
 
 type Config struct {
 	filepath       string
 	ServiceMode    ServiceMode      `json:"service_mode"`
 	DisplayColumns []task.TaskField `json:"display_columns"`
+
+	// StatusTransitions overrides the default status workflow when set.
+	StatusTransitions task.Transitions `json:"status_transitions,omitempty"`
+
+	// DisableAutoMigrate stops SQL backends from applying pending schema
+	// migrations on startup; use `tasks db migrate` instead.
+	DisableAutoMigrate bool `json:"disable_auto_migrate,omitempty"`
+
+	// LenientCSV moves CSV rows that cannot be parsed to a quarantine file
+	// instead of refusing to read the file.
+	LenientCSV bool `json:"lenient_csv,omitempty"`
+
+	// ShortIDLength is the minimum length of the IDs shown by list, which
+	// otherwise uses task.DefaultShortIDLength.
+	ShortIDLength int `json:"short_id_length,omitempty"`
+
+	// ConfirmThreshold is how many tasks complete, status and delete may
+	// change at once before asking for confirmation.
+	ConfirmThreshold int `json:"confirm_threshold,omitempty"`
 }
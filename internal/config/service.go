@@ -6,6 +6,7 @@ import (
 	"os"
 	"path/filepath"
 
+	"github.com/ncfex/tasks/internal/storage/fileformat"
 	"github.com/ncfex/tasks/internal/task"
 )
 
@@ -39,12 +40,16 @@ func (c *Config) Load() error {
 		c.ServiceMode = ServiceModeJSON
 	}
 
+	if err := c.StatusTransitions.Validate(); err != nil {
+		return fmt.Errorf("invalid config: %w", err)
+	}
+
 	updatedData, err := json.Marshal(c)
 	if err != nil {
 		return fmt.Errorf("failed to marshal config: %w", err)
 	}
 
-	if err := os.WriteFile(c.filepath, updatedData, 0644); err != nil {
+	if err := fileformat.WriteFile(c.filepath, updatedData, 0644); err != nil {
 		return fmt.Errorf("failed to write config file: %w", err)
 	}
 
@@ -67,7 +72,7 @@ func (c *Config) writeToFile() error {
 		return fmt.Errorf("failed to marshal config: %w", err)
 	}
 
-	if err := os.WriteFile(c.filepath, data, 0644); err != nil {
+	if err := fileformat.WriteFile(c.filepath, data, 0644); err != nil {
 		return fmt.Errorf("failed to write config file: %w", err)
 	}
 
@@ -83,7 +88,7 @@ func (c *Config) ensureFile(homeDir string) error {
 			return fmt.Errorf("failed to marshal default config: %w", err)
 		}
 
-		if err := os.WriteFile(p, data, 0644); err != nil {
+		if err := fileformat.WriteFile(p, data, 0644); err != nil {
 			return fmt.Errorf("failed to create config file: %w", err)
 		}
 	}
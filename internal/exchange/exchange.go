@@ -0,0 +1,75 @@
+// Package exchange moves tasks between repositories and files, in the
+// native json and csv formats of the file backends or in the formats of
+// other tools.
+package exchange
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+
+	"github.com/ncfex/tasks/internal/task"
+)
+
+// Encoder writes tasks one at a time. Close finishes the output and must be
+// called even if no task was written.
+type Encoder interface {
+	Encode(t *task.Task) error
+	Close() error
+}
+
+// Decoder reads tasks one at a time. Decode returns io.EOF after the last
+// task.
+type Decoder interface {
+	Decode() (*task.Task, error)
+}
+
+type Exporter interface {
+	NewEncoder(w io.Writer) Encoder
+}
+
+type Importer interface {
+	NewDecoder(r io.Reader) Decoder
+}
+
+// Export writes the tasks of repo that filter matches to enc, in task
+// number order, and closes enc. Tasks are read from repo a page at a time.
+// It returns the number of tasks written.
+func Export(ctx context.Context, repo task.Repository, filter *task.TaskFilter, enc Encoder) (int, error) {
+	count := 0
+	err := task.Walk(ctx, repo, filter, func(t *task.Task) error {
+		if err := enc.Encode(t); err != nil {
+			return fmt.Errorf("failed to write task %s: %w", t.ID, err)
+		}
+		count++
+		return nil
+	})
+	if err != nil {
+		return count, err
+	}
+	if err := enc.Close(); err != nil {
+		return count, fmt.Errorf("failed to finish export: %w", err)
+	}
+
+	return count, nil
+}
+
+// Import reads tasks from dec one at a time and merges them into repo by
+// ID, using opts.OnConflict for tasks repo already has.
+func Import(ctx context.Context, repo task.Repository, dec Decoder, opts task.MigrateOptions) (*task.MigrateResult, error) {
+	return task.ImportTasks(ctx, repo, func(fn func(*task.Task) error) error {
+		for {
+			t, err := dec.Decode()
+			if errors.Is(err, io.EOF) {
+				return nil
+			}
+			if err != nil {
+				return err
+			}
+			if err := fn(t); err != nil {
+				return err
+			}
+		}
+	}, opts)
+}
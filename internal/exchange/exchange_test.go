@@ -0,0 +1,305 @@
+package exchange_test
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"path/filepath"
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/ncfex/tasks/internal/exchange"
+	jsonstore "github.com/ncfex/tasks/internal/storage/json"
+	tasksql "github.com/ncfex/tasks/internal/storage/sql"
+	"github.com/ncfex/tasks/internal/storage/sqlite"
+	"github.com/ncfex/tasks/internal/task"
+)
+
+func newRepository(t *testing.T) task.Repository {
+	t.Helper()
+	return jsonstore.NewRepository(filepath.Join(t.TempDir(), "tasks.json"))
+}
+
+func export(t *testing.T, repo task.Repository, exporter exchange.Exporter) *bytes.Buffer {
+	t.Helper()
+	var buf bytes.Buffer
+	filter := &task.TaskFilter{IncludeCompleted: true, Trash: task.TrashInclude}
+	if _, err := exchange.Export(context.Background(), repo, filter, exporter.NewEncoder(&buf)); err != nil {
+		t.Fatal(err)
+	}
+	return &buf
+}
+
+func save(t *testing.T, repo task.Repository, tasks ...task.Task) {
+	t.Helper()
+	for i := range tasks {
+		if err := repo.Save(context.Background(), &tasks[i]); err != nil {
+			t.Fatalf("Save(%s): %v", tasks[i].Description, err)
+		}
+	}
+}
+
+func newTask(description string) task.Task {
+	now := time.Now().UTC().Truncate(time.Second)
+	return task.Task{ID: uuid.New(), Description: description, Status: task.StatusTodo, CreatedAt: now, UpdatedAt: now}
+}
+
+// Importing a file again updates the tasks changed since in formats that
+// keep annotations and the time of the last change, and leaves them alone in
+// the others.
+func TestImportDefaultConflictStrategy(t *testing.T) {
+	tests := []struct {
+		format      string
+		wantUpdated bool
+	}{
+		{"json", true},
+		{"csv", true},
+		{"taskwarrior", true},
+		{"todotxt", false},
+		{"ics", false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.format, func(t *testing.T) {
+			ctx := context.Background()
+			format, err := exchange.Lookup(tt.format)
+			if err != nil {
+				t.Fatal(err)
+			}
+			opts := task.MigrateOptions{OnConflict: format.OnConflict}
+
+			source, target := newRepository(t), newRepository(t)
+			created := time.Now().UTC().Truncate(24 * time.Hour)
+			original := &task.Task{ID: uuid.New(), Description: "pay rent", Status: task.StatusTodo, CreatedAt: created, UpdatedAt: created}
+			if err := source.Save(ctx, original); err != nil {
+				t.Fatal(err)
+			}
+			if _, err := exchange.Import(ctx, target, format.Importer.NewDecoder(export(t, source, format.Exporter)), opts); err != nil {
+				t.Fatal(err)
+			}
+
+			original.Description = "pay rent and deposit"
+			original.UpdatedAt = created.Add(time.Hour)
+			if err := source.Update(ctx, original); err != nil {
+				t.Fatal(err)
+			}
+			result, err := exchange.Import(ctx, target, format.Importer.NewDecoder(export(t, source, format.Exporter)), opts)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			got, err := target.GetByID(ctx, original.ID)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if updated := got.Description == original.Description; updated != tt.wantUpdated {
+				t.Errorf("description = %q after importing again with %s, want updated = %t (result %+v)", got.Description, format.OnConflict, tt.wantUpdated, result)
+			}
+		})
+	}
+}
+
+// Every format keeps the IDs and the fields the formats share, and native
+// ones keep trashed tasks too.
+func TestExportImportRoundTrip(t *testing.T) {
+	full := newTask("pay rent")
+	full.Priority = "A"
+	full.Project = "home"
+	full.Tags = []string{"bills"}
+	full.Estimate = 90 * time.Second
+	started := newTask("write report")
+	started.Status = task.StatusInProgress
+	done := newTask("buy milk")
+	done.Status = task.StatusDone
+	done.CompletedAt = done.CreatedAt
+	trashed := newTask("old idea")
+	trashed.DeletedAt = trashed.CreatedAt
+	want := []task.Task{full, started, done}
+
+	for _, name := range exchange.Names() {
+		t.Run(name, func(t *testing.T) {
+			ctx := context.Background()
+			format, err := exchange.Lookup(name)
+			if err != nil {
+				t.Fatal(err)
+			}
+			source, target := newRepository(t), newRepository(t)
+			save(t, source, full, started, done, trashed)
+
+			filter := &task.TaskFilter{IncludeCompleted: true}
+			wantTasks := want
+			if format.Native {
+				filter.Trash = task.TrashInclude
+				wantTasks = append(slices.Clone(want), trashed)
+			}
+			var buf bytes.Buffer
+			if _, err := exchange.Export(ctx, source, filter, format.Exporter.NewEncoder(&buf)); err != nil {
+				t.Fatal(err)
+			}
+			result, err := exchange.Import(ctx, target, format.Importer.NewDecoder(&buf), task.MigrateOptions{OnConflict: task.ConflictFail})
+			if err != nil {
+				t.Fatal(err)
+			}
+			if result.Created != len(wantTasks) {
+				t.Errorf("Import created %d tasks, want %d", result.Created, len(wantTasks))
+			}
+
+			for _, w := range wantTasks {
+				got, err := target.GetByID(ctx, w.ID)
+				if err != nil {
+					t.Errorf("GetByID(%s): %v", w.Description, err)
+					continue
+				}
+				switch {
+				case got.Description != w.Description:
+					t.Errorf("Description = %q, want %q", got.Description, w.Description)
+				case got.Status != w.Status:
+					t.Errorf("%s: Status = %q, want %q", w.Description, got.Status, w.Status)
+				case got.Priority != w.Priority || got.Project != w.Project || !slices.Equal(got.Tags, w.Tags):
+					t.Errorf("%s: priority, project and tags = %q %q %q, want %q %q %q", w.Description, got.Priority, got.Project, got.Tags, w.Priority, w.Project, w.Tags)
+				case got.Estimate != w.Estimate:
+					t.Errorf("%s: Estimate = %s, want %s", w.Description, got.Estimate, w.Estimate)
+				case got.IsDeleted() != w.IsDeleted():
+					t.Errorf("%s: deleted = %t, want %t", w.Description, got.IsDeleted(), w.IsDeleted())
+				}
+			}
+		})
+	}
+}
+
+// Export reads a Walker a page at a time and import writes tasks as they are
+// decoded, so sets larger than a page arrive whole and in order.
+func TestExportImportManyTasks(t *testing.T) {
+	ctx := context.Background()
+	db, err := sqlite.Connect(filepath.Join(t.TempDir(), "tasks.db"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { db.Close() })
+	migrator, err := sqlite.NewMigrator(db)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := migrator.Migrate(ctx); err != nil {
+		t.Fatal(err)
+	}
+	source := tasksql.NewRepositoryWithDB(db)
+
+	const count = 1201
+	var dump bytes.Buffer
+	enc := jsonstore.NewEncoder(&dump)
+	for i := 0; i < count; i++ {
+		tk := newTask(fmt.Sprintf("task %d", i))
+		tk.Num = i + 1
+		if err := enc.Encode(&tk); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := enc.Close(); err != nil {
+		t.Fatal(err)
+	}
+	result, err := exchange.Import(ctx, source, jsonstore.NewDecoder(&dump), task.MigrateOptions{OnConflict: task.ConflictFail})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if result.Created != count || result.Verified != count {
+		t.Fatalf("Import = %+v, want %d tasks created and verified", result, count)
+	}
+
+	for _, name := range exchange.Names() {
+		t.Run(name, func(t *testing.T) {
+			format, err := exchange.Lookup(name)
+			if err != nil {
+				t.Fatal(err)
+			}
+			var buf bytes.Buffer
+			written, err := exchange.Export(ctx, source, &task.TaskFilter{IncludeCompleted: true}, format.Exporter.NewEncoder(&buf))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if written != count {
+				t.Errorf("Export wrote %d tasks, want %d", written, count)
+			}
+
+			dec := format.Importer.NewDecoder(&buf)
+			for i := 0; ; i++ {
+				tk, err := dec.Decode()
+				if errors.Is(err, io.EOF) {
+					if i != count {
+						t.Errorf("decoded %d tasks, want %d", i, count)
+					}
+					break
+				}
+				if err != nil {
+					t.Fatal(err)
+				}
+				if want := fmt.Sprintf("task %d", i); tk.Description != want {
+					t.Fatalf("task %d = %q, want %q", i, tk.Description, want)
+				}
+			}
+		})
+	}
+}
+
+func TestImportConflictStrategies(t *testing.T) {
+	// The file has an older copy of stale, a newer copy of fresh, and a task
+	// the repository doesn't have.
+	stale, fresh, added := newTask("stale"), newTask("fresh"), newTask("added")
+	fileStale, fileFresh := stale, fresh
+	fileStale.Description = "stale from the file"
+	fileStale.UpdatedAt = stale.UpdatedAt.Add(-time.Hour)
+	fileFresh.Description = "fresh from the file"
+	fileFresh.UpdatedAt = fresh.UpdatedAt.Add(time.Hour)
+
+	tests := []struct {
+		strategy task.ConflictStrategy
+		wantErr  bool
+		want     map[string]string
+	}{
+		{task.ConflictFail, true, map[string]string{"stale": "stale", "fresh": "fresh"}},
+		{task.ConflictSkip, false, map[string]string{"stale": "stale", "fresh": "fresh", "added": "added"}},
+		{task.ConflictOverwrite, false, map[string]string{"stale": "stale from the file", "fresh": "fresh from the file", "added": "added"}},
+		{task.ConflictNewestWins, false, map[string]string{"stale": "stale", "fresh": "fresh from the file", "added": "added"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(string(tt.strategy), func(t *testing.T) {
+			ctx := context.Background()
+			repo := newRepository(t)
+			save(t, repo, stale, fresh)
+
+			var file bytes.Buffer
+			enc := jsonstore.NewEncoder(&file)
+			for _, tk := range []task.Task{fileStale, fileFresh, added} {
+				if err := enc.Encode(&tk); err != nil {
+					t.Fatal(err)
+				}
+			}
+			if err := enc.Close(); err != nil {
+				t.Fatal(err)
+			}
+
+			_, err := exchange.Import(ctx, repo, jsonstore.NewDecoder(&file), task.MigrateOptions{OnConflict: tt.strategy})
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("Import error = %v, want error %t", err, tt.wantErr)
+			}
+
+			for name, tk := range map[string]task.Task{"stale": stale, "fresh": fresh, "added": added} {
+				got, err := repo.GetByID(ctx, tk.ID)
+				want, ok := tt.want[name]
+				switch {
+				case !ok && err == nil:
+					t.Errorf("%s was imported, want it left out", name)
+				case ok && err != nil:
+					t.Errorf("GetByID(%s): %v", name, err)
+				case ok && got.Description != want:
+					t.Errorf("%s = %q, want %q", name, got.Description, want)
+				}
+			}
+		})
+	}
+}
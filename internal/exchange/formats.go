@@ -0,0 +1,95 @@
+package exchange
+
+import (
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/ncfex/tasks/internal/ics"
+	"github.com/ncfex/tasks/internal/storage/csv"
+	"github.com/ncfex/tasks/internal/storage/json"
+	"github.com/ncfex/tasks/internal/task"
+	"github.com/ncfex/tasks/internal/taskwarrior"
+	"github.com/ncfex/tasks/internal/todotxt"
+)
+
+// Format is a file format tasks can be exported to and imported from.
+// Native formats keep every task field, so they can hold a complete dump,
+// trashed tasks included. OnConflict is what import does by default with
+// tasks that already exist: formats that keep annotations and the time of
+// the last change update tasks changed since, the others leave them alone.
+type Format struct {
+	Name       string
+	Native     bool
+	OnConflict task.ConflictStrategy
+	Exporter   Exporter
+	Importer   Importer
+}
+
+var formats = []Format{
+	{
+		Name:       "json",
+		Native:     true,
+		OnConflict: task.ConflictNewestWins,
+		Exporter:   exporterFunc(func(w io.Writer) Encoder { return json.NewEncoder(w) }),
+		Importer:   importerFunc(func(r io.Reader) Decoder { return json.NewDecoder(r) }),
+	},
+	{
+		Name:       "csv",
+		Native:     true,
+		OnConflict: task.ConflictNewestWins,
+		Exporter:   exporterFunc(func(w io.Writer) Encoder { return csv.NewEncoder(w) }),
+		Importer:   importerFunc(func(r io.Reader) Decoder { return csv.NewDecoder(r) }),
+	},
+	{
+		Name:       "todotxt",
+		OnConflict: task.ConflictSkip,
+		Exporter:   exporterFunc(func(w io.Writer) Encoder { return todotxt.NewEncoder(w) }),
+		Importer:   importerFunc(func(r io.Reader) Decoder { return todotxt.NewDecoder(r) }),
+	},
+	{
+		Name:       "taskwarrior",
+		OnConflict: task.ConflictNewestWins,
+		Exporter:   exporterFunc(func(w io.Writer) Encoder { return taskwarrior.NewEncoder(w) }),
+		Importer:   importerFunc(func(r io.Reader) Decoder { return taskwarrior.NewDecoder(r) }),
+	},
+	{
+		Name:       "ics",
+		OnConflict: task.ConflictSkip,
+		Exporter:   exporterFunc(func(w io.Writer) Encoder { return ics.NewEncoder(w) }),
+		Importer:   importerFunc(func(r io.Reader) Decoder { return ics.NewDecoder(r) }),
+	},
+}
+
+// ICSEvents writes tasks with a due date as all-day calendar events rather
+// than the to-dos of the ics format.
+var ICSEvents Exporter = exporterFunc(func(w io.Writer) Encoder { return ics.NewEventEncoder(w) })
+
+func Lookup(name string) (Format, error) {
+	for _, format := range formats {
+		if format.Name == name {
+			return format, nil
+		}
+	}
+	return Format{}, fmt.Errorf("unsupported file format %q: must be %s", name, strings.Join(Names(), ", "))
+}
+
+func Names() []string {
+	names := make([]string, len(formats))
+	for i, format := range formats {
+		names[i] = format.Name
+	}
+	return names
+}
+
+type exporterFunc func(w io.Writer) Encoder
+
+func (f exporterFunc) NewEncoder(w io.Writer) Encoder {
+	return f(w)
+}
+
+type importerFunc func(r io.Reader) Decoder
+
+func (f importerFunc) NewDecoder(r io.Reader) Decoder {
+	return f(r)
+}
@@ -0,0 +1,546 @@
+// Package ics reads and writes tasks as iCalendar (RFC 5545) files, so
+// that calendar applications can show them.
+//
+// Tasks are written as VTODO components by NewEncoder, or as all-day VEVENT
+// components on their due dates by NewEventEncoder. A Decoder reads VTODO
+// components back; events and other components are ignored. Task fields
+// iCalendar has no property for are kept in X-TASKS- properties.
+package ics
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+	"unicode"
+	"unicode/utf8"
+
+	// TZID parameters name IANA zones, which must resolve even where the
+	// system has no zoneinfo database.
+	_ "time/tzdata"
+
+	"github.com/google/uuid"
+	"github.com/ncfex/tasks/internal/task"
+	"github.com/ncfex/tasks/internal/utils"
+)
+
+const (
+	dateTimeLayout = "20060102T150405Z"
+	dateLayout     = "20060102"
+
+	// maxLineLength is the longest content line, in octets, before it is
+	// folded onto a continuation line.
+	maxLineLength = 75
+)
+
+// Encoder writes a calendar one task at a time. Close ends the calendar and
+// flushes the output.
+type Encoder struct {
+	cw      *writer
+	events  bool
+	started bool
+}
+
+// NewEncoder returns an Encoder that writes tasks as VTODO components.
+func NewEncoder(w io.Writer) *Encoder {
+	return &Encoder{cw: newWriter(w)}
+}
+
+// NewEventEncoder returns an Encoder that writes every task with a due date
+// as an all-day VEVENT on that date. Tasks without a due date are left out.
+func NewEventEncoder(w io.Writer) *Encoder {
+	return &Encoder{cw: newWriter(w), events: true}
+}
+
+func (e *Encoder) Encode(t *task.Task) error {
+	e.begin()
+	switch {
+	case !e.events:
+		writeTodo(e.cw, t)
+	case !t.DueDate.IsZero():
+		writeEvent(e.cw, t)
+	}
+	return e.cw.err
+}
+
+func (e *Encoder) Close() error {
+	e.begin()
+	return e.cw.end()
+}
+
+func (e *Encoder) begin() {
+	if !e.started {
+		e.cw.begin()
+		e.started = true
+	}
+}
+
+func writeTodo(cw *writer, t *task.Task) {
+	cw.line("BEGIN:VTODO")
+	writeCommon(cw, t)
+	if !t.DueDate.IsZero() {
+		cw.line("DUE:" + formatDateTime(t.DueDate))
+	}
+
+	switch t.Status {
+	case task.StatusInProgress:
+		cw.line("STATUS:IN-PROCESS")
+	case task.StatusDone:
+		cw.line("STATUS:COMPLETED")
+		if !t.CompletedAt.IsZero() {
+			cw.line("COMPLETED:" + formatDateTime(t.CompletedAt))
+		}
+	case task.StatusCancelled:
+		cw.line("STATUS:CANCELLED")
+	default:
+		cw.line("STATUS:NEEDS-ACTION")
+	}
+	if t.Status == task.StatusWaiting {
+		cw.line("X-TASKS-STATUS:" + string(t.Status))
+	}
+
+	if t.Priority != "" {
+		cw.line("PRIORITY:" + strconv.Itoa(formatPriority(t.Priority)))
+	}
+	if t.Project != "" {
+		cw.line("X-TASKS-PROJECT:" + escapeText(t.Project))
+	}
+	if t.Estimate > 0 {
+		cw.line("X-TASKS-ESTIMATE:" + t.Estimate.String())
+	}
+	cw.line("END:VTODO")
+}
+
+func writeEvent(cw *writer, t *task.Task) {
+	due := t.DueDate.Local()
+	start := time.Date(due.Year(), due.Month(), due.Day(), 0, 0, 0, 0, time.Local)
+
+	cw.line("BEGIN:VEVENT")
+	writeCommon(cw, t)
+	cw.line("DTSTART;VALUE=DATE:" + start.Format(dateLayout))
+	cw.line("DTEND;VALUE=DATE:" + start.AddDate(0, 0, 1).Format(dateLayout))
+	cw.line("TRANSP:TRANSPARENT")
+
+	// Events have no status for to-do progress, only CANCELLED, so the
+	// others are kept in X-TASKS- properties.
+	switch t.Status {
+	case task.StatusTodo:
+	case task.StatusCancelled:
+		cw.line("STATUS:CANCELLED")
+	default:
+		cw.line("X-TASKS-STATUS:" + string(t.Status))
+	}
+	if t.Status == task.StatusDone && !t.CompletedAt.IsZero() {
+		cw.line("X-TASKS-COMPLETED:" + formatDateTime(t.CompletedAt))
+	}
+	cw.line("END:VEVENT")
+}
+
+func writeCommon(cw *writer, t *task.Task) {
+	cw.line("UID:" + t.ID.String())
+	cw.line("DTSTAMP:" + formatDateTime(t.UpdatedAt))
+	cw.line("CREATED:" + formatDateTime(t.CreatedAt))
+	cw.line("LAST-MODIFIED:" + formatDateTime(t.UpdatedAt))
+	cw.line("SUMMARY:" + escapeText(t.Description))
+	if len(t.Tags) > 0 {
+		categories := make([]string, len(t.Tags))
+		for i, tag := range t.Tags {
+			categories[i] = escapeText(tag)
+		}
+		cw.line("CATEGORIES:" + strings.Join(categories, ","))
+	}
+}
+
+// formatPriority maps A to I onto the iCalendar priorities 1 (highest) to
+// 9; lower priorities are all 9.
+func formatPriority(priority string) int {
+	return min(int(priority[0]-'A')+1, 9)
+}
+
+// writer writes content lines, folding long ones, and remembers the first
+// error so that callers need only check the result of end.
+type writer struct {
+	bw  *bufio.Writer
+	err error
+}
+
+func newWriter(w io.Writer) *writer {
+	return &writer{bw: bufio.NewWriter(w)}
+}
+
+func (cw *writer) begin() {
+	cw.line("BEGIN:VCALENDAR")
+	cw.line("VERSION:2.0")
+	cw.line("PRODID:-//ncfex//tasks//EN")
+	cw.line("CALSCALE:GREGORIAN")
+}
+
+func (cw *writer) end() error {
+	cw.line("END:VCALENDAR")
+	if cw.err != nil {
+		return cw.err
+	}
+	return cw.bw.Flush()
+}
+
+func (cw *writer) line(s string) {
+	if cw.err != nil {
+		return
+	}
+
+	var b strings.Builder
+	width := 0
+	for _, r := range s {
+		size := utf8.RuneLen(r)
+		if width+size > maxLineLength {
+			b.WriteString("\r\n ")
+			width = 1
+		}
+		b.WriteRune(r)
+		width += size
+	}
+	b.WriteString("\r\n")
+
+	_, cw.err = cw.bw.WriteString(b.String())
+}
+
+func escapeText(s string) string {
+	return strings.NewReplacer(
+		`\`, `\\`,
+		";", `\;`,
+		",", `\,`,
+		"\n", `\n`,
+	).Replace(s)
+}
+
+func formatDateTime(t time.Time) string {
+	return t.UTC().Format(dateTimeLayout)
+}
+
+// Decoder reads the VTODO components of a calendar one at a time. To-dos
+// whose UID is not a UUID, as written by other applications, get an ID
+// derived from the UID, so that importing the same file again finds them.
+type Decoder struct {
+	lines *lineReader
+	now   time.Time
+
+	todo   []property
+	depth  int
+	start  int
+	inTodo bool
+}
+
+func NewDecoder(r io.Reader) *Decoder {
+	return &Decoder{lines: newLineReader(r), now: time.Now()}
+}
+
+// Decode returns the next to-do, or io.EOF after the last one.
+func (d *Decoder) Decode() (*task.Task, error) {
+	for {
+		line, err := d.lines.next()
+		if errors.Is(err, io.EOF) {
+			return nil, io.EOF
+		}
+		if err != nil {
+			return nil, fmt.Errorf("failed to read calendar: %w", err)
+		}
+
+		prop, err := parseProperty(line)
+		if err != nil {
+			return nil, fmt.Errorf("line %d: %w", d.lines.n, err)
+		}
+
+		switch {
+		case prop.name == "BEGIN":
+			d.depth++
+			if strings.EqualFold(prop.value, "VTODO") && !d.inTodo {
+				d.inTodo, d.todo, d.start = true, nil, d.depth
+			}
+		case prop.name == "END":
+			ended := d.inTodo && d.depth == d.start
+			d.depth--
+			if ended {
+				d.inTodo = false
+				t, err := toTask(d.todo, d.now)
+				if err != nil {
+					return nil, fmt.Errorf("to-do ending on line %d: %w", d.lines.n, err)
+				}
+				return &t, nil
+			}
+		case d.inTodo && d.depth == d.start:
+			// Properties of nested components, such as alarms, are skipped.
+			d.todo = append(d.todo, prop)
+		}
+	}
+}
+
+type property struct {
+	name   string
+	params map[string]string
+	value  string
+}
+
+// lineReader returns content lines, joining continuation lines, which
+// start with a space or tab, onto the line before them.
+type lineReader struct {
+	scanner *bufio.Scanner
+	pending string
+	// n counts the content lines returned so far.
+	n int
+}
+
+func newLineReader(r io.Reader) *lineReader {
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 0, 64*1024), 1024*1024)
+	return &lineReader{scanner: scanner}
+}
+
+func (lr *lineReader) next() (string, error) {
+	// A content line is only complete once the next one is read, to see
+	// whether it continues it.
+	for lr.scanner.Scan() {
+		line := strings.TrimRight(lr.scanner.Text(), "\r")
+		if line == "" {
+			continue
+		}
+		if (line[0] == ' ' || line[0] == '\t') && lr.pending != "" {
+			lr.pending += line[1:]
+			continue
+		}
+
+		complete := lr.pending
+		lr.pending = line
+		if complete != "" {
+			lr.n++
+			return complete, nil
+		}
+	}
+	if err := lr.scanner.Err(); err != nil {
+		return "", err
+	}
+
+	if lr.pending == "" {
+		return "", io.EOF
+	}
+	complete := lr.pending
+	lr.pending = ""
+	lr.n++
+	return complete, nil
+}
+
+func parseProperty(line string) (property, error) {
+	prop := property{params: make(map[string]string)}
+
+	// Parameter values may be quoted and contain colons, so find the first
+	// colon outside quotes.
+	quoted := false
+	colon := -1
+	for i, r := range line {
+		if r == '"' {
+			quoted = !quoted
+		} else if r == ':' && !quoted {
+			colon = i
+			break
+		}
+	}
+	if colon < 0 {
+		return prop, fmt.Errorf("invalid content line %q", line)
+	}
+	prop.value = line[colon+1:]
+
+	parts := strings.Split(line[:colon], ";")
+	prop.name = strings.ToUpper(parts[0])
+	for _, param := range parts[1:] {
+		key, value, _ := strings.Cut(param, "=")
+		prop.params[strings.ToUpper(key)] = strings.Trim(value, `"`)
+	}
+
+	return prop, nil
+}
+
+func toTask(props []property, now time.Time) (task.Task, error) {
+	t := task.Task{Status: task.StatusTodo}
+	var summary, description, uid, status string
+	var err error
+
+	for _, prop := range props {
+		switch prop.name {
+		case "UID":
+			uid = prop.value
+		case "SUMMARY":
+			summary = unescapeText(prop.value)
+		case "DESCRIPTION":
+			description = unescapeText(prop.value)
+		case "STATUS":
+			status = strings.ToUpper(prop.value)
+		case "X-TASKS-STATUS":
+			if t.Status, err = task.ParseStatus(prop.value); err != nil {
+				return t, err
+			}
+			status = "X-TASKS"
+		case "CREATED":
+			t.CreatedAt, err = parseTime(prop)
+		case "LAST-MODIFIED":
+			t.UpdatedAt, err = parseTime(prop)
+		case "DUE":
+			t.DueDate, err = parseTime(prop)
+		case "COMPLETED":
+			t.CompletedAt, err = parseTime(prop)
+		case "PRIORITY":
+			t.Priority, err = parsePriority(prop.value)
+		case "CATEGORIES":
+			for _, category := range splitList(prop.value) {
+				// Tags cannot contain spaces, categories can.
+				tag := strings.Join(strings.Fields(category), "-")
+				if tag != "" && !slices.Contains(t.Tags, tag) {
+					t.Tags = append(t.Tags, tag)
+				}
+			}
+		case "X-TASKS-PROJECT":
+			t.Project = unescapeText(prop.value)
+		case "X-TASKS-ESTIMATE":
+			t.Estimate, err = utils.ParseExactDuration(prop.value)
+		}
+		if err != nil {
+			return t, fmt.Errorf("invalid %s %q", prop.name, prop.value)
+		}
+	}
+
+	if uid == "" {
+		return t, fmt.Errorf("missing UID")
+	}
+	if t.ID, err = uuid.Parse(uid); err != nil {
+		t.ID = uuid.NewSHA1(uuid.NameSpaceURL, []byte(uid))
+	}
+
+	t.Description = summary
+	if description != "" {
+		if t.Description == "" {
+			t.Description = description
+		} else {
+			t.Description += " -- " + description
+		}
+	}
+	// Descriptions are a single line.
+	t.Description = strings.Join(strings.Fields(t.Description), " ")
+
+	switch status {
+	case "IN-PROCESS":
+		t.Status = task.StatusInProgress
+	case "COMPLETED":
+		t.Status = task.StatusDone
+	case "CANCELLED":
+		t.Status = task.StatusCancelled
+	case "", "NEEDS-ACTION":
+		if !t.CompletedAt.IsZero() {
+			t.Status = task.StatusDone
+		}
+	case "X-TASKS":
+	default:
+		return t, fmt.Errorf("unknown STATUS %q", status)
+	}
+
+	if t.CreatedAt.IsZero() {
+		t.CreatedAt = now
+	}
+	if t.Status == task.StatusDone {
+		if t.CompletedAt.IsZero() {
+			t.CompletedAt = now
+		}
+	} else {
+		t.CompletedAt = time.Time{}
+	}
+	t.FillUpdatedAt()
+
+	if err := t.Validate(); err != nil {
+		return t, err
+	}
+
+	return t, nil
+}
+
+// parseTime reads a DATE or DATE-TIME value, which is either UTC, in the
+// zone named by TZID, or floating and taken as local time.
+func parseTime(prop property) (time.Time, error) {
+	loc := time.Local
+	if tzid := prop.params["TZID"]; tzid != "" {
+		if l, err := time.LoadLocation(tzid); err == nil {
+			loc = l
+		}
+	}
+
+	value := prop.value
+	switch {
+	case prop.params["VALUE"] == "DATE" || len(value) == len(dateLayout):
+		return time.ParseInLocation(dateLayout, value, loc)
+	case strings.HasSuffix(value, "Z"):
+		return time.Parse(dateTimeLayout, value)
+	default:
+		return time.ParseInLocation(strings.TrimSuffix(dateTimeLayout, "Z"), value, loc)
+	}
+}
+
+// parsePriority maps the iCalendar priorities 1 (highest) to 9 onto A to
+// I. Zero means no priority.
+func parsePriority(value string) (string, error) {
+	n, err := strconv.Atoi(value)
+	if err != nil || n < 0 || n > 9 {
+		return "", fmt.Errorf("invalid priority %q", value)
+	}
+	if n == 0 {
+		return "", nil
+	}
+	return string(rune('A' + n - 1)), nil
+}
+
+// splitList splits a comma-separated TEXT list, leaving escaped commas in
+// place.
+func splitList(value string) []string {
+	var items []string
+	var b strings.Builder
+	escaped := false
+	for _, r := range value {
+		switch {
+		case escaped:
+			b.WriteRune('\\')
+			b.WriteRune(r)
+			escaped = false
+		case r == '\\':
+			escaped = true
+		case r == ',':
+			items = append(items, unescapeText(b.String()))
+			b.Reset()
+		default:
+			b.WriteRune(r)
+		}
+	}
+	items = append(items, unescapeText(b.String()))
+
+	return slices.DeleteFunc(items, func(item string) bool {
+		return strings.TrimFunc(item, unicode.IsSpace) == ""
+	})
+}
+
+func unescapeText(s string) string {
+	var b strings.Builder
+	escaped := false
+	for _, r := range s {
+		switch {
+		case escaped:
+			if r == 'n' || r == 'N' {
+				r = '\n'
+			}
+			b.WriteRune(r)
+			escaped = false
+		case r == '\\':
+			escaped = true
+		default:
+			b.WriteRune(r)
+		}
+	}
+	return b.String()
+}
@@ -0,0 +1,290 @@
+package ics_test
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/ncfex/tasks/internal/ics"
+	"github.com/ncfex/tasks/internal/task"
+)
+
+// reminders is a to-do list as exported by a CalDAV client: CRLF line
+// endings, folded lines, a VTIMEZONE, a TZID on the due time, an alarm
+// with its own DESCRIPTION, and an event that import skips.
+var reminders = strings.ReplaceAll(`BEGIN:VCALENDAR
+VERSION:2.0
+PRODID:-//Nextcloud Tasks v0.15.0
+CALSCALE:GREGORIAN
+BEGIN:VTIMEZONE
+TZID:Europe/Berlin
+BEGIN:DAYLIGHT
+TZOFFSETFROM:+0100
+TZOFFSETTO:+0200
+TZNAME:CEST
+DTSTART:19700329T020000
+RRULE:FREQ=YEARLY;BYMONTH=3;BYDAY=-1SU
+END:DAYLIGHT
+BEGIN:STANDARD
+TZOFFSETFROM:+0200
+TZOFFSETTO:+0100
+TZNAME:CET
+DTSTART:19701025T030000
+RRULE:FREQ=YEARLY;BYMONTH=10;BYDAY=-1SU
+END:STANDARD
+END:VTIMEZONE
+BEGIN:VTODO
+UID:20240520T091500Z-4711@example.com
+DTSTAMP:20240521T101010Z
+CREATED:20240520T091500Z
+LAST-MODIFIED:20240521T101010Z
+SUMMARY:Prepare the quarterly report for the board meeting\, including the
+  budget figures
+DUE;TZID=Europe/Berlin:20240603T170000
+PRIORITY:1
+STATUS:IN-PROCESS
+PERCENT-COMPLETE:40
+CATEGORIES:Work,Q2 planning
+BEGIN:VALARM
+ACTION:DISPLAY
+TRIGGER;RELATED=END:-PT15M
+DESCRIPTION:Reminder
+END:VALARM
+END:VTODO
+BEGIN:VTODO
+UID:0F6A4B1E-2C1D-4D8E-9A3B-5C6D7E8F9A0B
+DTSTAMP:20240521T120000Z
+CREATED:20240520T091900Z
+SUMMARY:Buy milk
+STATUS:COMPLETED
+COMPLETED:20240521T120000Z
+END:VTODO
+BEGIN:VTODO
+UID:8c1f2e3d-4b5a-4697-8877-665544332211
+DTSTAMP:20240520T092000Z
+CREATED:20240520T092000Z
+SUMMARY:Renew passport
+DUE;VALUE=DATE:20240610
+END:VTODO
+BEGIN:VEVENT
+UID:event-1@example.com
+DTSTAMP:20240520T092000Z
+DTSTART;VALUE=DATE:20240611
+SUMMARY:Dentist
+END:VEVENT
+END:VCALENDAR
+`, "\n", "\r\n")
+
+func TestDecodeReminders(t *testing.T) {
+	tasks, err := decodeAll(ics.NewDecoder(strings.NewReader(reminders)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(tasks) != 3 {
+		t.Fatalf("Decode() = %d tasks, want the 3 to-dos", len(tasks))
+	}
+
+	berlin, err := time.LoadLocation("Europe/Berlin")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	report := tasks[0]
+	if want := uuid.NewSHA1(uuid.NameSpaceURL, []byte("20240520T091500Z-4711@example.com")); report.ID != want {
+		t.Errorf("report ID = %s, want %s derived from the UID", report.ID, want)
+	}
+	if want := "Prepare the quarterly report for the board meeting, including the budget figures"; report.Description != want {
+		t.Errorf("report description = %q, want %q", report.Description, want)
+	}
+	if want := time.Date(2024, 6, 3, 17, 0, 0, 0, berlin); !report.DueDate.Equal(want) {
+		t.Errorf("report due = %s, want %s", report.DueDate, want)
+	}
+	if report.Status != task.StatusInProgress || report.Priority != "A" || !slices.Equal(report.Tags, []string{"Work", "Q2-planning"}) {
+		t.Errorf("report = %+v", report)
+	}
+
+	milk := tasks[1]
+	if milk.ID != uuid.MustParse("0f6a4b1e-2c1d-4d8e-9a3b-5c6d7e8f9a0b") || milk.Status != task.StatusDone ||
+		!milk.CompletedAt.Equal(time.Date(2024, 5, 21, 12, 0, 0, 0, time.UTC)) {
+		t.Errorf("milk = %+v", milk)
+	}
+
+	passport := tasks[2]
+	if want := time.Date(2024, 6, 10, 0, 0, 0, 0, time.Local); passport.Status != task.StatusTodo || !passport.DueDate.Equal(want) {
+		t.Errorf("passport = %+v, want due on %s", passport, want)
+	}
+}
+
+func TestEncodeDecodeRoundTrip(t *testing.T) {
+	created := time.Date(2024, 5, 20, 9, 15, 0, 0, time.UTC)
+	base := task.Task{
+		Description: "pay rent",
+		Status:      task.StatusTodo,
+		CreatedAt:   created,
+		UpdatedAt:   created.Add(time.Hour),
+	}
+
+	tests := []struct {
+		name   string
+		change func(t *task.Task)
+	}{
+		{"todo", func(t *task.Task) {}},
+		{"fields", func(t *task.Task) {
+			t.Priority = "C"
+			t.Project = "home"
+			t.Tags = []string{"bills", "monthly"}
+			t.DueDate = created.Add(48 * time.Hour)
+			t.Estimate = 90 * time.Minute
+		}},
+		{"estimate under a minute", func(t *task.Task) { t.Estimate = 10 * time.Second }},
+		{"estimate between minutes", func(t *task.Task) { t.Estimate = 90 * time.Second }},
+		{"escaped text", func(t *task.Task) { t.Description = `rent; deposit, and fees \ extras` }},
+		{"long text", func(t *task.Task) { t.Description = strings.Repeat("grüße aus dem büro ", 12) + "ende" }},
+		{"in progress", func(t *task.Task) { t.Status = task.StatusInProgress }},
+		{"waiting", func(t *task.Task) { t.Status = task.StatusWaiting }},
+		{"done", func(t *task.Task) {
+			t.Status = task.StatusDone
+			t.CompletedAt = created.Add(time.Hour)
+		}},
+		{"cancelled", func(t *task.Task) { t.Status = task.StatusCancelled }},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			want := base
+			want.ID = uuid.New()
+			tt.change(&want)
+
+			var buf bytes.Buffer
+			if err := encodeAll(ics.NewEncoder(&buf), []task.Task{want}); err != nil {
+				t.Fatal(err)
+			}
+			for _, line := range strings.Split(buf.String(), "\r\n") {
+				if len(line) > 75 {
+					t.Errorf("line of %d octets is not folded: %q", len(line), line)
+				}
+			}
+
+			tasks, err := decodeAll(ics.NewDecoder(&buf))
+			if err != nil {
+				t.Fatalf("Decode(): %v", err)
+			}
+			if len(tasks) != 1 {
+				t.Fatalf("Decode() = %d tasks, want 1", len(tasks))
+			}
+
+			got := tasks[0]
+			switch {
+			case got.ID != want.ID:
+				t.Errorf("ID = %s, want %s", got.ID, want.ID)
+			case got.Description != want.Description:
+				t.Errorf("Description = %q, want %q", got.Description, want.Description)
+			case got.Status != want.Status:
+				t.Errorf("Status = %q, want %q", got.Status, want.Status)
+			case got.Priority != want.Priority:
+				t.Errorf("Priority = %q, want %q", got.Priority, want.Priority)
+			case got.Project != want.Project:
+				t.Errorf("Project = %q, want %q", got.Project, want.Project)
+			case !slices.Equal(got.Tags, want.Tags):
+				t.Errorf("Tags = %v, want %v", got.Tags, want.Tags)
+			case got.Estimate != want.Estimate:
+				t.Errorf("Estimate = %s, want %s", got.Estimate, want.Estimate)
+			case !got.CreatedAt.Equal(want.CreatedAt):
+				t.Errorf("CreatedAt = %s, want %s", got.CreatedAt, want.CreatedAt)
+			case !got.UpdatedAt.Equal(want.UpdatedAt):
+				t.Errorf("UpdatedAt = %s, want %s", got.UpdatedAt, want.UpdatedAt)
+			case !got.DueDate.Equal(want.DueDate):
+				t.Errorf("DueDate = %s, want %s", got.DueDate, want.DueDate)
+			case !got.CompletedAt.Equal(want.CompletedAt):
+				t.Errorf("CompletedAt = %s, want %s", got.CompletedAt, want.CompletedAt)
+			}
+		})
+	}
+}
+
+func TestEncodeEventsKeepsStatus(t *testing.T) {
+	due := time.Date(2024, 6, 3, 12, 0, 0, 0, time.UTC)
+	tasks := []task.Task{
+		{ID: uuid.New(), Description: "open", Status: task.StatusTodo, DueDate: due},
+		{ID: uuid.New(), Description: "done", Status: task.StatusDone, DueDate: due, CompletedAt: due.Add(-time.Hour)},
+		{ID: uuid.New(), Description: "dropped", Status: task.StatusCancelled, DueDate: due},
+		{ID: uuid.New(), Description: "no due date", Status: task.StatusTodo},
+	}
+
+	var buf bytes.Buffer
+	if err := encodeAll(ics.NewEventEncoder(&buf), tasks); err != nil {
+		t.Fatal(err)
+	}
+	events := strings.Split(buf.String(), "BEGIN:VEVENT")[1:]
+	if len(events) != 3 {
+		t.Fatalf("EncodeEvents() wrote %d events, want 3", len(events))
+	}
+
+	for i, want := range [][]string{
+		{"SUMMARY:open"},
+		{"SUMMARY:done", "X-TASKS-STATUS:done", "X-TASKS-COMPLETED:20240603T110000Z"},
+		{"SUMMARY:dropped", "STATUS:CANCELLED"},
+	} {
+		for _, line := range want {
+			if !strings.Contains(events[i], line+"\r\n") {
+				t.Errorf("event %d lacks %s:\n%s", i, line, events[i])
+			}
+		}
+	}
+	if strings.Contains(events[0], "STATUS") {
+		t.Errorf("open event has a status:\n%s", events[0])
+	}
+}
+
+type failingReader struct{}
+
+func (failingReader) Read([]byte) (int, error) {
+	return 0, errors.New("connection reset")
+}
+
+// A to-do is returned once the line after its end is read, before the rest
+// of the calendar.
+func TestDecoderStreams(t *testing.T) {
+	first, _, _ := strings.Cut(reminders, "UID:0F6A4B1E")
+	dec := ics.NewDecoder(io.MultiReader(strings.NewReader(first), failingReader{}))
+
+	report, err := dec.Decode()
+	if err != nil {
+		t.Fatalf("first Decode(): %v", err)
+	}
+	if !strings.HasPrefix(report.Description, "Prepare the quarterly report") {
+		t.Errorf("first Decode() = %q", report.Description)
+	}
+	if _, err := dec.Decode(); err == nil || errors.Is(err, io.EOF) {
+		t.Errorf("second Decode() error = %v, want the read error", err)
+	}
+}
+
+func encodeAll(enc *ics.Encoder, tasks []task.Task) error {
+	for i := range tasks {
+		if err := enc.Encode(&tasks[i]); err != nil {
+			return err
+		}
+	}
+	return enc.Close()
+}
+
+// decodeAll reads every task dec hands out.
+func decodeAll(dec *ics.Decoder) ([]task.Task, error) {
+	var tasks []task.Task
+	for {
+		t, err := dec.Decode()
+		if errors.Is(err, io.EOF) {
+			return tasks, nil
+		}
+		if err != nil {
+			return nil, err
+		}
+		tasks = append(tasks, *t)
+	}
+}
@@ -0,0 +1,121 @@
+package audit
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+
+	"github.com/google/uuid"
+	"github.com/ncfex/tasks/internal/storage/fileformat"
+	"github.com/ncfex/tasks/internal/task"
+)
+
+type auditLog struct {
+	filepath string
+	mu       sync.Mutex
+}
+
+// NewAuditLog returns an append-only audit log kept as JSON lines, used by
+// the file-based storage backends.
+func NewAuditLog(filepath string) task.AuditLog {
+	return &auditLog{
+		filepath: filepath,
+	}
+}
+
+func (l *auditLog) Record(ctx context.Context, event task.AuditEvent) error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if err := os.MkdirAll(filepath.Dir(l.filepath), 0755); err != nil {
+		return fmt.Errorf("failed to create directory: %w", err)
+	}
+
+	file, err := os.OpenFile(l.filepath, os.O_WRONLY|os.O_CREATE|os.O_APPEND, 0644)
+	if err != nil {
+		return fmt.Errorf("failed to open audit log: %w", err)
+	}
+	defer file.Close()
+
+	if err := json.NewEncoder(file).Encode(event); err != nil {
+		return fmt.Errorf("encode audit event: %w", err)
+	}
+
+	return nil
+}
+
+// BindTx returns an audit log that records only once the transaction of
+// repo, a repository handed to the WithTx callback of a file backend, has
+// written the tasks file.
+func (l *auditLog) BindTx(repo task.Repository) task.AuditLog {
+	committer, ok := repo.(fileformat.Committer)
+	if !ok {
+		return l
+	}
+	return &txAuditLog{auditLog: l, committer: committer}
+}
+
+type txAuditLog struct {
+	*auditLog
+	committer fileformat.Committer
+}
+
+func (l *txAuditLog) Record(ctx context.Context, event task.AuditEvent) error {
+	l.committer.OnCommit(func() error {
+		return l.auditLog.Record(context.WithoutCancel(ctx), event)
+	})
+	return nil
+}
+
+func (l *auditLog) History(ctx context.Context, taskID uuid.UUID) ([]task.AuditEvent, error) {
+	histories, err := l.Histories(ctx, []uuid.UUID{taskID})
+	if err != nil {
+		return nil, err
+	}
+	return histories[taskID], nil
+}
+
+// Histories reads the log once for all of taskIDs.
+func (l *auditLog) Histories(ctx context.Context, taskIDs []uuid.UUID) (map[uuid.UUID][]task.AuditEvent, error) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	histories := make(map[uuid.UUID][]task.AuditEvent, len(taskIDs))
+	if len(taskIDs) == 0 {
+		return histories, nil
+	}
+	wanted := make(map[uuid.UUID]bool, len(taskIDs))
+	for _, id := range taskIDs {
+		wanted[id] = true
+	}
+
+	file, err := os.Open(l.filepath)
+	if os.IsNotExist(err) {
+		return histories, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to open audit log: %w", err)
+	}
+	defer file.Close()
+
+	scanner := bufio.NewScanner(file)
+	scanner.Buffer(make([]byte, 0, 64*1024), 1024*1024)
+	for scanner.Scan() {
+		var event task.AuditEvent
+		if err := json.Unmarshal(scanner.Bytes(), &event); err != nil {
+			return nil, fmt.Errorf("decode audit event: %w", err)
+		}
+		if wanted[event.TaskID] {
+			histories[event.TaskID] = append(histories[event.TaskID], event)
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read audit log: %w", err)
+	}
+
+	return histories, nil
+}
@@ -0,0 +1,119 @@
+package csv
+
+import (
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+
+	"github.com/ncfex/tasks/internal/task"
+)
+
+// Encoder writes tasks in the tasks.csv format one at a time, so that a
+// dump of any size can be written without holding it in memory. The
+// output can be used as a tasks.csv file.
+type Encoder struct {
+	writer  *csv.Writer
+	started bool
+}
+
+func NewEncoder(w io.Writer) *Encoder {
+	return &Encoder{writer: csv.NewWriter(w)}
+}
+
+func (e *Encoder) Encode(t *task.Task) error {
+	if err := e.start(); err != nil {
+		return err
+	}
+	return e.writer.Write(encodeTask(t))
+}
+
+// Close writes the header rows if no task was encoded and flushes the
+// output.
+func (e *Encoder) Close() error {
+	if err := e.start(); err != nil {
+		return err
+	}
+	e.writer.Flush()
+	return e.writer.Error()
+}
+
+// start writes the format and header rows. The task number counter is left
+// out, since it is derived from the numbers in use when the file is read.
+func (e *Encoder) start() error {
+	if e.started {
+		return nil
+	}
+	e.started = true
+
+	if err := e.writer.Write([]string{formatMarker, strconv.Itoa(formatVersion)}); err != nil {
+		return err
+	}
+	return e.writer.Write(header)
+}
+
+// Decoder reads tasks written by Encoder, or a tasks.csv file in the
+// current format, one at a time.
+type Decoder struct {
+	reader *csv.Reader
+	index  map[string]int
+}
+
+func NewDecoder(r io.Reader) *Decoder {
+	reader := csv.NewReader(r)
+	reader.FieldsPerRecord = -1
+	return &Decoder{reader: reader}
+}
+
+// Decode returns the next task, or io.EOF after the last one. Unlike the
+// repository, it stops at the first row it cannot decode.
+func (d *Decoder) Decode() (*task.Task, error) {
+	if d.index == nil {
+		if err := d.readHeader(); err != nil {
+			return nil, err
+		}
+	}
+
+	record, err := d.reader.Read()
+	if errors.Is(err, io.EOF) {
+		return nil, io.EOF
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read CSV: %w", err)
+	}
+
+	t, err := decodeTask(record, d.index)
+	if err != nil {
+		line, _ := d.reader.FieldPos(0)
+		return nil, fmt.Errorf("line %d: %w", line, err)
+	}
+
+	return &t, nil
+}
+
+func (d *Decoder) readHeader() error {
+	first, err := d.reader.Read()
+	if err != nil {
+		return fmt.Errorf("invalid header: %w", err)
+	}
+	if len(first) < 2 || first[0] != formatMarker {
+		return fmt.Errorf("invalid header: missing %s row", formatMarker)
+	}
+	if version, err := strconv.Atoi(first[1]); err != nil || version != formatVersion {
+		return fmt.Errorf("unsupported format version %q: open the file with the csv backend to upgrade it", first[1])
+	}
+
+	headerRow, err := d.reader.Read()
+	if err != nil {
+		return fmt.Errorf("invalid header: %w", err)
+	}
+	d.index = columnIndex(headerRow)
+	for _, name := range requiredColumns {
+		if _, ok := d.index[name]; !ok {
+			return fmt.Errorf("invalid header: missing column %q", name)
+		}
+	}
+
+	return nil
+}
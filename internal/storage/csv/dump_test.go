@@ -0,0 +1,19 @@
+package csv_test
+
+import (
+	"io"
+	"testing"
+
+	"github.com/ncfex/tasks/internal/storage/csv"
+	"github.com/ncfex/tasks/internal/storage/storagetest"
+	"github.com/ncfex/tasks/internal/task"
+)
+
+func TestDump(t *testing.T) {
+	storagetest.RunDump(t, storagetest.Dump{
+		File:       "tasks.csv",
+		NewEncoder: func(w io.Writer) storagetest.Encoder { return csv.NewEncoder(w) },
+		NewDecoder: func(r io.Reader) storagetest.Decoder { return csv.NewDecoder(r) },
+		Open:       func(path string) task.Repository { return csv.NewRepository(path) },
+	})
+}
@@ -0,0 +1,184 @@
+package csv
+
+import (
+	"bytes"
+	"encoding/csv"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+
+	"github.com/ncfex/tasks/internal/storage/fileformat"
+	"github.com/ncfex/tasks/internal/task"
+)
+
+// formatVersion is the current layout of tasks.csv:
+//
+//	1: headerless rows with a true/false completion column
+//	2: a "#format,<version>" row, a header row, then one row per task
+//	3: a "num" column, and the format row ends with "next_num=<n>"
+//
+// Columns are read by name, so a column that may be missing, such as
+// "version" or "tags", can be added without a new format version.
+const formatVersion = 3
+
+const (
+	formatMarker = "#format"
+	nextNumKey   = "next_num"
+)
+
+var header = []string{
+	"id",
+	"num",
+	"description",
+	"status",
+	"created_at",
+	"updated_at",
+	"due_date",
+	"estimate",
+	"completed_at",
+	"deleted_at",
+	"version",
+	"priority",
+	"project",
+	"tags",
+	"annotations",
+}
+
+var pipeline = fileformat.Pipeline{
+	Current: formatVersion,
+	Steps: map[int]fileformat.UpgradeFunc{
+		1: upgradeV1,
+		2: upgradeV2,
+	},
+}
+
+func detectVersion(data []byte) (int, error) {
+	reader := csv.NewReader(bytes.NewReader(data))
+	reader.FieldsPerRecord = -1
+
+	first, err := reader.Read()
+	if err == io.EOF {
+		return formatVersion, nil
+	}
+	if err != nil || len(first) == 0 || first[0] != formatMarker {
+		return 1, nil
+	}
+
+	if len(first) < 2 {
+		return 0, fmt.Errorf("missing format version")
+	}
+
+	version, err := strconv.Atoi(first[1])
+	if err != nil {
+		return 0, fmt.Errorf("invalid format version %q: %w", first[1], err)
+	}
+
+	return version, nil
+}
+
+// detectNextNum returns the task number counter from the format row, or 0
+// if there is none.
+func detectNextNum(data []byte) int {
+	reader := csv.NewReader(bytes.NewReader(data))
+	reader.FieldsPerRecord = -1
+
+	first, err := reader.Read()
+	if err != nil || len(first) < 2 || first[0] != formatMarker {
+		return 0
+	}
+
+	for _, option := range first[2:] {
+		key, value, _ := strings.Cut(option, "=")
+		if key != nextNumKey {
+			continue
+		}
+		if num, err := strconv.Atoi(value); err == nil {
+			return num
+		}
+	}
+
+	return 0
+}
+
+// upgradeV1 adds the version and header rows and replaces the completion
+// flag with a status. Rows it cannot make sense of are carried over as-is.
+func upgradeV1(data []byte) ([]byte, error) {
+	reader := csv.NewReader(bytes.NewReader(data))
+	reader.FieldsPerRecord = -1
+	records, err := reader.ReadAll()
+	if err != nil {
+		return nil, fmt.Errorf("failed to read CSV: %w", err)
+	}
+
+	headerV2 := []string{"id", "description", "status", "created_at", "due_date", "estimate", "completed_at", "deleted_at"}
+	upgraded := [][]string{{formatMarker, "2"}, headerV2}
+	for _, record := range records {
+		if len(record) >= 5 {
+			if completed, err := strconv.ParseBool(record[2]); err == nil {
+				record[2] = string(task.StatusTodo)
+				if completed {
+					record[2] = string(task.StatusDone)
+				}
+			}
+			for len(record) < len(headerV2) {
+				record = append(record, "")
+			}
+		}
+		upgraded = append(upgraded, record)
+	}
+
+	return encodeRecords(upgraded)
+}
+
+// upgradeV2 adds a num column, numbering the existing rows in file order.
+// Rows short of trailing empty fields are padded first, so that the number
+// lands in its column.
+func upgradeV2(data []byte) ([]byte, error) {
+	reader := csv.NewReader(bytes.NewReader(data))
+	reader.FieldsPerRecord = -1
+	records, err := reader.ReadAll()
+	if err != nil {
+		return nil, fmt.Errorf("failed to read CSV: %w", err)
+	}
+	if len(records) < 2 {
+		return nil, fmt.Errorf("missing header row")
+	}
+
+	headerV2 := records[1]
+	rows := records[2:]
+	upgraded := make([][]string, 0, len(records))
+	upgraded = append(upgraded, formatRow(len(rows)+1), append(headerV2, "num"))
+	for i, record := range rows {
+		for len(record) < len(headerV2) {
+			record = append(record, "")
+		}
+		upgraded = append(upgraded, append(record, strconv.Itoa(i+1)))
+	}
+
+	return encodeRecords(upgraded)
+}
+
+func encodeRecords(records [][]string) ([]byte, error) {
+	var buf bytes.Buffer
+	writer := csv.NewWriter(&buf)
+	if err := writer.WriteAll(records); err != nil {
+		return nil, fmt.Errorf("failed to write CSV: %w", err)
+	}
+
+	return buf.Bytes(), nil
+}
+
+func formatRow(nextNum int) []string {
+	return []string{formatMarker, strconv.Itoa(formatVersion), nextNumKey + "=" + strconv.Itoa(nextNum)}
+}
+
+// columnIndex maps header names to their position so columns can be read by
+// name.
+func columnIndex(headerRow []string) map[string]int {
+	index := make(map[string]int, len(headerRow))
+	for i, name := range headerRow {
+		index[name] = i
+	}
+	return index
+}
@@ -0,0 +1,85 @@
+package csv_test
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/ncfex/tasks/internal/storage/csv"
+	"github.com/ncfex/tasks/internal/task"
+)
+
+const (
+	v1File = `0b6c2d4e-1f3a-4b5c-8d7e-9f0a1b2c3d4e,buy milk,false,2024-03-01T09:00:00Z,0001-01-01T00:00:00Z
+5e8f1a2b-3c4d-4e5f-a6b7-c8d9e0f1a2b3,file taxes,true,2024-03-02T09:00:00Z,2024-04-15T00:00:00Z
+`
+	v2File = `#format,2
+id,description,status,created_at,due_date,estimate,completed_at,deleted_at
+0b6c2d4e-1f3a-4b5c-8d7e-9f0a1b2c3d4e,buy milk,todo,2024-03-01T09:00:00Z,0001-01-01T00:00:00Z,,,
+5e8f1a2b-3c4d-4e5f-a6b7-c8d9e0f1a2b3,file taxes,done,2024-03-02T09:00:00Z,2024-04-15T00:00:00Z,,2024-03-05T10:00:00Z,
+`
+	// v2ShortRows leaves out trailing empty fields, as hand-edited files do.
+	v2ShortRows = `#format,2
+id,description,status,created_at,due_date,estimate,completed_at,deleted_at
+0b6c2d4e-1f3a-4b5c-8d7e-9f0a1b2c3d4e,buy milk,todo,2024-03-01T09:00:00Z,0001-01-01T00:00:00Z
+5e8f1a2b-3c4d-4e5f-a6b7-c8d9e0f1a2b3,file taxes,done,2024-03-02T09:00:00Z,2024-04-15T00:00:00Z,,2024-03-05T10:00:00Z
+`
+)
+
+func TestUpgrade(t *testing.T) {
+	tests := []struct {
+		name    string
+		content string
+		backup  string
+	}{
+		{name: "v1", content: v1File, backup: "tasks.csv.v1-*.bak"},
+		{name: "v2", content: v2File, backup: "tasks.csv.v2-*.bak"},
+		{name: "v2 short rows", content: v2ShortRows, backup: "tasks.csv.v2-*.bak"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir := t.TempDir()
+			path := filepath.Join(dir, "tasks.csv")
+			if err := os.WriteFile(path, []byte(tt.content), 0644); err != nil {
+				t.Fatal(err)
+			}
+
+			tasks, err := csv.NewRepository(path).List(context.Background(), task.NewTaskSelector(), &task.TaskFilter{IncludeCompleted: true})
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(tasks) != 2 {
+				t.Fatalf("List() = %d tasks, want 2", len(tasks))
+			}
+			byDescription := map[string]task.Task{}
+			for _, tk := range tasks {
+				byDescription[tk.Description] = tk
+			}
+			if milk := byDescription["buy milk"]; milk.Num != 1 || milk.Status != task.StatusTodo {
+				t.Errorf("buy milk = #%d %s, want #1 todo", milk.Num, milk.Status)
+			}
+			if taxes := byDescription["file taxes"]; taxes.Num != 2 || taxes.Status != task.StatusDone || taxes.DueDate.IsZero() {
+				t.Errorf("file taxes = #%d %s due %v, want #2 done with a due date", taxes.Num, taxes.Status, taxes.DueDate)
+			}
+
+			data, err := os.ReadFile(path)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if first, _, _ := strings.Cut(string(data), "\n"); first != "#format,3,next_num=3" {
+				t.Errorf("upgraded format row = %q, want %q", first, "#format,3,next_num=3")
+			}
+
+			backups, _ := filepath.Glob(filepath.Join(dir, tt.backup))
+			if len(backups) != 1 {
+				t.Fatalf("backups = %v, want one matching %s", backups, tt.backup)
+			}
+			if got, _ := os.ReadFile(backups[0]); string(got) != tt.content {
+				t.Errorf("backup = %q, want the original file", got)
+			}
+		})
+	}
+}
@@ -0,0 +1,332 @@
+package csv
+
+import (
+	"bytes"
+	"context"
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/ncfex/tasks/internal/storage/fileformat"
+	"github.com/ncfex/tasks/internal/task"
+)
+
+// RowError describes a row of a tasks file that could not be decoded. A row
+// that is not valid CSV at all, such as one with broken quoting, has no
+// Record; Raw holds its text instead, which may span several lines.
+type RowError struct {
+	Line   int
+	Record []string
+	Raw    string
+	Reason string
+}
+
+// ParseError lists every row of a tasks file that could not be decoded.
+type ParseError struct {
+	Path string
+	Rows []RowError
+}
+
+func (e *ParseError) Error() string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "%s has %d invalid row(s)", e.Path, len(e.Rows))
+	for _, row := range e.Rows {
+		fmt.Fprintf(&b, "\n  line %d: %s", row.Line, row.Reason)
+	}
+	return b.String()
+}
+
+var requiredColumns = []string{"id", "description", "status", "created_at", "due_date"}
+
+// decode parses a file in the current format. Rows that cannot be decoded
+// are collected rather than stopping the parse, so the caller can report or
+// quarantine all of them at once.
+func decode(data []byte) ([]task.Task, []RowError, error) {
+	reader := csv.NewReader(bytes.NewReader(data))
+	reader.FieldsPerRecord = -1
+
+	var index map[string]int
+	var columns int
+	var tasks []task.Task
+	var rowErrors []RowError
+
+	for n := 0; ; {
+		record, err := reader.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			var parseErr *csv.ParseError
+			if !errors.As(err, &parseErr) {
+				return nil, nil, fmt.Errorf("failed to read CSV: %w", err)
+			}
+			if n < 2 {
+				return nil, nil, fmt.Errorf("invalid header: %w", err)
+			}
+			rowErrors = append(rowErrors, RowError{
+				Line:   parseErr.StartLine,
+				Raw:    rawLines(data, parseErr.StartLine, parseErr.Line),
+				Reason: parseErr.Err.Error(),
+			})
+			continue
+		}
+		n++
+
+		switch n {
+		case 1:
+			continue
+		case 2:
+			index = columnIndex(record)
+			columns = len(record)
+			for _, name := range requiredColumns {
+				if _, ok := index[name]; !ok {
+					return nil, nil, fmt.Errorf("invalid header: missing column %q", name)
+				}
+			}
+			continue
+		}
+
+		line, _ := reader.FieldPos(0)
+		if len(record) != columns {
+			rowErrors = append(rowErrors, RowError{
+				Line:   line,
+				Record: record,
+				Reason: fmt.Sprintf("expected %d columns, got %d", columns, len(record)),
+			})
+			continue
+		}
+
+		t, err := decodeTask(record, index)
+		if err != nil {
+			rowErrors = append(rowErrors, RowError{
+				Line:   line,
+				Record: record,
+				Reason: err.Error(),
+			})
+			continue
+		}
+
+		tasks = append(tasks, t)
+	}
+
+	return tasks, rowErrors, nil
+}
+
+// rawLines returns lines first to last of data, counted from 1.
+func rawLines(data []byte, first, last int) string {
+	lines := strings.SplitAfter(string(data), "\n")
+	if first < 1 || first > len(lines) {
+		return ""
+	}
+	last = min(max(last, first), len(lines))
+	return strings.TrimRight(strings.Join(lines[first-1:last], ""), "\r\n")
+}
+
+func decodeTask(record []string, index map[string]int) (task.Task, error) {
+	var t task.Task
+	var err error
+
+	value := field(record, index, "id")
+	if t.ID, err = uuid.Parse(value); err != nil {
+		return t, fmt.Errorf("invalid id %q", value)
+	}
+
+	if value = field(record, index, "num"); value != "" {
+		if t.Num, err = strconv.Atoi(value); err != nil || t.Num < 0 {
+			return t, fmt.Errorf("invalid num %q", value)
+		}
+	}
+
+	t.Description = field(record, index, "description")
+
+	value = field(record, index, "status")
+	if t.Status, err = task.ParseStatus(value); err != nil {
+		return t, fmt.Errorf("invalid status %q", value)
+	}
+
+	if t.CreatedAt, err = parseTime(record, index, "created_at", true); err != nil {
+		return t, err
+	}
+	if t.UpdatedAt, err = parseTime(record, index, "updated_at", false); err != nil {
+		return t, err
+	}
+	if t.DueDate, err = parseTime(record, index, "due_date", true); err != nil {
+		return t, err
+	}
+
+	if value = field(record, index, "estimate"); value != "" {
+		if t.Estimate, err = time.ParseDuration(value); err != nil {
+			return t, fmt.Errorf("invalid estimate %q", value)
+		}
+	}
+
+	if t.CompletedAt, err = parseTime(record, index, "completed_at", false); err != nil {
+		return t, err
+	}
+	if t.DeletedAt, err = parseTime(record, index, "deleted_at", false); err != nil {
+		return t, err
+	}
+
+	if value = field(record, index, "version"); value != "" {
+		if t.Version, err = strconv.Atoi(value); err != nil || t.Version < 0 {
+			return t, fmt.Errorf("invalid version %q", value)
+		}
+	}
+	t.FillUpdatedAt()
+
+	t.Priority = field(record, index, "priority")
+	if !task.ValidPriority(t.Priority) {
+		return t, fmt.Errorf("invalid priority %q", t.Priority)
+	}
+	t.Project = field(record, index, "project")
+	t.Tags = task.ParseTags(field(record, index, "tags"))
+	if t.Annotations, err = task.ParseAnnotations(field(record, index, "annotations")); err != nil {
+		return t, err
+	}
+
+	return t, nil
+}
+
+func parseTime(record []string, index map[string]int, name string, required bool) (time.Time, error) {
+	value := field(record, index, name)
+	if value == "" && !required {
+		return time.Time{}, nil
+	}
+
+	parsed, err := time.Parse(time.RFC3339, value)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("invalid %s %q", name, value)
+	}
+
+	return parsed, nil
+}
+
+func field(record []string, index map[string]int, name string) string {
+	i, ok := index[name]
+	if !ok || i >= len(record) {
+		return ""
+	}
+	return record[i]
+}
+
+// QuarantinePath is where lenient mode moves rows it cannot decode.
+func QuarantinePath(path string) string {
+	return path + ".quarantine"
+}
+
+var quarantineHeader = []string{"quarantined_at", "line", "reason", "raw", "record"}
+
+// appendQuarantine appends rows to the quarantine file next to path. Each row
+// keeps the raw text of a row that is not valid CSV, or else the original
+// fields, after the line number and reason.
+func appendQuarantine(path string, rows []RowError) error {
+	qpath := QuarantinePath(path)
+
+	_, err := os.Stat(qpath)
+	isNew := os.IsNotExist(err)
+
+	file, err := os.OpenFile(qpath, os.O_WRONLY|os.O_CREATE|os.O_APPEND, 0644)
+	if err != nil {
+		return fmt.Errorf("failed to open quarantine file: %w", err)
+	}
+	defer file.Close()
+
+	writer := csv.NewWriter(file)
+	if isNew {
+		if err := writer.Write(quarantineHeader); err != nil {
+			return fmt.Errorf("failed to write quarantine header: %w", err)
+		}
+	}
+
+	now := time.Now().Format(time.RFC3339)
+	for _, row := range rows {
+		record := append([]string{now, strconv.Itoa(row.Line), row.Reason, row.Raw}, row.Record...)
+		if err := writer.Write(record); err != nil {
+			return fmt.Errorf("failed to write quarantined row: %w", err)
+		}
+	}
+
+	writer.Flush()
+	if err := writer.Error(); err != nil {
+		return fmt.Errorf("failed to write quarantine file: %w", err)
+	}
+
+	return file.Sync()
+}
+
+// Inspect reports the rows of the tasks file at path that cannot be decoded,
+// without modifying it. Files in an older format are checked as they would
+// be after upgrading.
+func Inspect(ctx context.Context, path string) ([]RowError, error) {
+	lock, err := fileformat.Acquire(ctx, path)
+	if err != nil {
+		return nil, err
+	}
+	defer lock.Release()
+
+	data, err := os.ReadFile(path)
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to open file: %w", err)
+	}
+
+	version, err := detectVersion(data)
+	if err != nil {
+		return nil, err
+	}
+
+	data, err = pipeline.Convert(data, version)
+	if err != nil {
+		return nil, err
+	}
+
+	_, rowErrors, err := decode(data)
+	return rowErrors, err
+}
+
+// Quarantined returns the rows previously moved to the quarantine file of
+// the tasks file at path.
+func Quarantined(path string) ([]RowError, error) {
+	file, err := os.Open(QuarantinePath(path))
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to open quarantine file: %w", err)
+	}
+	defer file.Close()
+
+	reader := csv.NewReader(file)
+	reader.FieldsPerRecord = -1
+	records, err := reader.ReadAll()
+	if err != nil {
+		return nil, fmt.Errorf("failed to read quarantine file: %w", err)
+	}
+
+	var rows []RowError
+	for _, record := range records {
+		if len(record) < 4 || record[0] == quarantineHeader[0] {
+			continue
+		}
+		line, _ := strconv.Atoi(record[1])
+		row := RowError{
+			Line:   line,
+			Raw:    record[3],
+			Reason: record[2],
+		}
+		if len(record) > 4 {
+			row.Record = record[4:]
+		}
+		rows = append(rows, row)
+	}
+
+	return rows, nil
+}
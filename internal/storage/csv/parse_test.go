@@ -0,0 +1,175 @@
+package csv_test
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/ncfex/tasks/internal/storage/csv"
+	"github.com/ncfex/tasks/internal/task"
+)
+
+const corruptedFile = `#format,3,next_num=4
+id,num,description,status,created_at,updated_at,due_date,estimate,completed_at,deleted_at,version,priority,project,tags
+0b6c2d4e-1f3a-4b5c-8d7e-9f0a1b2c3d4e,1,buy milk,todo,2024-03-01T09:00:00Z,,0001-01-01T00:00:00Z,,,,1,,,
+5e8f1a2b-3c4d-4e5f-a6b7-c8d9e0f1a2b3,2,file taxes,finished,2024-03-02T09:00:00Z,,0001-01-01T00:00:00Z,,,,1,,,
+7a1b2c3d-4e5f-4a6b-8c7d-9e0f1a2b3c4d,3,too short,todo
+`
+
+// wantLines are the lines of corruptedFile that cannot be decoded.
+var wantLines = []int{4, 5}
+
+func writeCorrupted(t *testing.T) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "tasks.csv")
+	if err := os.WriteFile(path, []byte(corruptedFile), 0644); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func rowLines(rows []csv.RowError) []int {
+	lines := make([]int, len(rows))
+	for i, row := range rows {
+		lines[i] = row.Line
+	}
+	return lines
+}
+
+func TestStrictParsingReportsEveryBadRow(t *testing.T) {
+	path := writeCorrupted(t)
+
+	_, err := csv.NewRepository(path).List(context.Background(), task.NewTaskSelector(), task.NewTaskFilter())
+	var parseErr *csv.ParseError
+	if !errors.As(err, &parseErr) {
+		t.Fatalf("List() error = %v, want a *csv.ParseError", err)
+	}
+	if parseErr.Path != path {
+		t.Errorf("ParseError.Path = %s, want %s", parseErr.Path, path)
+	}
+	if lines := rowLines(parseErr.Rows); !slices.Equal(lines, wantLines) {
+		t.Errorf("ParseError lines = %v, want %v", lines, wantLines)
+	}
+	if !strings.Contains(parseErr.Rows[0].Reason, `invalid status "finished"`) {
+		t.Errorf("line 4 reason = %q, want the invalid status", parseErr.Rows[0].Reason)
+	}
+	if !strings.Contains(parseErr.Rows[1].Reason, "expected 14 columns, got 4") {
+		t.Errorf("line 5 reason = %q, want the column count", parseErr.Rows[1].Reason)
+	}
+	for _, line := range []string{"line 4:", "line 5:"} {
+		if !strings.Contains(err.Error(), line) {
+			t.Errorf("error message %q does not mention %s", err, line)
+		}
+	}
+
+	if got, _ := os.ReadFile(path); string(got) != corruptedFile {
+		t.Error("strict parsing modified the file")
+	}
+	if _, err := os.Stat(csv.QuarantinePath(path)); !os.IsNotExist(err) {
+		t.Error("strict parsing wrote a quarantine file")
+	}
+}
+
+func TestLenientParsingQuarantinesBadRows(t *testing.T) {
+	ctx := context.Background()
+	path := writeCorrupted(t)
+	repo := csv.NewRepository(path, csv.WithLenientParsing())
+
+	tasks, err := repo.List(ctx, task.NewTaskSelector(), task.NewTaskFilter())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(tasks) != 1 || tasks[0].Description != "buy milk" {
+		t.Fatalf("List() = %+v, want only the valid row", tasks)
+	}
+
+	quarantined, err := csv.Quarantined(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if lines := rowLines(quarantined); !slices.Equal(lines, wantLines) {
+		t.Errorf("quarantined lines = %v, want %v", lines, wantLines)
+	}
+	if len(quarantined) == 2 && (quarantined[0].Record[2] != "file taxes" || quarantined[1].Record[2] != "too short") {
+		t.Errorf("quarantined records = %v, want the original fields", quarantined)
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if strings.Contains(string(data), "file taxes") || strings.Contains(string(data), "too short") {
+		t.Error("bad rows are still in the tasks file")
+	}
+
+	// The file is clean now: reading it again, even strictly, quarantines
+	// nothing more.
+	if _, err := csv.NewRepository(path).List(ctx, task.NewTaskSelector(), task.NewTaskFilter()); err != nil {
+		t.Fatalf("strict List() after quarantine: %v", err)
+	}
+	if quarantined, _ := csv.Quarantined(path); len(quarantined) != len(wantLines) {
+		t.Errorf("quarantine has %d rows after a second read, want %d", len(quarantined), len(wantLines))
+	}
+}
+
+func TestInspectDoesNotModify(t *testing.T) {
+	path := writeCorrupted(t)
+
+	rows, err := csv.Inspect(context.Background(), path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if lines := rowLines(rows); !slices.Equal(lines, wantLines) {
+		t.Errorf("Inspect() lines = %v, want %v", lines, wantLines)
+	}
+	if got, _ := os.ReadFile(path); string(got) != corruptedFile {
+		t.Error("Inspect() modified the file")
+	}
+}
+
+func TestLenientParsingKeepsRowsWithBrokenQuoting(t *testing.T) {
+	const broken = `9c8b7a6d-5e4f-4a3b-8c2d-1e0f9a8b7c6d,2,"call "mum" back",todo,2024-03-01T09:00:00Z,,0001-01-01T00:00:00Z,,,,1,,,`
+	content := strings.Join([]string{
+		"#format,3,next_num=4",
+		"id,num,description,status,created_at,updated_at,due_date,estimate,completed_at,deleted_at,version,priority,project,tags",
+		"0b6c2d4e-1f3a-4b5c-8d7e-9f0a1b2c3d4e,1,buy milk,todo,2024-03-01T09:00:00Z,,0001-01-01T00:00:00Z,,,,1,,,",
+		broken,
+		"5e8f1a2b-3c4d-4e5f-a6b7-c8d9e0f1a2b3,3,file taxes,todo,2024-03-02T09:00:00Z,,0001-01-01T00:00:00Z,,,,1,,,",
+	}, "\n") + "\n"
+	dir := t.TempDir()
+	path := filepath.Join(dir, "tasks.csv")
+	if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	tasks, err := csv.NewRepository(path, csv.WithLenientParsing()).List(context.Background(), task.NewTaskSelector(), task.NewTaskFilter())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(tasks) != 2 {
+		t.Fatalf("List() = %d tasks, want the 2 valid rows", len(tasks))
+	}
+
+	quarantined, err := csv.Quarantined(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(quarantined) != 1 {
+		t.Fatalf("quarantined = %+v, want the broken row", quarantined)
+	}
+	if row := quarantined[0]; row.Line != 4 || row.Raw != broken || len(row.Record) != 0 {
+		t.Errorf("quarantined row = line %d, raw %q, record %v, want line 4 with its text", row.Line, row.Raw, row.Record)
+	}
+
+	backups, _ := filepath.Glob(filepath.Join(dir, "tasks.csv.quarantine-*.bak"))
+	if len(backups) != 1 {
+		t.Fatalf("backups = %v, want one of the file before quarantine", backups)
+	}
+	if got, _ := os.ReadFile(backups[0]); string(got) != content {
+		t.Errorf("backup = %q, want the original file", got)
+	}
+}
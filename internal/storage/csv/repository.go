@@ -1,256 +1,196 @@
 package csv
 
 import (
+	"bytes"
+	"context"
 	"encoding/csv"
+	"errors"
 	"fmt"
+	"io/fs"
 	"os"
-	"path/filepath"
 	"strconv"
-	"strings"
-	"sync"
 	"time"
 
-	"github.com/google/uuid"
+	"github.com/ncfex/tasks/internal/storage/fileformat"
 	"github.com/ncfex/tasks/internal/task"
 )
 
-type repository struct {
-	filepath string
-	mu       sync.Mutex
+// codec stores tasks as CSV rows after a format row and a header.
+type codec struct {
+	lenient bool
 }
 
-func NewRepository(filepath string) task.Repository {
-	return &repository{
-		filepath: filepath,
-	}
-}
+type Option func(*codec)
 
-func (r *repository) Save(t *task.Task) error {
-	r.mu.Lock()
-	defer r.mu.Unlock()
-
-	tasks, err := r.readTasks()
-	if err != nil {
-		return fmt.Errorf("failed to read tasks: %w", err)
+// WithLenientParsing moves rows that cannot be decoded to the quarantine
+// file instead of failing every operation on the file.
+func WithLenientParsing() Option {
+	return func(c *codec) {
+		c.lenient = true
 	}
-
-	if t.ID == uuid.Nil {
-		t.ID = uuid.New()
-	}
-	tasks = append(tasks, *t)
-
-	return r.writeTasks(tasks)
 }
 
-func (r *repository) GetByID(id uuid.UUID) (*task.Task, error) {
-	r.mu.Lock()
-	defer r.mu.Unlock()
-
-	tasks, err := r.readTasks()
-	if err != nil {
-		return nil, fmt.Errorf("failed to read tasks: %w", err)
+func NewRepository(filepath string, opts ...Option) task.Repository {
+	var c codec
+	for _, opt := range opts {
+		opt(&c)
 	}
-
-	for _, t := range tasks {
-		if t.ID == id {
-			return &t, nil
-		}
-	}
-
-	return nil, task.ErrTaskNotFound
+	return fileformat.NewStore(filepath, c)
 }
 
-func (r *repository) GetTaskByPartialId(id string) (*task.Task, error) {
-	r.mu.Lock()
-	defer r.mu.Unlock()
-
-	tasks, err := r.readTasks()
+func (c codec) ReadTasks(path string) ([]task.Task, int, error) {
+	tasks, nextNum, rowErrors, err := load(path)
 	if err != nil {
-		return nil, fmt.Errorf("failed to read tasks: %w", err)
+		return nil, 0, err
 	}
 
-	var foundTask *task.Task
-	matchCount := 0
+	if len(rowErrors) > 0 {
+		if !c.lenient {
+			return nil, 0, &ParseError{Path: path, Rows: rowErrors}
+		}
 
-	for _, t := range tasks {
-		if strings.HasPrefix(t.ID.String(), id) {
-			foundTask = &t
-			matchCount++
+		if err := quarantine(path, tasks, nextNum, rowErrors); err != nil {
+			return nil, 0, err
 		}
 	}
 
-	switch matchCount {
-	case 0:
-		return nil, task.ErrTaskNotFound
-	case 1:
-		return foundTask, nil
-	default:
-		return nil, fmt.Errorf("multiple tasks found with partial ID %s", id)
-	}
+	return tasks, nextNum, nil
 }
 
-func (r *repository) List(selector *task.TaskSelector, filter *task.TaskFilter) ([]task.Task, error) {
-	r.mu.Lock()
-	defer r.mu.Unlock()
-
-	tasks, err := r.readTasks()
+// load decodes the file, upgrading it first if it is in an older format, and
+// returns the rows it could not decode separately.
+func load(path string) ([]task.Task, int, []RowError, error) {
+	data, err := os.ReadFile(path)
 	if err != nil {
-		return nil, fmt.Errorf("failed to read tasks: %w", err)
+		return nil, 0, nil, fmt.Errorf("failed to open file: %w", err)
 	}
 
-	var filtered []task.Task
-	for _, t := range tasks {
-		if !filter.IncludeCompleted && t.IsCompleted {
-			continue
-		}
-		filtered = append(filtered, t)
-	}
-
-	return filtered, nil
-}
-
-func (r *repository) Update(t *task.Task) error {
-	r.mu.Lock()
-	defer r.mu.Unlock()
-
-	tasks, err := r.readTasks()
+	version, err := detectVersion(data)
 	if err != nil {
-		return fmt.Errorf("failed to read tasks: %w", err)
+		return nil, 0, nil, err
 	}
 
-	found := false
-	for i, task := range tasks {
-		if task.ID == t.ID {
-			tasks[i] = *t
-			found = true
-			break
+	if version != formatVersion {
+		if _, err := pipeline.Upgrade(path, data, version); err != nil {
+			return nil, 0, nil, err
+		}
+
+		data, err = os.ReadFile(path)
+		if err != nil {
+			return nil, 0, nil, fmt.Errorf("failed to open file: %w", err)
 		}
 	}
 
-	if !found {
-		return task.ErrTaskNotFound
+	tasks, rowErrors, err := decode(data)
+	if err != nil {
+		return nil, 0, nil, fmt.Errorf("%s: %w", path, err)
 	}
 
-	return r.writeTasks(tasks)
+	return tasks, detectNextNum(data), rowErrors, nil
 }
 
-func (r *repository) Delete(t *task.Task) error {
-	r.mu.Lock()
-	defer r.mu.Unlock()
-
-	tasks, err := r.readTasks()
+// quarantine moves rows that could not be decoded to the quarantine file and
+// rewrites the tasks file with only the valid ones, keeping a backup of it.
+func quarantine(path string, tasks []task.Task, nextNum int, rowErrors []RowError) error {
+	data, err := os.ReadFile(path)
 	if err != nil {
-		return fmt.Errorf("failed to read tasks: %w", err)
+		return fmt.Errorf("failed to open file: %w", err)
 	}
-
-	found := false
-	indexToRemove := -1
-	for i, task := range tasks {
-		if task.ID == t.ID {
-			indexToRemove = i
-			found = true
-			break
-		}
+	backupPath := fmt.Sprintf("%s.quarantine-%s.bak", path, time.Now().Format("20060102T150405"))
+	if err := os.WriteFile(backupPath, data, 0644); err != nil {
+		return fmt.Errorf("failed to write backup: %w", err)
 	}
 
-	if !found {
-		return task.ErrTaskNotFound
+	if err := appendQuarantine(path, rowErrors); err != nil {
+		return err
 	}
-
-	tasks = append(tasks[:indexToRemove], tasks[indexToRemove+1:]...)
-
-	return r.writeTasks(tasks)
+	return codec{}.WriteTasks(path, tasks, fileformat.NextNum(nextNum, tasks))
 }
 
-func (r *repository) readTasks() ([]task.Task, error) {
-	if err := r.ensureFile(); err != nil {
+// Quarantine moves the rows of the tasks file at path that cannot be decoded
+// to its quarantine file, as lenient parsing would, and returns them.
+func Quarantine(ctx context.Context, path string) ([]RowError, error) {
+	lock, err := fileformat.Acquire(ctx, path)
+	if err != nil {
 		return nil, err
 	}
+	defer lock.Release()
 
-	file, err := os.OpenFile(r.filepath, os.O_RDONLY, 0644)
-	if err != nil {
-		return nil, fmt.Errorf("failed to open file: %w", err)
+	tasks, nextNum, rowErrors, err := load(path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil, nil
 	}
-	defer file.Close()
-
-	reader := csv.NewReader(file)
-	records, err := reader.ReadAll()
 	if err != nil {
-		return nil, fmt.Errorf("failed to read CSV: %w", err)
+		return nil, err
 	}
 
-	var tasks []task.Task
-	for _, record := range records {
-		if len(record) != 5 {
-			continue
+	if len(rowErrors) > 0 {
+		if err := quarantine(path, tasks, nextNum, rowErrors); err != nil {
+			return nil, err
 		}
+	}
 
-		id, err := uuid.Parse(record[0])
-		if err != nil {
-			continue
-		}
-		isCompleted, _ := strconv.ParseBool(record[2])
-		createdAt, _ := time.Parse(time.RFC3339, record[3])
-		dueDate, _ := time.Parse(time.RFC3339, record[4])
+	return rowErrors, nil
+}
 
-		task := task.Task{
-			ID:          id,
-			Description: record[1],
-			IsCompleted: isCompleted,
-			CreatedAt:   createdAt,
-			DueDate:     dueDate,
-		}
-		tasks = append(tasks, task)
+func (codec) WriteTasks(path string, tasks []task.Task, nextNum int) error {
+	records := make([][]string, 0, len(tasks)+2)
+	records = append(records, formatRow(nextNum), header)
+	for i := range tasks {
+		records = append(records, encodeTask(&tasks[i]))
 	}
 
-	return tasks, nil
+	return writeRecords(path, records)
 }
 
-func (r *repository) writeTasks(tasks []task.Task) error {
-	if err := r.ensureFile(); err != nil {
-		return err
+// encodeTask returns the row for t, in the order of header.
+func encodeTask(t *task.Task) []string {
+	return []string{
+		t.ID.String(),
+		formatNum(t.Num),
+		t.Description,
+		string(t.Status),
+		t.CreatedAt.Format(time.RFC3339),
+		formatTime(t.UpdatedAt),
+		t.DueDate.Format(time.RFC3339),
+		formatDuration(t.Estimate),
+		formatTime(t.CompletedAt),
+		formatTime(t.DeletedAt),
+		strconv.Itoa(t.Version),
+		t.Priority,
+		t.Project,
+		task.FormatTags(t.Tags),
+		task.FormatAnnotations(t.Annotations),
 	}
+}
 
-	file, err := os.OpenFile(r.filepath, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, 0644)
-	if err != nil {
-		return fmt.Errorf("failed to open file: %w", err)
+func writeRecords(path string, records [][]string) error {
+	var buf bytes.Buffer
+	writer := csv.NewWriter(&buf)
+	if err := writer.WriteAll(records); err != nil {
+		return fmt.Errorf("failed to write records: %w", err)
 	}
-	defer file.Close()
-
-	writer := csv.NewWriter(file)
-	defer writer.Flush()
 
-	for _, t := range tasks {
-		record := []string{
-			t.ID.String(),
-			t.Description,
-			strconv.FormatBool(t.IsCompleted),
-			t.CreatedAt.Format(time.RFC3339),
-			t.DueDate.Format(time.RFC3339),
-		}
+	return fileformat.WriteFile(path, buf.Bytes(), 0644)
+}
 
-		if err := writer.Write(record); err != nil {
-			return fmt.Errorf("failed to write record: %w", err)
-		}
+func formatNum(num int) string {
+	if num == 0 {
+		return ""
 	}
-
-	return nil
+	return strconv.Itoa(num)
 }
 
-func (r *repository) ensureFile() error {
-	dir := filepath.Dir(r.filepath)
-	if err := os.MkdirAll(dir, 0755); err != nil {
-		return fmt.Errorf("failed to create directory: %w", err)
+func formatDuration(d time.Duration) string {
+	if d == 0 {
+		return ""
 	}
+	return d.String()
+}
 
-	if _, err := os.Stat(r.filepath); os.IsNotExist(err) {
-		file, err := os.Create(r.filepath)
-		if err != nil {
-			return fmt.Errorf("failed to create file: %w", err)
-		}
-		// TODO WRITE HEADERS
-		file.Close()
+func formatTime(t time.Time) string {
+	if t.IsZero() {
+		return ""
 	}
-
-	return nil
+	return t.Format(time.RFC3339)
 }
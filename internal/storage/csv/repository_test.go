@@ -0,0 +1,25 @@
+package csv_test
+
+import (
+	"path/filepath"
+	"testing"
+
+	"github.com/ncfex/tasks/internal/storage/csv"
+	"github.com/ncfex/tasks/internal/storage/storagetest"
+	"github.com/ncfex/tasks/internal/task"
+)
+
+func TestRepository(t *testing.T) {
+	storagetest.Run(t, func(t *testing.T) storagetest.Opener {
+		path := filepath.Join(t.TempDir(), "tasks.csv")
+		return func(t *testing.T) task.Repository {
+			return csv.NewRepository(path)
+		}
+	})
+}
+
+func TestConcurrentProcesses(t *testing.T) {
+	storagetest.RunProcesses(t, "tasks.csv", func(path string) task.Repository {
+		return csv.NewRepository(path)
+	})
+}
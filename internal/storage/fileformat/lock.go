@@ -0,0 +1,59 @@
+package fileformat
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// lockRetryInterval is how often Acquire retries a lock held by another
+// process.
+const lockRetryInterval = 10 * time.Millisecond
+
+// Lock is an advisory lock on a data file, held through a sibling ".lock"
+// file so the data file itself can be replaced by rename while locked.
+type Lock struct {
+	file *os.File
+}
+
+// Acquire waits until it holds an exclusive lock for path or ctx is done.
+// The lock is seen by other processes too, so it should wrap a whole
+// read-modify-write cycle.
+func Acquire(ctx context.Context, path string) (*Lock, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, fmt.Errorf("failed to lock %s: %w", path, err)
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return nil, fmt.Errorf("failed to create directory: %w", err)
+	}
+
+	file, err := os.OpenFile(path+".lock", os.O_RDWR|os.O_CREATE, 0644)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open lock file: %w", err)
+	}
+
+	for {
+		locked, err := tryLockFile(file)
+		if err != nil {
+			file.Close()
+			return nil, fmt.Errorf("failed to lock %s: %w", path, err)
+		}
+		if locked {
+			return &Lock{file: file}, nil
+		}
+
+		select {
+		case <-ctx.Done():
+			file.Close()
+			return nil, fmt.Errorf("failed to lock %s: %w", path, ctx.Err())
+		case <-time.After(lockRetryInterval):
+		}
+	}
+}
+
+func (l *Lock) Release() error {
+	defer l.file.Close()
+	return unlockFile(l.file)
+}
@@ -0,0 +1,16 @@
+//go:build !unix
+
+package fileformat
+
+import "os"
+
+// Advisory locking is only implemented on unix; elsewhere only the
+// in-process mutex of each repository applies.
+
+func tryLockFile(file *os.File) (bool, error) {
+	return true, nil
+}
+
+func unlockFile(file *os.File) error {
+	return nil
+}
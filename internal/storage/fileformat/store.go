@@ -0,0 +1,245 @@
+package fileformat
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+
+	"github.com/google/uuid"
+	"github.com/ncfex/tasks/internal/task"
+)
+
+// Codec reads and writes the tasks file of one storage format. A Store does
+// the locking, numbering and transactions around it.
+type Codec interface {
+	// ReadTasks returns the tasks in the file at path and its task number
+	// counter, upgrading the file first if it is in an older format.
+	ReadTasks(path string) ([]task.Task, int, error)
+
+	// WriteTasks replaces the file at path with tasks and the counter.
+	WriteTasks(path string, tasks []task.Task, nextNum int) error
+}
+
+// Store is a task.Repository kept in a single file, which every call reads
+// and, if it changed anything, writes back whole while holding the lock.
+type Store struct {
+	path  string
+	codec Codec
+	mu    sync.Mutex
+
+	// nextNum is the task number counter of the file, as of the last read.
+	// It is only used while the lock is held.
+	nextNum int
+}
+
+func NewStore(path string, codec Codec) *Store {
+	return &Store{
+		path:  path,
+		codec: codec,
+	}
+}
+
+func (s *Store) Save(ctx context.Context, t *task.Task) error {
+	return s.WithTx(ctx, func(tx task.Repository) error {
+		return tx.Save(ctx, t)
+	})
+}
+
+func (s *Store) GetByID(ctx context.Context, id uuid.UUID) (*task.Task, error) {
+	var found *task.Task
+	err := s.WithTx(ctx, func(tx task.Repository) error {
+		var err error
+		found, err = tx.GetByID(ctx, id)
+		return err
+	})
+	return found, err
+}
+
+func (s *Store) GetTaskByPartialId(ctx context.Context, id string) (*task.Task, error) {
+	var found *task.Task
+	err := s.WithTx(ctx, func(tx task.Repository) error {
+		var err error
+		found, err = tx.GetTaskByPartialId(ctx, id)
+		return err
+	})
+	return found, err
+}
+
+func (s *Store) GetByNum(ctx context.Context, num int) (*task.Task, error) {
+	var found *task.Task
+	err := s.WithTx(ctx, func(tx task.Repository) error {
+		var err error
+		found, err = tx.GetByNum(ctx, num)
+		return err
+	})
+	return found, err
+}
+
+func (s *Store) List(ctx context.Context, selector *task.TaskSelector, filter *task.TaskFilter) ([]task.Task, error) {
+	var tasks []task.Task
+	err := s.WithTx(ctx, func(tx task.Repository) error {
+		var err error
+		tasks, err = tx.List(ctx, selector, filter)
+		return err
+	})
+	return tasks, err
+}
+
+func (s *Store) Update(ctx context.Context, t *task.Task) error {
+	return s.WithTx(ctx, func(tx task.Repository) error {
+		return tx.Update(ctx, t)
+	})
+}
+
+func (s *Store) Delete(ctx context.Context, t *task.Task) error {
+	return s.WithTx(ctx, func(tx task.Repository) error {
+		return tx.Delete(ctx, t)
+	})
+}
+
+// WithTx runs fn against a single read of the file and writes every change
+// it makes in one go, so other processes never see part of them. Nothing is
+// written if fn fails. Writes registered through Committer follow the tasks
+// file, still under the lock.
+func (s *Store) WithTx(ctx context.Context, fn func(task.Repository) error) error {
+	unlock, err := s.lock(ctx)
+	if err != nil {
+		return err
+	}
+	defer unlock()
+
+	tasks, err := s.read()
+	if err != nil {
+		return fmt.Errorf("failed to read tasks: %w", err)
+	}
+
+	t := &tx{s: s, tasks: tasks}
+	if err := fn(t); err != nil {
+		return err
+	}
+
+	if t.dirty {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		if err := s.codec.WriteTasks(s.path, t.tasks, s.nextNum); err != nil {
+			return err
+		}
+	}
+
+	for _, fn := range t.onCommit {
+		if err := fn(); err != nil {
+			return fmt.Errorf("tasks were saved, but recording the change failed: %w", err)
+		}
+	}
+	return nil
+}
+
+// ReplaceAll overwrites the file with tasks, keeping its task number
+// counter and numbering tasks that have no number.
+func (s *Store) ReplaceAll(ctx context.Context, tasks []task.Task) error {
+	unlock, err := s.lock(ctx)
+	if err != nil {
+		return err
+	}
+	defer unlock()
+
+	if _, err := s.read(); err != nil {
+		return err
+	}
+	s.nextNum = NextNum(s.nextNum, tasks)
+	for i := range tasks {
+		if tasks[i].Num == 0 {
+			tasks[i].Num = s.nextNum
+			s.nextNum++
+		}
+	}
+
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	return s.codec.WriteTasks(s.path, tasks, s.nextNum)
+}
+
+// NextNum returns the number for the next new task: the stored counter, or
+// one past the highest number in use if the file was edited by hand.
+func NextNum(stored int, tasks []task.Task) int {
+	next := max(stored, 1)
+	for i := range tasks {
+		next = max(next, tasks[i].Num+1)
+	}
+	return next
+}
+
+// assignNum gives t the next task number, unless it already has one that no
+// other task uses, as when a deleted task is saved again by undo.
+func (s *Store) assignNum(t *task.Task, tasks []task.Task) {
+	if t.Num > 0 {
+		taken := false
+		for i := range tasks {
+			if tasks[i].Num == t.Num {
+				taken = true
+				break
+			}
+		}
+		if !taken {
+			s.nextNum = max(s.nextNum, t.Num+1)
+			return
+		}
+	}
+
+	t.Num = s.nextNum
+	s.nextNum++
+}
+
+// lock serializes access to the file within this process and, through an
+// advisory file lock, with other tasks processes.
+func (s *Store) lock(ctx context.Context) (func(), error) {
+	s.mu.Lock()
+
+	lock, err := Acquire(ctx, s.path)
+	if err != nil {
+		s.mu.Unlock()
+		return nil, err
+	}
+
+	return func() {
+		lock.Release()
+		s.mu.Unlock()
+	}, nil
+}
+
+func (s *Store) read() ([]task.Task, error) {
+	if err := s.ensureFile(); err != nil {
+		return nil, err
+	}
+
+	tasks, stored, err := s.codec.ReadTasks(s.path)
+	if err != nil {
+		return nil, err
+	}
+
+	if tasks == nil {
+		tasks = []task.Task{}
+	}
+	s.nextNum = NextNum(stored, tasks)
+
+	return tasks, nil
+}
+
+func (s *Store) ensureFile() error {
+	dir := filepath.Dir(s.path)
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return fmt.Errorf("failed to create directory: %w", err)
+	}
+
+	if _, err := os.Stat(s.path); os.IsNotExist(err) {
+		if err := s.codec.WriteTasks(s.path, []task.Task{}, 1); err != nil {
+			return fmt.Errorf("failed to initialize %s: %w", s.path, err)
+		}
+	}
+
+	return nil
+}
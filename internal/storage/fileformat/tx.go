@@ -0,0 +1,138 @@
+package fileformat
+
+import (
+	"context"
+	"strings"
+
+	"github.com/google/uuid"
+	"github.com/ncfex/tasks/internal/task"
+)
+
+// tx is the content of the tasks file, held in memory while the file is
+// locked. WithTx writes it back once, and only if something changed.
+type tx struct {
+	s     *Store
+	tasks []task.Task
+	dirty bool
+
+	onCommit []func() error
+}
+
+// Committer is implemented by the repository a Store hands to a WithTx
+// callback. Files kept alongside the tasks file, such as the journal,
+// register their writes with OnCommit so that they only happen once the
+// tasks file has been written.
+type Committer interface {
+	OnCommit(fn func() error)
+}
+
+func (t *tx) OnCommit(fn func() error) {
+	t.onCommit = append(t.onCommit, fn)
+}
+
+// WithTx lets code that already runs in a transaction start another one,
+// which simply joins the outer one.
+func (t *tx) WithTx(ctx context.Context, fn func(task.Repository) error) error {
+	return fn(t)
+}
+
+func (t *tx) Save(ctx context.Context, newTask *task.Task) error {
+	if newTask.ID == uuid.Nil {
+		newTask.ID = uuid.New()
+	}
+	if t.index(newTask.ID) >= 0 {
+		return &task.ConflictError{ID: newTask.ID, Reason: "a task with this ID already exists"}
+	}
+
+	t.s.assignNum(newTask, t.tasks)
+	t.tasks = append(t.tasks, *newTask)
+	t.dirty = true
+	return nil
+}
+
+func (t *tx) GetByID(ctx context.Context, id uuid.UUID) (*task.Task, error) {
+	i := t.index(id)
+	if i < 0 {
+		return nil, &task.NotFoundError{ID: id.String()}
+	}
+
+	found := t.tasks[i]
+	return &found, nil
+}
+
+func (t *tx) GetTaskByPartialId(ctx context.Context, id string) (*task.Task, error) {
+	var matches []task.Task
+	for _, existing := range t.tasks {
+		if strings.HasPrefix(existing.ID.String(), id) {
+			matches = append(matches, existing)
+		}
+	}
+
+	switch len(matches) {
+	case 0:
+		return nil, &task.NotFoundError{ID: id}
+	case 1:
+		return &matches[0], nil
+	default:
+		return nil, &task.AmbiguousIDError{Prefix: id, Candidates: matches}
+	}
+}
+
+func (t *tx) GetByNum(ctx context.Context, num int) (*task.Task, error) {
+	for _, existing := range t.tasks {
+		if existing.Num == num {
+			return &existing, nil
+		}
+	}
+
+	return nil, &task.NotFoundError{ID: task.FormatNum(num)}
+}
+
+func (t *tx) List(ctx context.Context, selector *task.TaskSelector, filter *task.TaskFilter) ([]task.Task, error) {
+	var filtered []task.Task
+	for _, existing := range t.tasks {
+		if !filter.Matches(&existing) {
+			continue
+		}
+		filtered = append(filtered, existing)
+	}
+
+	return filtered, nil
+}
+
+// Update stores updated if its version still matches the stored one, and
+// bumps the version of both.
+func (t *tx) Update(ctx context.Context, updated *task.Task) error {
+	i := t.index(updated.ID)
+	if i < 0 {
+		return &task.NotFoundError{ID: updated.ID.String()}
+	}
+	if t.tasks[i].Version != updated.Version {
+		return &task.VersionConflictError{Attempted: *updated, Current: t.tasks[i]}
+	}
+
+	updated.Version++
+	t.tasks[i] = *updated
+	t.dirty = true
+	return nil
+}
+
+func (t *tx) Delete(ctx context.Context, deleted *task.Task) error {
+	i := t.index(deleted.ID)
+	if i < 0 {
+		return &task.NotFoundError{ID: deleted.ID.String()}
+	}
+
+	t.tasks = append(t.tasks[:i], t.tasks[i+1:]...)
+	t.dirty = true
+	return nil
+}
+
+func (t *tx) index(id uuid.UUID) int {
+	for i := range t.tasks {
+		if t.tasks[i].ID == id {
+			return i
+		}
+	}
+	return -1
+}
@@ -0,0 +1,66 @@
+package fileformat
+
+import (
+	"fmt"
+	"os"
+	"time"
+)
+
+// UpgradeFunc converts the contents of a file from one format version to the
+// next.
+type UpgradeFunc func(data []byte) ([]byte, error)
+
+// Pipeline upgrades files of one storage format to its current version.
+// Steps[v] converts version v to version v+1.
+type Pipeline struct {
+	Current int
+	Steps   map[int]UpgradeFunc
+}
+
+// Convert runs the upgrade steps from version to p.Current on data without
+// touching the file.
+func (p Pipeline) Convert(data []byte, version int) ([]byte, error) {
+	if version > p.Current {
+		return nil, fmt.Errorf("format version %d is newer than the supported version %d", version, p.Current)
+	}
+
+	upgraded := data
+	for v := version; v < p.Current; v++ {
+		step, ok := p.Steps[v]
+		if !ok {
+			return nil, fmt.Errorf("no upgrade from format version %d", v)
+		}
+
+		var err error
+		upgraded, err = step(upgraded)
+		if err != nil {
+			return nil, fmt.Errorf("upgrade from format version %d: %w", v, err)
+		}
+	}
+
+	return upgraded, nil
+}
+
+// Upgrade migrates the file at path in place from version to p.Current. The
+// original contents are kept in a backup file whose path is returned.
+func (p Pipeline) Upgrade(path string, data []byte, version int) (string, error) {
+	if version == p.Current {
+		return "", nil
+	}
+
+	upgraded, err := p.Convert(data, version)
+	if err != nil {
+		return "", fmt.Errorf("%s: %w", path, err)
+	}
+
+	backupPath := fmt.Sprintf("%s.v%d-%s.bak", path, version, time.Now().Format("20060102T150405"))
+	if err := os.WriteFile(backupPath, data, 0644); err != nil {
+		return "", fmt.Errorf("failed to write backup: %w", err)
+	}
+
+	if err := WriteFile(path, upgraded, 0644); err != nil {
+		return "", fmt.Errorf("failed to write upgraded file: %w", err)
+	}
+
+	return backupPath, nil
+}
@@ -0,0 +1,127 @@
+package fileformat_test
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/ncfex/tasks/internal/storage/fileformat"
+)
+
+// appendStep returns a step that appends its version, so the output shows
+// which steps ran and in what order.
+func appendStep(v int) fileformat.UpgradeFunc {
+	return func(data []byte) ([]byte, error) {
+		return append(data, fmt.Sprintf(">%d", v+1)...), nil
+	}
+}
+
+var testPipeline = fileformat.Pipeline{
+	Current: 3,
+	Steps: map[int]fileformat.UpgradeFunc{
+		1: appendStep(1),
+		2: appendStep(2),
+	},
+}
+
+func TestPipelineConvert(t *testing.T) {
+	tests := []struct {
+		version int
+		want    string
+	}{
+		{version: 1, want: "v>2>3"},
+		{version: 2, want: "v>3"},
+		{version: 3, want: "v"},
+	}
+
+	for _, tt := range tests {
+		got, err := testPipeline.Convert([]byte("v"), tt.version)
+		if err != nil {
+			t.Errorf("Convert(v%d): %v", tt.version, err)
+			continue
+		}
+		if string(got) != tt.want {
+			t.Errorf("Convert(v%d) = %q, want %q", tt.version, got, tt.want)
+		}
+	}
+}
+
+func TestPipelineConvertErrors(t *testing.T) {
+	if _, err := testPipeline.Convert([]byte("v"), 4); err == nil || !strings.Contains(err.Error(), "newer") {
+		t.Errorf("Convert(v4) error = %v, want a newer-version error", err)
+	}
+
+	if _, err := testPipeline.Convert([]byte("v"), 0); err == nil || !strings.Contains(err.Error(), "no upgrade from format version 0") {
+		t.Errorf("Convert(v0) error = %v, want a missing-step error", err)
+	}
+
+	failing := fileformat.Pipeline{
+		Current: 2,
+		Steps: map[int]fileformat.UpgradeFunc{
+			1: func([]byte) ([]byte, error) { return nil, fmt.Errorf("bad row") },
+		},
+	}
+	if _, err := failing.Convert([]byte("v"), 1); err == nil || !strings.Contains(err.Error(), "bad row") {
+		t.Errorf("Convert() error = %v, want the step's error", err)
+	}
+}
+
+func TestPipelineUpgradeKeepsBackup(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "tasks.txt")
+	original := []byte("v")
+	if err := os.WriteFile(path, original, 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	backup, err := testPipeline.Upgrade(path, original, 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if got, _ := os.ReadFile(path); string(got) != "v>2>3" {
+		t.Errorf("upgraded file = %q, want %q", got, "v>2>3")
+	}
+	if !strings.HasPrefix(filepath.Base(backup), "tasks.txt.v1-") || filepath.Dir(backup) != filepath.Dir(path) {
+		t.Errorf("backup path = %s, want tasks.txt.v1-* next to the file", backup)
+	}
+	if got, err := os.ReadFile(backup); err != nil || !bytes.Equal(got, original) {
+		t.Errorf("backup = %q, %v, want the original contents", got, err)
+	}
+}
+
+func TestPipelineUpgradeCurrentIsNoop(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "tasks.txt")
+	if err := os.WriteFile(path, []byte("v"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	backup, err := testPipeline.Upgrade(path, []byte("v"), 3)
+	if err != nil || backup != "" {
+		t.Errorf("Upgrade(current) = %q, %v, want no backup", backup, err)
+	}
+	if entries, _ := os.ReadDir(dir); len(entries) != 1 {
+		t.Errorf("directory has %d files, want only the data file", len(entries))
+	}
+}
+
+func TestPipelineUpgradeFailureLeavesFile(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "tasks.txt")
+	if err := os.WriteFile(path, []byte("v"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := testPipeline.Upgrade(path, []byte("v"), 0); err == nil {
+		t.Fatal("Upgrade() succeeded without a step for version 0")
+	}
+	if got, _ := os.ReadFile(path); string(got) != "v" {
+		t.Errorf("file = %q after a failed upgrade, want it unchanged", got)
+	}
+	if entries, _ := os.ReadDir(dir); len(entries) != 1 {
+		t.Errorf("directory has %d files, want no backup for a failed upgrade", len(entries))
+	}
+}
@@ -0,0 +1,64 @@
+package fileformat_test
+
+import (
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/ncfex/tasks/internal/storage/fileformat"
+)
+
+func TestWriteFileReplacesContents(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "tasks.json")
+	if err := os.WriteFile(path, []byte("old"), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := fileformat.WriteFile(path, []byte("new"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	if got, _ := os.ReadFile(path); string(got) != "new" {
+		t.Errorf("file = %q, want %q", got, "new")
+	}
+	if info, err := os.Stat(path); err != nil || info.Mode().Perm() != 0644 {
+		t.Errorf("file mode = %v, %v, want 0644", info.Mode().Perm(), err)
+	}
+}
+
+// A write that fails part way leaves the original file and no temporary
+// file behind.
+func TestWriteFileFailureKeepsOriginal(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "tasks.json")
+	if err := os.WriteFile(path, []byte("old"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	errDiskFull := errors.New("disk full")
+	err := fileformat.WriteFileFunc(path, 0644, func(w io.Writer) error {
+		if _, err := w.Write([]byte("half")); err != nil {
+			return err
+		}
+		return errDiskFull
+	})
+	if !errors.Is(err, errDiskFull) {
+		t.Fatalf("WriteFileFunc() error = %v, want %v", err, errDiskFull)
+	}
+
+	if got, _ := os.ReadFile(path); string(got) != "old" {
+		t.Errorf("file = %q after a failed write, want %q", got, "old")
+	}
+	if entries, _ := os.ReadDir(dir); len(entries) != 1 {
+		t.Errorf("directory has %d entries, want only the original file", len(entries))
+	}
+}
+
+func TestWriteFileMissingDirectory(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "missing", "tasks.json")
+	if err := fileformat.WriteFile(path, []byte("new"), 0644); err == nil {
+		t.Error("WriteFile() succeeded in a directory that does not exist")
+	}
+}
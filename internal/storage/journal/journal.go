@@ -0,0 +1,246 @@
+package journal
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+
+	"github.com/ncfex/tasks/internal/storage/fileformat"
+	"github.com/ncfex/tasks/internal/task"
+)
+
+// maxEntries bounds how far back undo can go.
+const maxEntries = 100
+
+type journal struct {
+	filepath string
+	mu       sync.Mutex
+}
+
+type stacks struct {
+	Undo []task.JournalEntry `json:"undo"`
+	Redo []task.JournalEntry `json:"redo"`
+}
+
+// NewJournal returns a journal kept in a JSON file, used by the file-based
+// storage backends.
+func NewJournal(filepath string) task.Journal {
+	return &journal{
+		filepath: filepath,
+	}
+}
+
+func (j *journal) Append(ctx context.Context, entry task.JournalEntry) error {
+	unlock, err := j.lock(ctx)
+	if err != nil {
+		return err
+	}
+	defer unlock()
+
+	s, err := j.read()
+	if err != nil {
+		return err
+	}
+
+	s.Undo = append(s.Undo, entry)
+	if len(s.Undo) > maxEntries {
+		s.Undo = s.Undo[len(s.Undo)-maxEntries:]
+	}
+	s.Redo = nil
+
+	return j.write(s)
+}
+
+func (j *journal) Undo(ctx context.Context, apply func(task.JournalEntry) error) (*task.JournalEntry, error) {
+	return j.move(ctx, true, apply)
+}
+
+func (j *journal) Redo(ctx context.Context, apply func(task.JournalEntry) error) (*task.JournalEntry, error) {
+	return j.move(ctx, false, apply)
+}
+
+// move hands the entry on top of the undo stack, or of the redo stack, to
+// apply and moves it onto the other one if apply succeeds.
+func (j *journal) move(ctx context.Context, undo bool, apply func(task.JournalEntry) error) (*task.JournalEntry, error) {
+	unlock, err := j.lock(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer unlock()
+
+	s, err := j.read()
+	if err != nil {
+		return nil, err
+	}
+
+	from, to := &s.Undo, &s.Redo
+	if !undo {
+		from, to = to, from
+	}
+	if len(*from) == 0 {
+		if undo {
+			return nil, task.ErrNothingToUndo
+		}
+		return nil, task.ErrNothingToRedo
+	}
+
+	entry := (*from)[len(*from)-1]
+	if err := apply(entry); err != nil {
+		return nil, err
+	}
+
+	*from = (*from)[:len(*from)-1]
+	*to = append(*to, entry)
+
+	return &entry, j.write(s)
+}
+
+// Discard removes entry from the top of either stack. An entry that is no
+// longer on top is left alone.
+func (j *journal) Discard(ctx context.Context, entry task.JournalEntry) error {
+	unlock, err := j.lock(ctx)
+	if err != nil {
+		return err
+	}
+	defer unlock()
+
+	s, err := j.read()
+	if err != nil {
+		return err
+	}
+
+	for _, stack := range []*[]task.JournalEntry{&s.Undo, &s.Redo} {
+		if n := len(*stack); n > 0 && sameEntry((*stack)[n-1], entry) {
+			*stack = (*stack)[:n-1]
+			return j.write(s)
+		}
+	}
+
+	return nil
+}
+
+func sameEntry(a, b task.JournalEntry) bool {
+	return a.Summary == b.Summary && a.RecordedAt.Equal(b.RecordedAt)
+}
+
+// BindTx returns a journal that writes only once the transaction of repo,
+// a repository handed to the WithTx callback of a file backend, has
+// written the tasks file, so that it never records a change that was not
+// made.
+func (j *journal) BindTx(repo task.Repository) task.Journal {
+	committer, ok := repo.(fileformat.Committer)
+	if !ok {
+		return j
+	}
+	return &txJournal{journal: j, committer: committer}
+}
+
+type txJournal struct {
+	*journal
+	committer fileformat.Committer
+}
+
+func (j *txJournal) Append(ctx context.Context, entry task.JournalEntry) error {
+	j.committer.OnCommit(func() error {
+		return j.journal.Append(context.WithoutCancel(ctx), entry)
+	})
+	return nil
+}
+
+func (j *txJournal) Undo(ctx context.Context, apply func(task.JournalEntry) error) (*task.JournalEntry, error) {
+	return j.move(ctx, true, apply)
+}
+
+func (j *txJournal) Redo(ctx context.Context, apply func(task.JournalEntry) error) (*task.JournalEntry, error) {
+	return j.move(ctx, false, apply)
+}
+
+// move applies the entry on top of the stack now and moves it to the other
+// stack on commit, as long as it is still on top by then.
+func (j *txJournal) move(ctx context.Context, undo bool, apply func(task.JournalEntry) error) (*task.JournalEntry, error) {
+	var applied task.JournalEntry
+	_, err := j.journal.move(ctx, undo, func(entry task.JournalEntry) error {
+		if err := apply(entry); err != nil {
+			return err
+		}
+		applied = entry
+		return errDeferred
+	})
+	if !errors.Is(err, errDeferred) {
+		return nil, err
+	}
+
+	j.committer.OnCommit(func() error {
+		_, err := j.journal.move(context.WithoutCancel(ctx), undo, func(entry task.JournalEntry) error {
+			if !sameEntry(entry, applied) {
+				return fmt.Errorf("journal entry %q was changed by another process", applied.Summary)
+			}
+			return nil
+		})
+		return err
+	})
+	return &applied, nil
+}
+
+// errDeferred stops journal.move from moving an entry that a txJournal
+// moves on commit instead.
+var errDeferred = errors.New("journal write deferred")
+
+// lock guards the read-modify-write of the journal file against other
+// goroutines and other tasks processes.
+func (j *journal) lock(ctx context.Context) (func(), error) {
+	j.mu.Lock()
+
+	lock, err := fileformat.Acquire(ctx, j.filepath)
+	if err != nil {
+		j.mu.Unlock()
+		return nil, err
+	}
+
+	return func() {
+		lock.Release()
+		j.mu.Unlock()
+	}, nil
+}
+
+func (j *journal) read() (*stacks, error) {
+	data, err := os.ReadFile(j.filepath)
+	if os.IsNotExist(err) {
+		return &stacks{}, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read journal: %w", err)
+	}
+
+	s := &stacks{}
+	if len(data) == 0 {
+		return s, nil
+	}
+
+	if err := json.Unmarshal(data, s); err != nil {
+		return nil, fmt.Errorf("decode journal: %w", err)
+	}
+
+	return s, nil
+}
+
+func (j *journal) write(s *stacks) error {
+	if err := os.MkdirAll(filepath.Dir(j.filepath), 0755); err != nil {
+		return fmt.Errorf("failed to create directory: %w", err)
+	}
+
+	data, err := json.Marshal(s)
+	if err != nil {
+		return fmt.Errorf("encode journal: %w", err)
+	}
+
+	if err := fileformat.WriteFile(j.filepath, data, 0644); err != nil {
+		return fmt.Errorf("failed to write journal: %w", err)
+	}
+
+	return nil
+}
@@ -0,0 +1,184 @@
+package json
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/ncfex/tasks/internal/storage/fileformat"
+	"github.com/ncfex/tasks/internal/task"
+)
+
+// RecordError describes an entry of a tasks file that could not be decoded.
+// Index is the zero-based position of the entry in the tasks array.
+type RecordError struct {
+	Index  int             `json:"index"`
+	Record json.RawMessage `json:"record"`
+	Reason string          `json:"reason"`
+}
+
+type rawDocument struct {
+	Version int               `json:"version"`
+	NextNum int               `json:"next_num"`
+	Tasks   []json.RawMessage `json:"tasks"`
+}
+
+// decodeRecords decodes each entry on its own, so one bad entry doesn't
+// hide the others.
+func decodeRecords(data []byte) ([]task.Task, []RecordError, error) {
+	if len(bytes.TrimSpace(data)) == 0 {
+		return nil, nil, nil
+	}
+
+	var doc rawDocument
+	if err := json.Unmarshal(data, &doc); err != nil {
+		return nil, nil, fmt.Errorf("decode tasks: %w", err)
+	}
+
+	tasks := make([]task.Task, 0, len(doc.Tasks))
+	var recordErrors []RecordError
+	for i, raw := range doc.Tasks {
+		var t task.Task
+		if err := json.Unmarshal(raw, &t); err != nil {
+			recordErrors = append(recordErrors, RecordError{
+				Index:  i,
+				Record: raw,
+				Reason: err.Error(),
+			})
+			continue
+		}
+		tasks = append(tasks, t)
+	}
+
+	return tasks, recordErrors, nil
+}
+
+// Inspect reports the entries of the tasks file at path that cannot be
+// decoded, without modifying it.
+func Inspect(ctx context.Context, path string) ([]RecordError, error) {
+	lock, err := fileformat.Acquire(ctx, path)
+	if err != nil {
+		return nil, err
+	}
+	defer lock.Release()
+
+	data, err := os.ReadFile(path)
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to open file: %w", err)
+	}
+
+	if len(bytes.TrimSpace(data)) == 0 {
+		return nil, nil
+	}
+
+	version, err := detectVersion(data)
+	if err != nil {
+		return nil, err
+	}
+
+	data, err = pipeline.Convert(data, version)
+	if err != nil {
+		return nil, err
+	}
+
+	_, recordErrors, err := decodeRecords(data)
+	return recordErrors, err
+}
+
+// QuarantinePath is where entries that cannot be decoded are moved to.
+func QuarantinePath(path string) string {
+	return path + ".quarantine"
+}
+
+type quarantined struct {
+	QuarantinedAt time.Time `json:"quarantined_at"`
+	RecordError
+}
+
+// Quarantine moves the entries of the tasks file at path that cannot be
+// decoded to its quarantine file, one JSON object per line, and returns
+// them.
+func Quarantine(ctx context.Context, path string) ([]RecordError, error) {
+	lock, err := fileformat.Acquire(ctx, path)
+	if err != nil {
+		return nil, err
+	}
+	defer lock.Release()
+
+	data, err := readData(path)
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	tasks, recordErrors, err := decodeRecords(data)
+	if err != nil || len(recordErrors) == 0 {
+		return nil, err
+	}
+
+	var doc rawDocument
+	if err := json.Unmarshal(data, &doc); err != nil {
+		return nil, fmt.Errorf("decode tasks: %w", err)
+	}
+
+	file, err := os.OpenFile(QuarantinePath(path), os.O_WRONLY|os.O_CREATE|os.O_APPEND, 0644)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open quarantine file: %w", err)
+	}
+	defer file.Close()
+
+	encoder := json.NewEncoder(file)
+	now := time.Now()
+	for _, recordError := range recordErrors {
+		if err := encoder.Encode(quarantined{QuarantinedAt: now, RecordError: recordError}); err != nil {
+			return nil, fmt.Errorf("failed to write quarantine file: %w", err)
+		}
+	}
+	if err := file.Sync(); err != nil {
+		return nil, fmt.Errorf("failed to write quarantine file: %w", err)
+	}
+
+	if err := (codec{}).WriteTasks(path, tasks, fileformat.NextNum(doc.NextNum, tasks)); err != nil {
+		return nil, err
+	}
+
+	return recordErrors, nil
+}
+
+// Quarantined returns the entries previously moved to the quarantine file of
+// the tasks file at path.
+func Quarantined(path string) ([]RecordError, error) {
+	file, err := os.Open(QuarantinePath(path))
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to open quarantine file: %w", err)
+	}
+	defer file.Close()
+
+	var records []RecordError
+	scanner := bufio.NewScanner(file)
+	scanner.Buffer(make([]byte, 0, 64*1024), 1024*1024)
+	for scanner.Scan() {
+		var entry quarantined
+		if err := json.Unmarshal(scanner.Bytes(), &entry); err != nil {
+			return nil, fmt.Errorf("failed to read quarantine file: %w", err)
+		}
+		records = append(records, entry.RecordError)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read quarantine file: %w", err)
+	}
+
+	return records, nil
+}
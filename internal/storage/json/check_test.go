@@ -0,0 +1,81 @@
+package json_test
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	jsonstore "github.com/ncfex/tasks/internal/storage/json"
+	"github.com/ncfex/tasks/internal/task"
+)
+
+// corruptedV1File is a version 1 file whose second entry has a bad date.
+const corruptedV1File = `[
+  {"id":"0b6c2d4e-1f3a-4b5c-8d7e-9f0a1b2c3d4e","description":"buy milk","is_completed":false,"created_at":"2024-03-01T09:00:00Z","due_date":"0001-01-01T00:00:00Z"},
+  {"id":"5e8f1a2b-3c4d-4e5f-a6b7-c8d9e0f1a2b3","description":"file taxes","is_completed":true,"created_at":"bad","due_date":"2024-04-15T00:00:00Z"},
+  {"id":"7a1b2c3d-4e5f-4a6b-8c7d-9e0f1a2b3c4d","description":"call mum","is_completed":false,"created_at":"2024-03-03T09:00:00Z","due_date":"0001-01-01T00:00:00Z"}
+]`
+
+func TestInspectCorruptedV1File(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "tasks.json")
+	if err := os.WriteFile(path, []byte(corruptedV1File), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	records, err := jsonstore.Inspect(context.Background(), path)
+	if err != nil {
+		t.Fatalf("Inspect(): %v", err)
+	}
+	if len(records) != 1 || records[0].Index != 1 || !strings.Contains(string(records[0].Record), "file taxes") {
+		t.Fatalf("Inspect() = %+v, want the second entry", records)
+	}
+	if got, _ := os.ReadFile(path); string(got) != corruptedV1File {
+		t.Error("Inspect() modified the file")
+	}
+}
+
+func TestQuarantineCorruptedV1File(t *testing.T) {
+	ctx := context.Background()
+	path := filepath.Join(t.TempDir(), "tasks.json")
+	if err := os.WriteFile(path, []byte(corruptedV1File), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := jsonstore.NewRepository(path).List(ctx, task.NewTaskSelector(), task.NewTaskFilter()); err == nil {
+		t.Fatal("List() read a file with a bad entry")
+	}
+
+	records, err := jsonstore.Quarantine(ctx, path)
+	if err != nil {
+		t.Fatalf("Quarantine(): %v", err)
+	}
+	if len(records) != 1 || records[0].Index != 1 {
+		t.Fatalf("Quarantine() = %+v, want the second entry", records)
+	}
+
+	quarantined, err := jsonstore.Quarantined(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(quarantined) != 1 || !strings.Contains(string(quarantined[0].Record), `"created_at":"bad"`) {
+		t.Errorf("Quarantined() = %+v, want the original entry", quarantined)
+	}
+
+	tasks, err := jsonstore.NewRepository(path).List(ctx, task.NewTaskSelector(), &task.TaskFilter{IncludeCompleted: true})
+	if err != nil {
+		t.Fatalf("List() after quarantine: %v", err)
+	}
+	nums := map[string]int{}
+	for _, tk := range tasks {
+		nums[tk.Description] = tk.Num
+	}
+	if len(tasks) != 2 || nums["buy milk"] != 1 || nums["call mum"] != 3 {
+		t.Errorf("tasks after quarantine = %v, want buy milk #1 and call mum #3", nums)
+	}
+
+	if backups, _ := filepath.Glob(path + ".v1-*.bak"); len(backups) != 1 {
+		t.Errorf("backups = %v, want the original v1 file kept", backups)
+	}
+}
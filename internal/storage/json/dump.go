@@ -0,0 +1,145 @@
+package json
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+
+	"github.com/ncfex/tasks/internal/task"
+)
+
+// Encoder writes tasks as a tasks.json document one at a time, so that a
+// dump of any size can be written without holding it in memory. The
+// output can be used as a tasks.json file.
+type Encoder struct {
+	writer  *bufio.Writer
+	count   int
+	nextNum int
+}
+
+func NewEncoder(w io.Writer) *Encoder {
+	return &Encoder{writer: bufio.NewWriter(w), nextNum: 1}
+}
+
+func (e *Encoder) Encode(t *task.Task) error {
+	data, err := json.Marshal(t)
+	if err != nil {
+		return fmt.Errorf("encode task %s: %w", t.ID, err)
+	}
+
+	prefix := ",\n"
+	if e.count == 0 {
+		prefix = fmt.Sprintf("{\"version\":%d,\"tasks\":[\n", formatVersion)
+	}
+	if _, err := e.writer.WriteString(prefix); err != nil {
+		return err
+	}
+	if _, err := e.writer.Write(data); err != nil {
+		return err
+	}
+
+	e.count++
+	e.nextNum = max(e.nextNum, t.Num+1)
+	return nil
+}
+
+// Close ends the document with the task number counter, which is only
+// known once every task has been written, and flushes the output.
+func (e *Encoder) Close() error {
+	var err error
+	if e.count == 0 {
+		_, err = fmt.Fprintf(e.writer, "{\"version\":%d,\"tasks\":[],\"next_num\":1}\n", formatVersion)
+	} else {
+		_, err = fmt.Fprintf(e.writer, "\n],\"next_num\":%d}\n", e.nextNum)
+	}
+	if err != nil {
+		return err
+	}
+	return e.writer.Flush()
+}
+
+// Decoder reads tasks written by Encoder, or a tasks.json file in the
+// current format, one at a time.
+type Decoder struct {
+	decoder *json.Decoder
+	inTasks bool
+	done    bool
+}
+
+func NewDecoder(r io.Reader) *Decoder {
+	return &Decoder{decoder: json.NewDecoder(r)}
+}
+
+// Decode returns the next task, or io.EOF after the last one.
+func (d *Decoder) Decode() (*task.Task, error) {
+	for !d.done {
+		if d.inTasks {
+			if d.decoder.More() {
+				var t task.Task
+				if err := d.decoder.Decode(&t); err != nil {
+					return nil, fmt.Errorf("decode tasks: %w", err)
+				}
+				return &t, nil
+			}
+			// The closing bracket of the tasks array.
+			if _, err := d.decoder.Token(); err != nil {
+				return nil, fmt.Errorf("decode tasks: %w", err)
+			}
+			d.inTasks = false
+		}
+
+		if err := d.nextKey(); err != nil {
+			return nil, err
+		}
+	}
+
+	return nil, io.EOF
+}
+
+// nextKey reads the document up to the tasks array, checking the format
+// version on the way, or to its end.
+func (d *Decoder) nextKey() error {
+	tok, err := d.decoder.Token()
+	if errors.Is(err, io.EOF) {
+		return fmt.Errorf("decode tasks: unexpected end of document")
+	}
+	if err != nil {
+		return fmt.Errorf("decode tasks: %w", err)
+	}
+
+	switch tok {
+	case json.Delim('{'):
+		return nil
+	case json.Delim('}'):
+		d.done = true
+		return nil
+	case json.Delim('['):
+		return fmt.Errorf("unsupported format version 1: open the file with the json backend to upgrade it")
+	case "version":
+		var version int
+		if err := d.decoder.Decode(&version); err != nil {
+			return fmt.Errorf("decode format version: %w", err)
+		}
+		if version != formatVersion {
+			return fmt.Errorf("unsupported format version %d: open the file with the json backend to upgrade it", version)
+		}
+	case "tasks":
+		tok, err := d.decoder.Token()
+		if err != nil {
+			return fmt.Errorf("decode tasks: %w", err)
+		}
+		if tok != json.Delim('[') {
+			return fmt.Errorf("decode tasks: tasks is not an array")
+		}
+		d.inTasks = true
+	default:
+		var skipped json.RawMessage
+		if err := d.decoder.Decode(&skipped); err != nil {
+			return fmt.Errorf("decode tasks: %w", err)
+		}
+	}
+
+	return nil
+}
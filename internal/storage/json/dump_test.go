@@ -0,0 +1,19 @@
+package json_test
+
+import (
+	"io"
+	"testing"
+
+	jsonstore "github.com/ncfex/tasks/internal/storage/json"
+	"github.com/ncfex/tasks/internal/storage/storagetest"
+	"github.com/ncfex/tasks/internal/task"
+)
+
+func TestDump(t *testing.T) {
+	storagetest.RunDump(t, storagetest.Dump{
+		File:       "tasks.json",
+		NewEncoder: func(w io.Writer) storagetest.Encoder { return jsonstore.NewEncoder(w) },
+		NewDecoder: func(r io.Reader) storagetest.Decoder { return jsonstore.NewDecoder(r) },
+		Open:       func(path string) task.Repository { return jsonstore.NewRepository(path) },
+	})
+}
@@ -0,0 +1,95 @@
+package json
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"strconv"
+
+	"github.com/ncfex/tasks/internal/storage/fileformat"
+	"github.com/ncfex/tasks/internal/task"
+)
+
+// formatVersion is the current layout of tasks.json:
+//
+//	1: a bare array of tasks
+//	2: an object with a "version" and a "tasks" array
+//	3: tasks have a "num" and the object a "next_num" counter
+const formatVersion = 3
+
+type document struct {
+	Version int         `json:"version"`
+	NextNum int         `json:"next_num"`
+	Tasks   []task.Task `json:"tasks"`
+}
+
+var pipeline = fileformat.Pipeline{
+	Current: formatVersion,
+	Steps: map[int]fileformat.UpgradeFunc{
+		1: upgradeV1,
+		2: upgradeV2,
+	},
+}
+
+func detectVersion(data []byte) (int, error) {
+	trimmed := bytes.TrimSpace(data)
+	if len(trimmed) > 0 && trimmed[0] == '[' {
+		return 1, nil
+	}
+
+	var header struct {
+		Version int `json:"version"`
+	}
+	if err := json.Unmarshal(trimmed, &header); err != nil {
+		return 0, fmt.Errorf("decode format version: %w", err)
+	}
+	if header.Version == 0 {
+		return 0, fmt.Errorf("missing format version")
+	}
+
+	return header.Version, nil
+}
+
+// upgradeV1 wraps the array in a versioned document. Entries are carried
+// over as they are, so one that cannot be decoded is reported or
+// quarantined like in any other file instead of failing the upgrade.
+func upgradeV1(data []byte) ([]byte, error) {
+	var tasks []json.RawMessage
+	if err := json.Unmarshal(data, &tasks); err != nil {
+		return nil, fmt.Errorf("decode tasks: %w", err)
+	}
+	if tasks == nil {
+		tasks = []json.RawMessage{}
+	}
+
+	return json.Marshal(rawDocument{Version: 2, Tasks: tasks})
+}
+
+// upgradeV2 numbers the existing tasks in file order. Entries that are not
+// objects are carried over unnumbered.
+func upgradeV2(data []byte) ([]byte, error) {
+	var doc rawDocument
+	if err := json.Unmarshal(data, &doc); err != nil {
+		return nil, fmt.Errorf("decode tasks: %w", err)
+	}
+	if doc.Tasks == nil {
+		doc.Tasks = []json.RawMessage{}
+	}
+
+	for i, raw := range doc.Tasks {
+		var fields map[string]json.RawMessage
+		if err := json.Unmarshal(raw, &fields); err != nil || fields == nil {
+			continue
+		}
+		fields["num"] = json.RawMessage(strconv.Itoa(i + 1))
+		numbered, err := json.Marshal(fields)
+		if err != nil {
+			return nil, fmt.Errorf("encode task: %w", err)
+		}
+		doc.Tasks[i] = numbered
+	}
+	doc.Version = 3
+	doc.NextNum = len(doc.Tasks) + 1
+
+	return json.Marshal(doc)
+}
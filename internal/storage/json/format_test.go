@@ -0,0 +1,100 @@
+package json_test
+
+import (
+	"context"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+
+	jsonstore "github.com/ncfex/tasks/internal/storage/json"
+	"github.com/ncfex/tasks/internal/task"
+)
+
+const (
+	v1File = `[
+  {"id":"0b6c2d4e-1f3a-4b5c-8d7e-9f0a1b2c3d4e","description":"buy milk","is_completed":false,"created_at":"2024-03-01T09:00:00Z","due_date":"0001-01-01T00:00:00Z"},
+  {"id":"5e8f1a2b-3c4d-4e5f-a6b7-c8d9e0f1a2b3","description":"file taxes","is_completed":true,"created_at":"2024-03-02T09:00:00Z","due_date":"2024-04-15T00:00:00Z"}
+]`
+	v2File = `{"version":2,"tasks":[
+  {"id":"0b6c2d4e-1f3a-4b5c-8d7e-9f0a1b2c3d4e","description":"buy milk","status":"todo","created_at":"2024-03-01T09:00:00Z","due_date":"0001-01-01T00:00:00Z"},
+  {"id":"5e8f1a2b-3c4d-4e5f-a6b7-c8d9e0f1a2b3","description":"file taxes","status":"done","created_at":"2024-03-02T09:00:00Z","due_date":"2024-04-15T00:00:00Z","completed_at":"2024-03-05T10:00:00Z"}
+]}`
+)
+
+func TestUpgrade(t *testing.T) {
+	tests := []struct {
+		name    string
+		content string
+		backup  string
+	}{
+		{name: "v1", content: v1File, backup: "tasks.json.v1-*.bak"},
+		{name: "v2", content: v2File, backup: "tasks.json.v2-*.bak"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir := t.TempDir()
+			path := filepath.Join(dir, "tasks.json")
+			if err := os.WriteFile(path, []byte(tt.content), 0644); err != nil {
+				t.Fatal(err)
+			}
+
+			tasks, err := jsonstore.NewRepository(path).List(context.Background(), task.NewTaskSelector(), &task.TaskFilter{IncludeCompleted: true})
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(tasks) != 2 {
+				t.Fatalf("List() = %d tasks, want 2", len(tasks))
+			}
+			byDescription := map[string]task.Task{}
+			for _, tk := range tasks {
+				byDescription[tk.Description] = tk
+			}
+			if milk := byDescription["buy milk"]; milk.Num != 1 || milk.Status != task.StatusTodo {
+				t.Errorf("buy milk = #%d %s, want #1 todo", milk.Num, milk.Status)
+			}
+			if taxes := byDescription["file taxes"]; taxes.Num != 2 || taxes.Status != task.StatusDone {
+				t.Errorf("file taxes = #%d %s, want #2 done", taxes.Num, taxes.Status)
+			}
+
+			data, err := os.ReadFile(path)
+			if err != nil {
+				t.Fatal(err)
+			}
+			var doc struct {
+				Version int `json:"version"`
+				NextNum int `json:"next_num"`
+			}
+			if err := json.Unmarshal(data, &doc); err != nil {
+				t.Fatalf("upgraded file: %v", err)
+			}
+			if doc.Version != 3 || doc.NextNum != 3 {
+				t.Errorf("upgraded file has version %d and next_num %d, want 3 and 3", doc.Version, doc.NextNum)
+			}
+
+			backups, _ := filepath.Glob(filepath.Join(dir, tt.backup))
+			if len(backups) != 1 {
+				t.Fatalf("backups = %v, want one matching %s", backups, tt.backup)
+			}
+			if got, _ := os.ReadFile(backups[0]); string(got) != tt.content {
+				t.Errorf("backup = %q, want the original file", got)
+			}
+		})
+	}
+}
+
+func TestNewerFormatIsRejected(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "tasks.json")
+	content := `{"version":99,"tasks":[]}`
+	if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := jsonstore.NewRepository(path).List(context.Background(), nil, nil); err == nil {
+		t.Fatal("List() read a file from a newer format version")
+	}
+	if got, _ := os.ReadFile(path); string(got) != content {
+		t.Errorf("file = %q, want it unchanged", got)
+	}
+}
@@ -0,0 +1,25 @@
+package json_test
+
+import (
+	"path/filepath"
+	"testing"
+
+	jsonstore "github.com/ncfex/tasks/internal/storage/json"
+	"github.com/ncfex/tasks/internal/storage/storagetest"
+	"github.com/ncfex/tasks/internal/task"
+)
+
+func TestRepository(t *testing.T) {
+	storagetest.Run(t, func(t *testing.T) storagetest.Opener {
+		path := filepath.Join(t.TempDir(), "tasks.json")
+		return func(t *testing.T) task.Repository {
+			return jsonstore.NewRepository(path)
+		}
+	})
+}
+
+func TestConcurrentProcesses(t *testing.T) {
+	storagetest.RunProcesses(t, "tasks.json", func(path string) task.Repository {
+		return jsonstore.NewRepository(path)
+	})
+}
@@ -0,0 +1,69 @@
+package sql
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+
+	"github.com/google/uuid"
+	"github.com/ncfex/tasks/internal/storage/sql/database"
+	"github.com/ncfex/tasks/internal/task"
+)
+
+type auditLog struct {
+	db *database.Queries
+}
+
+func NewAuditLog(db *sql.DB) task.AuditLog {
+	return &auditLog{db: database.New(db)}
+}
+
+// BindTx returns an audit log that records in the transaction of repo, a
+// repository handed to a WithTx callback.
+func (l *auditLog) BindTx(repo task.Repository) task.AuditLog {
+	r, ok := repo.(*repository)
+	if !ok || r.tx == nil {
+		return l
+	}
+	return &auditLog{db: l.db.WithTx(r.tx)}
+}
+
+func (l *auditLog) Record(ctx context.Context, event task.AuditEvent) error {
+	diffs, err := json.Marshal(event.Diffs)
+	if err != nil {
+		return fmt.Errorf("encode audit event: %w", err)
+	}
+
+	params := database.InsertTaskHistoryParams{
+		TaskID:     event.TaskID,
+		Action:     string(event.Action),
+		Username:   event.User,
+		Diffs:      string(diffs),
+		RecordedAt: event.RecordedAt,
+	}
+
+	return l.db.InsertTaskHistory(ctx, params)
+}
+
+func (l *auditLog) History(ctx context.Context, taskID uuid.UUID) ([]task.AuditEvent, error) {
+	rows, err := l.db.GetTaskHistory(ctx, taskID)
+	if err != nil {
+		return nil, err
+	}
+
+	events := make([]task.AuditEvent, len(rows))
+	for i, row := range rows {
+		events[i] = task.AuditEvent{
+			TaskID:     row.TaskID,
+			Action:     task.Action(row.Action),
+			User:       row.Username,
+			RecordedAt: row.RecordedAt,
+		}
+		if err := json.Unmarshal([]byte(row.Diffs), &events[i].Diffs); err != nil {
+			return nil, fmt.Errorf("decode audit event: %w", err)
+		}
+	}
+
+	return events, nil
+}
@@ -5,15 +5,44 @@
 package database
 
 import (
+	"database/sql"
 	"time"
 
 	"github.com/google/uuid"
 )
 
+type JournalEntry struct {
+	ID         int64
+	Summary    string
+	Changes    string
+	RecordedAt time.Time
+	Undone     bool
+	Username   string
+}
+
+type TaskHistory struct {
+	ID         int64
+	TaskID     uuid.UUID
+	Action     string
+	Username   string
+	Diffs      string
+	RecordedAt time.Time
+}
+
 type Task struct {
-	ID          uuid.UUID
-	Description string
-	IsCompleted bool
-	CreatedAt   time.Time
-	DueDate     time.Time
+	ID              uuid.UUID
+	Description     string
+	CreatedAt       time.Time
+	DueDate         time.Time
+	EstimateSeconds int64
+	CompletedAt     sql.NullTime
+	Status          string
+	DeletedAt       sql.NullTime
+	Num             int64
+	Version         int64
+	UpdatedAt       time.Time
+	Priority        string
+	Project         string
+	Tags            string
+	Annotations     string
 }
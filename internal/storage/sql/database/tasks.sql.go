@@ -13,70 +13,51 @@ import (
 	"github.com/google/uuid"
 )
 
-const completeTask = `-- name: CompleteTask :one
-UPDATE tasks
-SET is_completed = TRUE
-WHERE id = $1
-RETURNING id, description, is_completed, created_at, due_date
-`
-
-func (q *Queries) CompleteTask(ctx context.Context, id uuid.UUID) (Task, error) {
-	row := q.db.QueryRowContext(ctx, completeTask, id)
-	var i Task
-	err := row.Scan(
-		&i.ID,
-		&i.Description,
-		&i.IsCompleted,
-		&i.CreatedAt,
-		&i.DueDate,
-	)
-	return i, err
-}
-
-const createTask = `-- name: CreateTask :one
-INSERT INTO tasks (id, description, is_completed, created_at, due_date)
-VALUES (
-    gen_random_uuid(),
-    $1,
-    FALSE,
-    NOW(),
-    $2
-)
-RETURNING id, description, is_completed, created_at, due_date
+const createTask = `-- name: CreateTask :exec
+INSERT INTO tasks (id, description, status, created_at, due_date, estimate_seconds, completed_at, deleted_at, num, version, updated_at, priority, project, tags, annotations)
+VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14, $15)
 `
 
 type CreateTaskParams struct {
-	Description string
-	DueDate     time.Time
+	ID              uuid.UUID
+	Description     string
+	Status          string
+	CreatedAt       time.Time
+	DueDate         time.Time
+	EstimateSeconds int64
+	CompletedAt     sql.NullTime
+	DeletedAt       sql.NullTime
+	Num             sql.NullInt64
+	Version         int64
+	UpdatedAt       time.Time
+	Priority        string
+	Project         string
+	Tags            string
+	Annotations     string
 }
 
-func (q *Queries) CreateTask(ctx context.Context, arg CreateTaskParams) (Task, error) {
-	row := q.db.QueryRowContext(ctx, createTask, arg.Description, arg.DueDate)
-	var i Task
-	err := row.Scan(
-		&i.ID,
-		&i.Description,
-		&i.IsCompleted,
-		&i.CreatedAt,
-		&i.DueDate,
-	)
-	return i, err
+func (q *Queries) CreateTask(ctx context.Context, arg CreateTaskParams) error {
+	_, err := q.db.ExecContext(ctx, createTask, arg.ID, arg.Description, arg.Status, arg.CreatedAt, arg.DueDate, arg.EstimateSeconds, arg.CompletedAt, arg.DeletedAt, arg.Num, arg.Version, arg.UpdatedAt, arg.Priority, arg.Project, arg.Tags, arg.Annotations)
+	return err
 }
 
-const deleteTask = `-- name: DeleteTask :exec
+const deleteTask = `-- name: DeleteTask :execrows
 DELETE FROM tasks
 WHERE id = $1
 `
 
-func (q *Queries) DeleteTask(ctx context.Context, id uuid.UUID) error {
-	_, err := q.db.ExecContext(ctx, deleteTask, id)
-	return err
+func (q *Queries) DeleteTask(ctx context.Context, id uuid.UUID) (int64, error) {
+	result, err := q.db.ExecContext(ctx, deleteTask, id)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
 }
 
 const getAllCompletedTasks = `-- name: GetAllCompletedTasks :many
-SELECT id, description, is_completed, created_at, due_date
+SELECT id, description, created_at, due_date, estimate_seconds, completed_at, status, deleted_at, num, version, updated_at, priority, project, tags, annotations
 FROM tasks
-WHERE is_completed::boolean = TRUE
+WHERE status = 'done'
 `
 
 func (q *Queries) GetAllCompletedTasks(ctx context.Context) ([]Task, error) {
@@ -91,9 +72,19 @@ func (q *Queries) GetAllCompletedTasks(ctx context.Context) ([]Task, error) {
 		if err := rows.Scan(
 			&i.ID,
 			&i.Description,
-			&i.IsCompleted,
 			&i.CreatedAt,
 			&i.DueDate,
+			&i.EstimateSeconds,
+			&i.CompletedAt,
+			&i.Status,
+			&i.DeletedAt,
+			&i.Num,
+			&i.Version,
+			&i.UpdatedAt,
+			&i.Priority,
+			&i.Project,
+			&i.Tags,
+			&i.Annotations,
 		); err != nil {
 			return nil, err
 		}
@@ -109,9 +100,9 @@ func (q *Queries) GetAllCompletedTasks(ctx context.Context) ([]Task, error) {
 }
 
 const getAllDueTasks = `-- name: GetAllDueTasks :many
-SELECT id, description, is_completed, created_at, due_date
+SELECT id, description, created_at, due_date, estimate_seconds, completed_at, status, deleted_at, num, version, updated_at, priority, project, tags, annotations
 FROM tasks
-WHERE is_completed::boolean = FALSE
+WHERE status NOT IN ('done', 'cancelled')
 `
 
 func (q *Queries) GetAllDueTasks(ctx context.Context) ([]Task, error) {
@@ -126,9 +117,19 @@ func (q *Queries) GetAllDueTasks(ctx context.Context) ([]Task, error) {
 		if err := rows.Scan(
 			&i.ID,
 			&i.Description,
-			&i.IsCompleted,
 			&i.CreatedAt,
 			&i.DueDate,
+			&i.EstimateSeconds,
+			&i.CompletedAt,
+			&i.Status,
+			&i.DeletedAt,
+			&i.Num,
+			&i.Version,
+			&i.UpdatedAt,
+			&i.Priority,
+			&i.Project,
+			&i.Tags,
+			&i.Annotations,
 		); err != nil {
 			return nil, err
 		}
@@ -144,7 +145,7 @@ func (q *Queries) GetAllDueTasks(ctx context.Context) ([]Task, error) {
 }
 
 const getAllTasks = `-- name: GetAllTasks :many
-SELECT id, description, is_completed, created_at, due_date 
+SELECT id, description, created_at, due_date, estimate_seconds, completed_at, status, deleted_at, num, version, updated_at, priority, project, tags, annotations 
 FROM tasks
 `
 
@@ -160,9 +161,19 @@ func (q *Queries) GetAllTasks(ctx context.Context) ([]Task, error) {
 		if err := rows.Scan(
 			&i.ID,
 			&i.Description,
-			&i.IsCompleted,
 			&i.CreatedAt,
 			&i.DueDate,
+			&i.EstimateSeconds,
+			&i.CompletedAt,
+			&i.Status,
+			&i.DeletedAt,
+			&i.Num,
+			&i.Version,
+			&i.UpdatedAt,
+			&i.Priority,
+			&i.Project,
+			&i.Tags,
+			&i.Annotations,
 		); err != nil {
 			return nil, err
 		}
@@ -178,7 +189,7 @@ func (q *Queries) GetAllTasks(ctx context.Context) ([]Task, error) {
 }
 
 const getTaskById = `-- name: GetTaskById :one
-SELECT id, description, is_completed, created_at, due_date
+SELECT id, description, created_at, due_date, estimate_seconds, completed_at, status, deleted_at, num, version, updated_at, priority, project, tags, annotations
 FROM tasks
 WHERE id = $1
 `
@@ -189,29 +200,204 @@ func (q *Queries) GetTaskById(ctx context.Context, id uuid.UUID) (Task, error) {
 	err := row.Scan(
 		&i.ID,
 		&i.Description,
-		&i.IsCompleted,
 		&i.CreatedAt,
 		&i.DueDate,
+		&i.EstimateSeconds,
+		&i.CompletedAt,
+		&i.Status,
+		&i.DeletedAt,
+		&i.Num,
+		&i.Version,
+		&i.UpdatedAt,
+		&i.Priority,
+		&i.Project,
+		&i.Tags,
+		&i.Annotations,
 	)
 	return i, err
 }
 
-const getTaskByPartialId = `-- name: GetTaskByPartialId :one
-SELECT id, description, is_completed, created_at, due_date
+const getTaskByNum = `-- name: GetTaskByNum :one
+SELECT id, description, created_at, due_date, estimate_seconds, completed_at, status, deleted_at, num, version, updated_at, priority, project, tags, annotations
+FROM tasks
+WHERE num = $1
+`
+
+func (q *Queries) GetTaskByNum(ctx context.Context, num int64) (Task, error) {
+	row := q.db.QueryRowContext(ctx, getTaskByNum, num)
+	var i Task
+	err := row.Scan(
+		&i.ID,
+		&i.Description,
+		&i.CreatedAt,
+		&i.DueDate,
+		&i.EstimateSeconds,
+		&i.CompletedAt,
+		&i.Status,
+		&i.DeletedAt,
+		&i.Num,
+		&i.Version,
+		&i.UpdatedAt,
+		&i.Priority,
+		&i.Project,
+		&i.Tags,
+		&i.Annotations,
+	)
+	return i, err
+}
+
+const getTasksByPartialId = `-- name: GetTasksByPartialId :many
+SELECT id, description, created_at, due_date, estimate_seconds, completed_at, status, deleted_at, num, version, updated_at, priority, project, tags, annotations
+FROM tasks
+WHERE substr(CAST(id AS TEXT), 1, length(CAST($1 AS TEXT))) = CAST($1 AS TEXT)
+ORDER BY id
+`
+
+func (q *Queries) GetTasksByPartialId(ctx context.Context, dollar_1 string) ([]Task, error) {
+	rows, err := q.db.QueryContext(ctx, getTasksByPartialId, dollar_1)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []Task
+	for rows.Next() {
+		var i Task
+		if err := rows.Scan(
+			&i.ID,
+			&i.Description,
+			&i.CreatedAt,
+			&i.DueDate,
+			&i.EstimateSeconds,
+			&i.CompletedAt,
+			&i.Status,
+			&i.DeletedAt,
+			&i.Num,
+			&i.Version,
+			&i.UpdatedAt,
+			&i.Priority,
+			&i.Project,
+			&i.Tags,
+			&i.Annotations,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const listTasksAfter = `-- name: ListTasksAfter :many
+SELECT id, description, created_at, due_date, estimate_seconds, completed_at, status, deleted_at, num, version, updated_at, priority, project, tags, annotations
 FROM tasks
-WHERE id::text LIKE $1 || '%'
-LIMIT 1
+WHERE num > $1 OR (num = $1 AND id > $2)
+ORDER BY num, id
+LIMIT $3
 `
 
-func (q *Queries) GetTaskByPartialId(ctx context.Context, dollar_1 sql.NullString) (Task, error) {
-	row := q.db.QueryRowContext(ctx, getTaskByPartialId, dollar_1)
+type ListTasksAfterParams struct {
+	Num   int64
+	ID    uuid.UUID
+	Limit int32
+}
+
+func (q *Queries) ListTasksAfter(ctx context.Context, arg ListTasksAfterParams) ([]Task, error) {
+	rows, err := q.db.QueryContext(ctx, listTasksAfter, arg.Num, arg.ID, arg.Limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []Task
+	for rows.Next() {
+		var i Task
+		if err := rows.Scan(
+			&i.ID,
+			&i.Description,
+			&i.CreatedAt,
+			&i.DueDate,
+			&i.EstimateSeconds,
+			&i.CompletedAt,
+			&i.Status,
+			&i.DeletedAt,
+			&i.Num,
+			&i.Version,
+			&i.UpdatedAt,
+			&i.Priority,
+			&i.Project,
+			&i.Tags,
+			&i.Annotations,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const updateTask = `-- name: UpdateTask :one
+UPDATE tasks
+SET description = $2,
+    status = $3,
+    due_date = $4,
+    estimate_seconds = $5,
+    completed_at = $6,
+    deleted_at = $7,
+    updated_at = $9,
+    priority = $10,
+    project = $11,
+    tags = $12,
+    annotations = $13,
+    version = version + 1
+WHERE id = $1 AND version = $8
+RETURNING id, description, created_at, due_date, estimate_seconds, completed_at, status, deleted_at, num, version, updated_at, priority, project, tags, annotations
+`
+
+type UpdateTaskParams struct {
+	ID              uuid.UUID
+	Description     string
+	Status          string
+	DueDate         time.Time
+	EstimateSeconds int64
+	CompletedAt     sql.NullTime
+	DeletedAt       sql.NullTime
+	Version         int64
+	UpdatedAt       time.Time
+	Priority        string
+	Project         string
+	Tags            string
+	Annotations     string
+}
+
+func (q *Queries) UpdateTask(ctx context.Context, arg UpdateTaskParams) (Task, error) {
+	row := q.db.QueryRowContext(ctx, updateTask, arg.ID, arg.Description, arg.Status, arg.DueDate, arg.EstimateSeconds, arg.CompletedAt, arg.DeletedAt, arg.Version, arg.UpdatedAt, arg.Priority, arg.Project, arg.Tags, arg.Annotations)
 	var i Task
 	err := row.Scan(
 		&i.ID,
 		&i.Description,
-		&i.IsCompleted,
 		&i.CreatedAt,
 		&i.DueDate,
+		&i.EstimateSeconds,
+		&i.CompletedAt,
+		&i.Status,
+		&i.DeletedAt,
+		&i.Num,
+		&i.Version,
+		&i.UpdatedAt,
+		&i.Priority,
+		&i.Project,
+		&i.Tags,
+		&i.Annotations,
 	)
 	return i, err
 }
@@ -0,0 +1,203 @@
+package sql
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+
+	"github.com/ncfex/tasks/internal/storage/sql/database"
+	"github.com/ncfex/tasks/internal/task"
+)
+
+// maxJournalEntries bounds how far back undo can go.
+const maxJournalEntries = 100
+
+// journal keeps the undo and redo stacks of one user. Each call runs in a
+// single transaction, the one of the repository it was bound to if any.
+type journal struct {
+	conn    *sql.DB
+	db      *database.Queries
+	dialect Dialect
+	user    string
+
+	// tx is set on a journal bound to a repository transaction.
+	tx *sql.Tx
+}
+
+// NewJournal returns the journal of user, so that each user undoes their
+// own changes only.
+func NewJournal(db *sql.DB, dialect Dialect, user string) task.Journal {
+	return &journal{
+		conn:    db,
+		db:      database.New(db),
+		dialect: dialect,
+		user:    user,
+	}
+}
+
+// BindTx returns a journal that works in the transaction of repo, a
+// repository handed to a WithTx callback.
+func (j *journal) BindTx(repo task.Repository) task.Journal {
+	r, ok := repo.(*repository)
+	if !ok || r.tx == nil {
+		return j
+	}
+
+	bound := *j
+	bound.db = j.db.WithTx(r.tx)
+	bound.tx = r.tx
+	return &bound
+}
+
+func (j *journal) Append(ctx context.Context, entry task.JournalEntry) error {
+	changes, err := json.Marshal(entry.Changes)
+	if err != nil {
+		return fmt.Errorf("encode journal entry: %w", err)
+	}
+
+	return j.withTx(ctx, func(db *database.Queries) error {
+		if err := db.DeleteUndoneJournalEntries(ctx, j.user); err != nil {
+			return err
+		}
+
+		params := database.AppendJournalEntryParams{
+			Username:   j.user,
+			Summary:    entry.Summary,
+			Changes:    string(changes),
+			RecordedAt: entry.RecordedAt,
+		}
+		if err := db.AppendJournalEntry(ctx, params); err != nil {
+			return err
+		}
+
+		return db.TrimJournalEntries(ctx, database.TrimJournalEntriesParams{
+			Username: j.user,
+			Limit:    maxJournalEntries,
+		})
+	})
+}
+
+func (j *journal) Undo(ctx context.Context, apply func(task.JournalEntry) error) (*task.JournalEntry, error) {
+	var entry *task.JournalEntry
+	err := j.withTx(ctx, func(db *database.Queries) error {
+		next := db.GetNextUndoJournalEntry
+		if j.dialect == DialectPostgres {
+			next = db.GetNextUndoJournalEntryForUpdate
+		}
+
+		row, err := next(ctx, j.user)
+		if errors.Is(err, sql.ErrNoRows) {
+			return task.ErrNothingToUndo
+		}
+		if err != nil {
+			return err
+		}
+
+		entry, err = move(ctx, db, row, true, apply)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return entry, nil
+}
+
+func (j *journal) Redo(ctx context.Context, apply func(task.JournalEntry) error) (*task.JournalEntry, error) {
+	var entry *task.JournalEntry
+	err := j.withTx(ctx, func(db *database.Queries) error {
+		next := db.GetNextRedoJournalEntry
+		if j.dialect == DialectPostgres {
+			next = db.GetNextRedoJournalEntryForUpdate
+		}
+
+		row, err := next(ctx, j.user)
+		if errors.Is(err, sql.ErrNoRows) {
+			return task.ErrNothingToRedo
+		}
+		if err != nil {
+			return err
+		}
+
+		entry, err = move(ctx, db, row, false, apply)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return entry, nil
+}
+
+// Discard removes entry from the top of either stack. An entry that is no
+// longer on top is left alone.
+func (j *journal) Discard(ctx context.Context, entry task.JournalEntry) error {
+	return j.withTx(ctx, func(db *database.Queries) error {
+		for _, next := range []func(context.Context, string) (database.JournalEntry, error){
+			db.GetNextUndoJournalEntry,
+			db.GetNextRedoJournalEntry,
+		} {
+			row, err := next(ctx, j.user)
+			if errors.Is(err, sql.ErrNoRows) {
+				continue
+			}
+			if err != nil {
+				return err
+			}
+			if row.Summary == entry.Summary && row.RecordedAt.Equal(entry.RecordedAt) {
+				return db.DeleteJournalEntry(ctx, row.ID)
+			}
+		}
+		return nil
+	})
+}
+
+// withTx runs fn in the bound transaction, or in a new one that is
+// committed if fn returns nil.
+func (j *journal) withTx(ctx context.Context, fn func(*database.Queries) error) error {
+	if j.tx != nil {
+		return fn(j.db)
+	}
+
+	tx, err := j.conn.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	if err := fn(j.db.WithTx(tx)); err != nil {
+		return err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit transaction: %w", err)
+	}
+
+	return nil
+}
+
+func move(ctx context.Context, db *database.Queries, row database.JournalEntry, undone bool, apply func(task.JournalEntry) error) (*task.JournalEntry, error) {
+	entry := task.JournalEntry{
+		Summary:    row.Summary,
+		RecordedAt: row.RecordedAt,
+	}
+	if err := json.Unmarshal([]byte(row.Changes), &entry.Changes); err != nil {
+		return nil, fmt.Errorf("decode journal entry: %w", err)
+	}
+
+	if err := apply(entry); err != nil {
+		return nil, err
+	}
+
+	params := database.SetJournalEntryUndoneParams{
+		ID:     row.ID,
+		Undone: undone,
+	}
+	if err := db.SetJournalEntryUndone(ctx, params); err != nil {
+		return nil, err
+	}
+
+	return &entry, nil
+}
@@ -0,0 +1,265 @@
+package sql_test
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"testing/fstest"
+
+	"github.com/google/uuid"
+	tasksql "github.com/ncfex/tasks/internal/storage/sql"
+	_ "modernc.org/sqlite"
+)
+
+var testMigrations = fstest.MapFS{
+	"schema/001_notes.sql": {Data: []byte(`CREATE TABLE IF NOT EXISTS notes (id INTEGER PRIMARY KEY);
+`)},
+	"schema/002_note_body.sql": {Data: []byte(`-- +goose Up
+ALTER TABLE notes ADD COLUMN body TEXT NOT NULL DEFAULT '';
+
+-- +goose Down
+ALTER TABLE notes DROP COLUMN body;
+`)},
+	"schema/003_tags.sql": {Data: []byte(`-- +goose Up
+CREATE TABLE tags (name TEXT PRIMARY KEY);
+
+-- +goose Down
+DROP TABLE tags;
+`)},
+}
+
+func openSQLite(t *testing.T) *sql.DB {
+	t.Helper()
+	db, err := sql.Open("sqlite", filepath.Join(t.TempDir(), "test.db"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { db.Close() })
+	return db
+}
+
+func newTestMigrator(t *testing.T, db *sql.DB) *tasksql.Migrator {
+	t.Helper()
+	migrator, err := tasksql.NewMigratorFromFS(db, tasksql.DialectSQLite, testMigrations, "schema")
+	if err != nil {
+		t.Fatal(err)
+	}
+	return migrator
+}
+
+func tableExists(t *testing.T, db *sql.DB, table string) bool {
+	t.Helper()
+	var n int
+	if err := db.QueryRow(`SELECT COUNT(*) FROM sqlite_master WHERE type = 'table' AND name = $1`, table).Scan(&n); err != nil {
+		t.Fatal(err)
+	}
+	return n > 0
+}
+
+func TestMigratorMigrateAndRollback(t *testing.T) {
+	ctx := context.Background()
+	db := openSQLite(t)
+	migrator := newTestMigrator(t, db)
+
+	applied, err := migrator.Migrate(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(applied) != 3 || applied[0].Version != 1 || applied[2].Version != 3 {
+		t.Fatalf("Migrate() applied %+v, want versions 1 to 3", applied)
+	}
+	if _, err := db.Exec(`INSERT INTO notes (id, body) VALUES (1, 'hello')`); err != nil {
+		t.Fatalf("schema after Migrate: %v", err)
+	}
+
+	if applied, err := migrator.Migrate(ctx); err != nil || len(applied) != 0 {
+		t.Errorf("second Migrate() = %d applied, %v, want nothing to do", len(applied), err)
+	}
+
+	rolledBack, err := migrator.Rollback(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if rolledBack.Version != 3 || tableExists(t, db, "tags") {
+		t.Errorf("Rollback() reverted %d, tags table left = %v, want 3 dropped", rolledBack.Version, tableExists(t, db, "tags"))
+	}
+
+	if _, err := migrator.Rollback(ctx); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := db.Exec(`INSERT INTO notes (id, body) VALUES (2, 'hello')`); err == nil {
+		t.Error("body column still exists after rolling back 002")
+	}
+
+	statuses, err := migrator.Status(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, status := range statuses {
+		if want := status.Version == 1; status.Applied != want {
+			t.Errorf("migration %d applied = %v, want %v", status.Version, status.Applied, want)
+		}
+	}
+
+	if _, err := migrator.Rollback(ctx); !errors.Is(err, tasksql.ErrIrreversibleMigration) {
+		t.Errorf("Rollback() of 001 without a Down section error = %v, want ErrIrreversibleMigration", err)
+	}
+	if applied, err := migrator.Migrate(ctx); err != nil || len(applied) != 2 {
+		t.Errorf("Migrate() after rollback = %d applied, %v, want 2", len(applied), err)
+	}
+}
+
+func TestMigratorRollbackWithNothingApplied(t *testing.T) {
+	migrator := newTestMigrator(t, openSQLite(t))
+	if _, err := migrator.Rollback(context.Background()); !errors.Is(err, tasksql.ErrNoMigrationsApplied) {
+		t.Errorf("Rollback() error = %v, want ErrNoMigrationsApplied", err)
+	}
+}
+
+func TestMigratorFailedMigrationIsNotRecorded(t *testing.T) {
+	ctx := context.Background()
+	db := openSQLite(t)
+
+	fsys := fstest.MapFS{
+		"schema/001_notes.sql": testMigrations["schema/001_notes.sql"],
+		"schema/002_broken.sql": {Data: []byte(`-- +goose Up
+CREATE TABLE broken (id INTEGER PRIMARY KEY);
+ALTER TABLE missing ADD COLUMN body TEXT;
+`)},
+	}
+	migrator, err := tasksql.NewMigratorFromFS(db, tasksql.DialectSQLite, fsys, "schema")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := migrator.Migrate(ctx); err == nil || !strings.Contains(err.Error(), "002_broken") {
+		t.Fatalf("Migrate() error = %v, want one naming 002_broken", err)
+	}
+	if tableExists(t, db, "broken") {
+		t.Error("failed migration left part of its changes")
+	}
+
+	statuses, err := migrator.Status(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !statuses[0].Applied || statuses[1].Applied {
+		t.Errorf("statuses = %+v, want only 001 applied", statuses)
+	}
+}
+
+// A database whose tables predate schema_migrations is baselined at
+// version 1 instead of having 001 applied again.
+func TestMigratorBaselinesUntrackedDatabase(t *testing.T) {
+	ctx := context.Background()
+	db := openSQLite(t)
+	if _, err := db.Exec(`CREATE TABLE tasks (id TEXT PRIMARY KEY); CREATE TABLE notes (id INTEGER PRIMARY KEY)`); err != nil {
+		t.Fatal(err)
+	}
+
+	applied, err := newTestMigrator(t, db).Migrate(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(applied) != 2 || applied[0].Version != 2 {
+		t.Errorf("Migrate() applied %+v, want 002 and 003 only", applied)
+	}
+}
+
+func TestMigratorRejectsBadFileNames(t *testing.T) {
+	for _, name := range []string{"schema/tasks.sql", "schema/one_tasks.sql"} {
+		fsys := fstest.MapFS{name: {Data: []byte("-- +goose Up\n")}}
+		if _, err := tasksql.NewMigratorFromFS(nil, tasksql.DialectSQLite, fsys, "schema"); err == nil {
+			t.Errorf("NewMigratorFromFS() accepted %s", name)
+		}
+	}
+}
+
+// Concurrent migrators on Postgres wait for the advisory lock, so every
+// migration is applied exactly once.
+func TestMigratorLocksOnPostgres(t *testing.T) {
+	dbURL := os.Getenv("TEST_DB_URL")
+	if dbURL == "" {
+		t.Skip("TEST_DB_URL is not set")
+	}
+	ctx := context.Background()
+
+	schema := "migratetest_" + strings.ReplaceAll(uuid.NewString(), "-", "")
+	admin, err := tasksql.Connect(dbURL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { admin.Close() })
+	if _, err := admin.Exec("CREATE SCHEMA " + schema); err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { admin.Exec("DROP SCHEMA " + schema + " CASCADE") })
+
+	schemaURL, err := withSearchPath(dbURL, schema)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	const workers = 4
+	var wg sync.WaitGroup
+	var mu sync.Mutex
+	total := 0
+	for i := 0; i < workers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			db, err := tasksql.Connect(schemaURL)
+			if err != nil {
+				t.Error(err)
+				return
+			}
+			defer db.Close()
+
+			migrator, err := tasksql.NewMigrator(db)
+			if err != nil {
+				t.Error(err)
+				return
+			}
+			applied, err := migrator.Migrate(ctx)
+			if err != nil {
+				t.Errorf("Migrate(): %v", err)
+				return
+			}
+			mu.Lock()
+			total += len(applied)
+			mu.Unlock()
+		}()
+	}
+	wg.Wait()
+
+	db, err := tasksql.Connect(schemaURL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+	migrator, err := tasksql.NewMigrator(db)
+	if err != nil {
+		t.Fatal(err)
+	}
+	statuses, err := migrator.Status(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if total != len(statuses) {
+		t.Errorf("migrators applied %d migrations in total, want each of the %d once", total, len(statuses))
+	}
+
+	for range statuses {
+		if _, err := migrator.Rollback(ctx); err != nil {
+			t.Fatalf("Rollback(): %v", err)
+		}
+	}
+	if applied, err := migrator.Migrate(ctx); err != nil || len(applied) != len(statuses) {
+		t.Errorf("Migrate() after full rollback = %d applied, %v, want %d", len(applied), err, len(statuses))
+	}
+}
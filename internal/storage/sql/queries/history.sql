@@ -0,0 +1,9 @@
+-- name: InsertTaskHistory :exec
+INSERT INTO task_history (task_id, action, username, diffs, recorded_at)
+VALUES ($1, $2, $3, $4, $5);
+
+-- name: GetTaskHistory :many
+SELECT *
+FROM task_history
+WHERE task_id = $1
+ORDER BY recorded_at, id;
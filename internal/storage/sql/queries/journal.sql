@@ -0,0 +1,56 @@
+-- name: AppendJournalEntry :exec
+INSERT INTO journal_entries (username, summary, changes, recorded_at)
+VALUES ($1, $2, $3, $4);
+
+-- name: DeleteUndoneJournalEntries :exec
+DELETE FROM journal_entries
+WHERE username = $1 AND undone = TRUE;
+
+-- name: TrimJournalEntries :exec
+DELETE FROM journal_entries
+WHERE username = $1 AND id NOT IN (
+    SELECT id
+    FROM journal_entries
+    WHERE username = $1
+    ORDER BY id DESC
+    LIMIT $2
+);
+
+-- name: GetNextUndoJournalEntry :one
+SELECT *
+FROM journal_entries
+WHERE username = $1 AND undone = FALSE
+ORDER BY id DESC
+LIMIT 1;
+
+-- name: GetNextUndoJournalEntryForUpdate :one
+SELECT *
+FROM journal_entries
+WHERE username = $1 AND undone = FALSE
+ORDER BY id DESC
+LIMIT 1
+FOR UPDATE;
+
+-- name: GetNextRedoJournalEntry :one
+SELECT *
+FROM journal_entries
+WHERE username = $1 AND undone = TRUE
+ORDER BY id ASC
+LIMIT 1;
+
+-- name: GetNextRedoJournalEntryForUpdate :one
+SELECT *
+FROM journal_entries
+WHERE username = $1 AND undone = TRUE
+ORDER BY id ASC
+LIMIT 1
+FOR UPDATE;
+
+-- name: SetJournalEntryUndone :exec
+UPDATE journal_entries
+SET undone = $2
+WHERE id = $1;
+
+-- name: DeleteJournalEntry :exec
+DELETE FROM journal_entries
+WHERE id = $1;
@@ -1,13 +1,6 @@
--- name: CreateTask :one
-INSERT INTO tasks (id, description, is_completed, created_at, due_date)
-VALUES (
-    gen_random_uuid(),
-    $1,
-    FALSE,
-    NOW(),
-    $2
-)
-RETURNING *;
+-- name: CreateTask :exec
+INSERT INTO tasks (id, description, status, created_at, due_date, estimate_seconds, completed_at, deleted_at, num, version, updated_at, priority, project, tags, annotations)
+VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14, $15);
 
 -- name: GetAllTasks :many
 SELECT * 
@@ -16,30 +9,53 @@ FROM tasks;
 -- name: GetAllCompletedTasks :many
 SELECT *
 FROM tasks
-WHERE is_completed::boolean = TRUE;
+WHERE status = 'done';
 
 -- name: GetAllDueTasks :many
 SELECT *
 FROM tasks
-WHERE is_completed::boolean = FALSE;
+WHERE status NOT IN ('done', 'cancelled');
 
 -- name: GetTaskById :one
 SELECT *
 FROM tasks
 WHERE id = $1;
 
--- name: GetTaskByPartialId :one
+-- name: GetTaskByNum :one
+SELECT *
+FROM tasks
+WHERE num = $1;
+
+-- name: GetTasksByPartialId :many
 SELECT *
 FROM tasks
-WHERE id::text LIKE $1 || '%'
-LIMIT 1;
+WHERE substr(CAST(id AS TEXT), 1, length(CAST($1 AS TEXT))) = CAST($1 AS TEXT)
+ORDER BY id;
 
--- name: CompleteTask :one
+-- name: UpdateTask :one
 UPDATE tasks
-SET is_completed = TRUE
-WHERE id = $1
+SET description = $2,
+    status = $3,
+    due_date = $4,
+    estimate_seconds = $5,
+    completed_at = $6,
+    deleted_at = $7,
+    updated_at = $9,
+    priority = $10,
+    project = $11,
+    tags = $12,
+    annotations = $13,
+    version = version + 1
+WHERE id = $1 AND version = $8
 RETURNING *;
 
--- name: DeleteTask :exec
+-- name: DeleteTask :execrows
 DELETE FROM tasks
-WHERE id = $1;
\ No newline at end of file
+WHERE id = $1;
+
+-- name: ListTasksAfter :many
+SELECT *
+FROM tasks
+WHERE num > $1 OR (num = $1 AND id > $2)
+ORDER BY num, id
+LIMIT $3;
@@ -3,114 +3,359 @@ package sql
 import (
 	"context"
 	"database/sql"
+	"errors"
 	"fmt"
+	"strings"
+	"time"
 
 	"github.com/google/uuid"
-	_ "github.com/lib/pq"
+	"github.com/lib/pq"
 	"github.com/ncfex/tasks/internal/storage/sql/database"
 	"github.com/ncfex/tasks/internal/task"
 )
 
 type repository struct {
-	db *database.Queries
+	conn *sql.DB
+	db   *database.Queries
+
+	// tx is set on the repository handed to a WithTx callback.
+	tx *sql.Tx
 }
 
-func NewRepository(dbURL string) (task.Repository, error) {
+func Connect(dbURL string) (*sql.DB, error) {
 	db, err := sql.Open("postgres", dbURL)
 	if err != nil {
 		return nil, fmt.Errorf("failed to open database connection: %w", err)
 	}
 
-	queries := database.New(db)
-	return &repository{db: queries}, nil
+	return db, nil
+}
+
+// NewRepository connects to Postgres and applies any pending migrations.
+func NewRepository(ctx context.Context, dbURL string) (task.Repository, error) {
+	db, err := Connect(dbURL)
+	if err != nil {
+		return nil, err
+	}
+
+	migrator, err := NewMigrator(db)
+	if err != nil {
+		return nil, err
+	}
+
+	if _, err := migrator.Migrate(ctx); err != nil {
+		return nil, err
+	}
+
+	return NewRepositoryWithDB(db), nil
+}
+
+func NewRepositoryWithDB(db *sql.DB) task.Repository {
+	return &repository{conn: db, db: database.New(db)}
 }
 
 func (r *repository) toSQLTask(t *task.Task) database.Task {
 	return database.Task{
-		ID:          uuid.New(),
-		Description: t.Description,
-		IsCompleted: t.IsCompleted,
-		CreatedAt:   t.CreatedAt,
-		DueDate:     t.DueDate,
+		ID:              t.ID,
+		Description:     t.Description,
+		Status:          string(t.Status),
+		CreatedAt:       t.CreatedAt,
+		UpdatedAt:       t.UpdatedAt,
+		DueDate:         t.DueDate,
+		EstimateSeconds: int64(t.Estimate / time.Second),
+		CompletedAt:     toNullTime(t.CompletedAt),
+		DeletedAt:       toNullTime(t.DeletedAt),
+		Num:             int64(t.Num),
+		Version:         int64(t.Version),
+		Priority:        t.Priority,
+		Project:         t.Project,
+		Tags:            task.FormatTags(t.Tags),
+		Annotations:     task.FormatAnnotations(t.Annotations),
 	}
 }
 
-func (r *repository) toDomainTask(t database.Task) task.Task {
+func (r *repository) toDomainTask(t database.Task) (task.Task, error) {
+	annotations, err := task.ParseAnnotations(t.Annotations)
+	if err != nil {
+		return task.Task{}, fmt.Errorf("task %s: %w", t.ID, err)
+	}
+
 	return task.Task{
 		ID:          t.ID,
+		Num:         int(t.Num),
 		Description: t.Description,
-		IsCompleted: t.IsCompleted,
+		Status:      task.Status(t.Status),
 		CreatedAt:   t.CreatedAt,
+		UpdatedAt:   t.UpdatedAt,
 		DueDate:     t.DueDate,
-	}
+		Estimate:    time.Duration(t.EstimateSeconds) * time.Second,
+		CompletedAt: t.CompletedAt.Time,
+		DeletedAt:   t.DeletedAt.Time,
+		Version:     int(t.Version),
+		Priority:    t.Priority,
+		Project:     t.Project,
+		Tags:        task.ParseTags(t.Tags),
+		Annotations: annotations,
+	}, nil
 }
 
-func (r *repository) Save(t *task.Task) error {
+func (r *repository) Save(ctx context.Context, t *task.Task) error {
+	if t.ID == uuid.Nil {
+		t.ID = uuid.New()
+	}
+
 	sqlTask := r.toSQLTask(t)
 	params := database.CreateTaskParams{
-		Description: sqlTask.Description,
-		DueDate:     sqlTask.DueDate,
+		ID:              sqlTask.ID,
+		Description:     sqlTask.Description,
+		Status:          sqlTask.Status,
+		CreatedAt:       sqlTask.CreatedAt,
+		DueDate:         sqlTask.DueDate,
+		EstimateSeconds: sqlTask.EstimateSeconds,
+		CompletedAt:     sqlTask.CompletedAt,
+		DeletedAt:       sqlTask.DeletedAt,
+		Num:             sql.NullInt64{Int64: sqlTask.Num, Valid: t.Num > 0},
+		Version:         sqlTask.Version,
+		UpdatedAt:       sqlTask.UpdatedAt,
+		Priority:        sqlTask.Priority,
+		Project:         sqlTask.Project,
+		Tags:            sqlTask.Tags,
+		Annotations:     sqlTask.Annotations,
+	}
+
+	if err := r.db.CreateTask(ctx, params); err != nil {
+		if isUniqueViolation(err) {
+			return &task.ConflictError{ID: t.ID, Reason: "a task with this ID already exists"}
+		}
+		return err
+	}
+
+	// The number is assigned by the database, so read it back.
+	saved, err := r.db.GetTaskById(ctx, t.ID)
+	if err != nil {
+		return err
 	}
+	t.Num = int(saved.Num)
 
-	rT, err := r.db.CreateTask(context.Background(), params)
-	t.ID = rT.ID
-	return err
+	return nil
 }
 
-func (r *repository) GetByID(uuid uuid.UUID) (*task.Task, error) {
-	sqlTask, err := r.db.GetTaskById(context.Background(), uuid)
+func (r *repository) GetByID(ctx context.Context, uuid uuid.UUID) (*task.Task, error) {
+	sqlTask, err := r.db.GetTaskById(ctx, uuid)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, &task.NotFoundError{ID: uuid.String()}
+	}
 	if err != nil {
 		return nil, err
 	}
 
-	domainTask := r.toDomainTask(sqlTask)
+	domainTask, err := r.toDomainTask(sqlTask)
+	if err != nil {
+		return nil, err
+	}
 	return &domainTask, nil
 }
 
-func (r *repository) GetTaskByPartialId(uuid string) (*task.Task, error) {
-	nullUUID := sql.NullString{
-		String: uuid,
-		Valid:  true,
+func (r *repository) GetByNum(ctx context.Context, num int) (*task.Task, error) {
+	sqlTask, err := r.db.GetTaskByNum(ctx, int64(num))
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, &task.NotFoundError{ID: task.FormatNum(num)}
 	}
-
-	sqlTask, err := r.db.GetTaskByPartialId(context.Background(), nullUUID)
 	if err != nil {
 		return nil, err
 	}
 
-	domainTask := r.toDomainTask(sqlTask)
+	domainTask, err := r.toDomainTask(sqlTask)
+	if err != nil {
+		return nil, err
+	}
 	return &domainTask, nil
 }
 
-func (r *repository) List(selector *task.TaskSelector, filter *task.TaskFilter) ([]task.Task, error) {
+func (r *repository) GetTaskByPartialId(ctx context.Context, uuid string) (*task.Task, error) {
+	sqlTasks, err := r.db.GetTasksByPartialId(ctx, uuid)
+	if err != nil {
+		return nil, err
+	}
+
+	switch len(sqlTasks) {
+	case 0:
+		return nil, &task.NotFoundError{ID: uuid}
+	case 1:
+		domainTask, err := r.toDomainTask(sqlTasks[0])
+		if err != nil {
+			return nil, err
+		}
+		return &domainTask, nil
+	}
+
+	candidates := make([]task.Task, len(sqlTasks))
+	for i, sqlTask := range sqlTasks {
+		if candidates[i], err = r.toDomainTask(sqlTask); err != nil {
+			return nil, err
+		}
+	}
+	return nil, &task.AmbiguousIDError{Prefix: uuid, Candidates: candidates}
+}
+
+func (r *repository) List(ctx context.Context, selector *task.TaskSelector, filter *task.TaskFilter) ([]task.Task, error) {
 	var sqlTasks []database.Task
 	var err error
 
-	if filter.IncludeCompleted {
-		sqlTasks, err = r.db.GetAllTasks(context.Background())
+	if filter.IncludeCompleted || len(filter.Statuses) > 0 {
+		sqlTasks, err = r.db.GetAllTasks(ctx)
 	} else {
-		sqlTasks, err = r.db.GetAllDueTasks(context.Background())
+		sqlTasks, err = r.db.GetAllDueTasks(ctx)
 	}
 	if err != nil {
 		return nil, err
 	}
 
-	tasks := make([]task.Task, len(sqlTasks))
-	for i, sqlTask := range sqlTasks {
-		tasks[i] = r.toDomainTask(sqlTask)
+	tasks := make([]task.Task, 0, len(sqlTasks))
+	for _, sqlTask := range sqlTasks {
+		t, err := r.toDomainTask(sqlTask)
+		if err != nil {
+			return nil, err
+		}
+		if !filter.Matches(&t) {
+			continue
+		}
+		tasks = append(tasks, t)
 	}
 	return tasks, nil
 }
 
-func (r *repository) Update(t *task.Task) error {
-	_, err := r.db.CompleteTask(context.Background(), t.ID)
+// walkPageSize is how many tasks Walk reads per query.
+const walkPageSize = 500
+
+// Walk reads the tasks a page at a time, each page starting after the last
+// task number of the one before.
+func (r *repository) Walk(ctx context.Context, filter *task.TaskFilter, fn func(*task.Task) error) error {
+	var params database.ListTasksAfterParams
+	params.Limit = walkPageSize
+
+	for {
+		page, err := r.db.ListTasksAfter(ctx, params)
+		if err != nil {
+			return err
+		}
+
+		for _, sqlTask := range page {
+			t, err := r.toDomainTask(sqlTask)
+			if err != nil {
+				return err
+			}
+			if !filter.Matches(&t) {
+				continue
+			}
+			if err := fn(&t); err != nil {
+				return err
+			}
+		}
+
+		if len(page) < walkPageSize {
+			return nil
+		}
+		last := page[len(page)-1]
+		params.Num, params.ID = last.Num, last.ID
+	}
+}
+
+func (r *repository) updateParams(t *task.Task) database.UpdateTaskParams {
+	sqlTask := r.toSQLTask(t)
+	return database.UpdateTaskParams{
+		ID:              t.ID,
+		Description:     sqlTask.Description,
+		Status:          sqlTask.Status,
+		DueDate:         sqlTask.DueDate,
+		EstimateSeconds: sqlTask.EstimateSeconds,
+		CompletedAt:     sqlTask.CompletedAt,
+		DeletedAt:       sqlTask.DeletedAt,
+		Version:         sqlTask.Version,
+		UpdatedAt:       sqlTask.UpdatedAt,
+		Priority:        sqlTask.Priority,
+		Project:         sqlTask.Project,
+		Tags:            sqlTask.Tags,
+		Annotations:     sqlTask.Annotations,
+	}
+}
+
+// Update stores t if its version still matches the stored one, and bumps
+// the version of both.
+func (r *repository) Update(ctx context.Context, t *task.Task) error {
+	updated, err := r.db.UpdateTask(ctx, r.updateParams(t))
+	if errors.Is(err, sql.ErrNoRows) {
+		return r.updateFailed(ctx, t)
+	}
 	if err != nil {
 		return err
 	}
 
+	t.Version = int(updated.Version)
 	return nil
 }
 
-func (r *repository) Delete(t *task.Task) error {
-	return r.db.DeleteTask(context.Background(), t.ID)
+// updateFailed explains why an update matched no row: the task is either
+// gone or has been changed since it was read.
+func (r *repository) updateFailed(ctx context.Context, t *task.Task) error {
+	current, err := r.GetByID(ctx, t.ID)
+	if err != nil {
+		return err
+	}
+
+	return &task.VersionConflictError{Attempted: *t, Current: *current}
+}
+
+// WithTx runs fn in a database transaction, which is committed if fn
+// returns nil and rolled back otherwise. Called inside fn, it joins the
+// transaction already in progress.
+func (r *repository) WithTx(ctx context.Context, fn func(task.Repository) error) error {
+	if r.tx != nil {
+		return fn(r)
+	}
+
+	tx, err := r.conn.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	if err := fn(&repository{db: r.db.WithTx(tx), tx: tx}); err != nil {
+		return err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit transaction: %w", err)
+	}
+
+	return nil
+}
+
+func (r *repository) Delete(ctx context.Context, t *task.Task) error {
+	deleted, err := r.db.DeleteTask(ctx, t.ID)
+	if err != nil {
+		return err
+	}
+	if deleted == 0 {
+		return &task.NotFoundError{ID: t.ID.String()}
+	}
+
+	return nil
+}
+
+// isUniqueViolation reports whether err is a primary key or unique
+// constraint violation from Postgres or SQLite.
+func isUniqueViolation(err error) bool {
+	var pqErr *pq.Error
+	if errors.As(err, &pqErr) {
+		return pqErr.Code == "23505"
+	}
+	return strings.Contains(err.Error(), "UNIQUE constraint failed")
+}
+
+func toNullTime(t time.Time) sql.NullTime {
+	return sql.NullTime{
+		Time:  t,
+		Valid: !t.IsZero(),
+	}
 }
@@ -0,0 +1,71 @@
+package sql_test
+
+import (
+	"context"
+	"fmt"
+	"net/url"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/google/uuid"
+	tasksql "github.com/ncfex/tasks/internal/storage/sql"
+	"github.com/ncfex/tasks/internal/storage/storagetest"
+	"github.com/ncfex/tasks/internal/task"
+)
+
+// TestRepository runs against the Postgres server in TEST_DB_URL, for
+// example the one in docker/postgres.yaml. Each test gets its own schema.
+func TestRepository(t *testing.T) {
+	dbURL := os.Getenv("TEST_DB_URL")
+	if dbURL == "" {
+		t.Skip("TEST_DB_URL is not set")
+	}
+
+	storagetest.Run(t, func(t *testing.T) storagetest.Opener {
+		schema := "storagetest_" + strings.ReplaceAll(uuid.NewString(), "-", "")
+
+		admin, err := tasksql.Connect(dbURL)
+		if err != nil {
+			t.Fatal(err)
+		}
+		t.Cleanup(func() { admin.Close() })
+		if _, err := admin.Exec("CREATE SCHEMA " + schema); err != nil {
+			t.Fatal(err)
+		}
+		t.Cleanup(func() { admin.Exec("DROP SCHEMA " + schema + " CASCADE") })
+
+		schemaURL, err := withSearchPath(dbURL, schema)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		return func(t *testing.T) task.Repository {
+			db, err := tasksql.Connect(schemaURL)
+			if err != nil {
+				t.Fatal(err)
+			}
+			t.Cleanup(func() { db.Close() })
+
+			migrator, err := tasksql.NewMigrator(db)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if _, err := migrator.Migrate(context.Background()); err != nil {
+				t.Fatal(err)
+			}
+			return tasksql.NewRepositoryWithDB(db)
+		}
+	})
+}
+
+func withSearchPath(dbURL, schema string) (string, error) {
+	u, err := url.Parse(dbURL)
+	if err != nil {
+		return "", fmt.Errorf("invalid TEST_DB_URL: %w", err)
+	}
+	query := u.Query()
+	query.Set("search_path", schema)
+	u.RawQuery = query.Encode()
+	return u.String(), nil
+}
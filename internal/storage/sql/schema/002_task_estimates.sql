@@ -0,0 +1,9 @@
+-- +goose Up
+ALTER TABLE tasks
+    ADD COLUMN estimate_seconds BIGINT NOT NULL DEFAULT 0,
+    ADD COLUMN completed_at TIMESTAMP;
+
+-- +goose Down
+ALTER TABLE tasks
+    DROP COLUMN completed_at,
+    DROP COLUMN estimate_seconds;
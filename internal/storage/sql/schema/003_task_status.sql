@@ -0,0 +1,15 @@
+-- +goose Up
+ALTER TABLE tasks
+    ADD COLUMN status TEXT NOT NULL DEFAULT 'todo'
+    CHECK (status IN ('todo', 'in_progress', 'waiting', 'done', 'cancelled'));
+
+UPDATE tasks SET status = 'done' WHERE is_completed;
+
+ALTER TABLE tasks DROP COLUMN is_completed;
+
+-- +goose Down
+ALTER TABLE tasks ADD COLUMN is_completed BOOLEAN NOT NULL DEFAULT FALSE;
+
+UPDATE tasks SET is_completed = (status = 'done');
+
+ALTER TABLE tasks DROP COLUMN status;
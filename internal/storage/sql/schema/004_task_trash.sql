@@ -0,0 +1,5 @@
+-- +goose Up
+ALTER TABLE tasks ADD COLUMN deleted_at TIMESTAMP;
+
+-- +goose Down
+ALTER TABLE tasks DROP COLUMN deleted_at;
@@ -0,0 +1,11 @@
+-- +goose Up
+CREATE TABLE journal_entries (
+    id BIGSERIAL PRIMARY KEY,
+    summary TEXT NOT NULL,
+    changes TEXT NOT NULL,
+    recorded_at TIMESTAMP NOT NULL,
+    undone BOOLEAN NOT NULL DEFAULT FALSE
+);
+
+-- +goose Down
+DROP TABLE journal_entries;
@@ -0,0 +1,14 @@
+-- +goose Up
+CREATE TABLE task_history (
+    id BIGSERIAL PRIMARY KEY,
+    task_id UUID NOT NULL,
+    action TEXT NOT NULL,
+    username TEXT NOT NULL,
+    diffs TEXT NOT NULL,
+    recorded_at TIMESTAMP NOT NULL
+);
+
+CREATE INDEX task_history_task_id_idx ON task_history (task_id);
+
+-- +goose Down
+DROP TABLE task_history;
@@ -0,0 +1,44 @@
+-- +goose Up
+CREATE SEQUENCE tasks_num_seq;
+
+ALTER TABLE tasks ADD COLUMN num BIGINT;
+
+UPDATE tasks
+SET num = numbered.num
+FROM (SELECT id, ROW_NUMBER() OVER (ORDER BY created_at, id) AS num FROM tasks) AS numbered
+WHERE tasks.id = numbered.id;
+
+SELECT setval('tasks_num_seq', COALESCE(MAX(num), 0) + 1, false) FROM tasks;
+
+ALTER TABLE tasks
+    ALTER COLUMN num SET NOT NULL,
+    ALTER COLUMN num SET DEFAULT nextval('tasks_num_seq');
+
+ALTER SEQUENCE tasks_num_seq OWNED BY tasks.num;
+
+CREATE UNIQUE INDEX tasks_num_idx ON tasks (num);
+
+-- Tasks copied from another store keep their number unless it is taken.
+CREATE FUNCTION tasks_assign_num() RETURNS trigger AS $$
+BEGIN
+    IF NEW.num IS NULL OR EXISTS (SELECT 1 FROM tasks WHERE num = NEW.num) THEN
+        NEW.num := nextval('tasks_num_seq');
+    ELSIF NEW.num >= (SELECT last_value FROM tasks_num_seq) THEN
+        PERFORM setval('tasks_num_seq', NEW.num);
+    END IF;
+    RETURN NEW;
+END;
+$$ LANGUAGE plpgsql;
+
+CREATE TRIGGER tasks_assign_num
+    BEFORE INSERT ON tasks
+    FOR EACH ROW EXECUTE FUNCTION tasks_assign_num();
+
+-- +goose Down
+DROP TRIGGER tasks_assign_num ON tasks;
+
+DROP FUNCTION tasks_assign_num();
+
+DROP INDEX tasks_num_idx;
+
+ALTER TABLE tasks DROP COLUMN num;
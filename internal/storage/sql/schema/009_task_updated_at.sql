@@ -0,0 +1,10 @@
+-- +goose Up
+ALTER TABLE tasks ADD COLUMN updated_at TIMESTAMP;
+
+UPDATE tasks
+SET updated_at = GREATEST(created_at, completed_at, deleted_at);
+
+ALTER TABLE tasks ALTER COLUMN updated_at SET NOT NULL;
+
+-- +goose Down
+ALTER TABLE tasks DROP COLUMN updated_at;
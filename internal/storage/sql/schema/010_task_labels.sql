@@ -0,0 +1,9 @@
+-- +goose Up
+ALTER TABLE tasks ADD COLUMN priority TEXT NOT NULL DEFAULT '';
+ALTER TABLE tasks ADD COLUMN project TEXT NOT NULL DEFAULT '';
+ALTER TABLE tasks ADD COLUMN tags TEXT NOT NULL DEFAULT '';
+
+-- +goose Down
+ALTER TABLE tasks DROP COLUMN tags;
+ALTER TABLE tasks DROP COLUMN project;
+ALTER TABLE tasks DROP COLUMN priority;
@@ -0,0 +1,95 @@
+package sqlite_test
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	tasksql "github.com/ncfex/tasks/internal/storage/sql"
+	"github.com/ncfex/tasks/internal/storage/sqlite"
+	"github.com/ncfex/tasks/internal/task"
+)
+
+func openDB(t *testing.T) *sql.DB {
+	t.Helper()
+	db, err := sqlite.Connect(filepath.Join(t.TempDir(), "tasks.db"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { db.Close() })
+
+	migrator, err := sqlite.NewMigrator(db)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := migrator.Migrate(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	return db
+}
+
+func newService(db *sql.DB, user string, auditLog task.AuditLog) task.TaskService {
+	return task.NewService(tasksql.NewRepositoryWithDB(db),
+		task.WithJournal(tasksql.NewJournal(db, tasksql.DialectSQLite, user)),
+		task.WithAuditLog(auditLog, user),
+	)
+}
+
+func TestJournalIsPerUser(t *testing.T) {
+	ctx := context.Background()
+	db := openDB(t)
+	alice := newService(db, "alice", tasksql.NewAuditLog(db))
+	bob := newService(db, "bob", tasksql.NewAuditLog(db))
+
+	created, err := alice.Create(ctx, "water plants", time.Time{}, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := bob.Undo(ctx); !errors.Is(err, task.ErrNothingToUndo) {
+		t.Fatalf("bob.Undo() error = %v, want ErrNothingToUndo", err)
+	}
+	if _, err := alice.Undo(ctx); err != nil {
+		t.Fatalf("alice.Undo(): %v", err)
+	}
+	if _, err := alice.GetByID(ctx, created.ID); !errors.Is(err, task.ErrTaskNotFound) {
+		t.Errorf("GetByID after undo error = %v, want ErrTaskNotFound", err)
+	}
+}
+
+type failingAuditLog struct{}
+
+func (failingAuditLog) Record(ctx context.Context, event task.AuditEvent) error {
+	return errors.New("disk full")
+}
+
+func (failingAuditLog) History(ctx context.Context, taskID uuid.UUID) ([]task.AuditEvent, error) {
+	return nil, nil
+}
+
+// A change whose records cannot be written is rolled back together with its
+// journal entry.
+func TestJournalCommitsWithChange(t *testing.T) {
+	ctx := context.Background()
+	db := openDB(t)
+
+	if _, err := newService(db, "alice", failingAuditLog{}).Create(ctx, "water plants", time.Time{}, 0); err == nil {
+		t.Fatal("Create() succeeded with a failing audit log")
+	}
+
+	service := newService(db, "alice", tasksql.NewAuditLog(db))
+	tasks, err := service.List(ctx, nil, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(tasks) != 0 {
+		t.Errorf("List() = %d tasks, want none", len(tasks))
+	}
+	if _, err := service.Undo(ctx); !errors.Is(err, task.ErrNothingToUndo) {
+		t.Errorf("Undo() error = %v, want ErrNothingToUndo", err)
+	}
+}
@@ -0,0 +1,48 @@
+package sqlite_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	tasksql "github.com/ncfex/tasks/internal/storage/sql"
+	"github.com/ncfex/tasks/internal/storage/sqlite"
+)
+
+// Every migration after the first rolls back cleanly and applies again on
+// top of what its Down section left. The first one predates migration
+// tracking and has no Down section.
+func TestSchemaRollsBack(t *testing.T) {
+	ctx := context.Background()
+	db := openDB(t)
+
+	migrator, err := sqlite.NewMigrator(db)
+	if err != nil {
+		t.Fatal(err)
+	}
+	statuses, err := migrator.Status(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for i := len(statuses) - 1; i > 0; i-- {
+		rolledBack, err := migrator.Rollback(ctx)
+		if err != nil {
+			t.Fatalf("Rollback(): %v", err)
+		}
+		if rolledBack.Version != statuses[i].Version {
+			t.Fatalf("Rollback() reverted %d, want %d", rolledBack.Version, statuses[i].Version)
+		}
+	}
+	if _, err := migrator.Rollback(ctx); !errors.Is(err, tasksql.ErrIrreversibleMigration) {
+		t.Fatalf("Rollback() of 001 error = %v, want ErrIrreversibleMigration", err)
+	}
+
+	applied, err := migrator.Migrate(ctx)
+	if err != nil {
+		t.Fatalf("Migrate() after rollback: %v", err)
+	}
+	if len(applied) != len(statuses)-1 {
+		t.Errorf("Migrate() applied %d migrations, want %d", len(applied), len(statuses)-1)
+	}
+}
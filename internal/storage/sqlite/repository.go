@@ -0,0 +1,59 @@
+package sqlite
+
+import (
+	"context"
+	"database/sql"
+	"embed"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	tasksql "github.com/ncfex/tasks/internal/storage/sql"
+	"github.com/ncfex/tasks/internal/task"
+	_ "modernc.org/sqlite"
+)
+
+//go:embed schema/*.sql
+var schemaFS embed.FS
+
+// Connect opens the SQLite database at path, creating the file when needed.
+// The returned handle works with the query layer of the sql package.
+func Connect(path string) (*sql.DB, error) {
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return nil, fmt.Errorf("failed to create directory: %w", err)
+	}
+
+	// Transactions take the write lock when they begin, so one that reads
+	// before writing cannot fail to upgrade its lock under contention.
+	dsn := fmt.Sprintf("file:%s?_pragma=busy_timeout(5000)&_pragma=journal_mode(WAL)&_txlock=immediate", path)
+	db, err := sql.Open("sqlite", dsn)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open database: %w", err)
+	}
+
+	return db, nil
+}
+
+// NewMigrator returns a migrator for the SQLite schema embedded in this
+// package.
+func NewMigrator(db *sql.DB) (*tasksql.Migrator, error) {
+	return tasksql.NewMigratorFromFS(db, tasksql.DialectSQLite, schemaFS, "schema")
+}
+
+func NewRepository(ctx context.Context, path string) (task.Repository, error) {
+	db, err := Connect(path)
+	if err != nil {
+		return nil, err
+	}
+
+	migrator, err := NewMigrator(db)
+	if err != nil {
+		return nil, err
+	}
+
+	if _, err := migrator.Migrate(ctx); err != nil {
+		return nil, err
+	}
+
+	return tasksql.NewRepositoryWithDB(db), nil
+}
@@ -0,0 +1,34 @@
+package sqlite_test
+
+import (
+	"context"
+	"path/filepath"
+	"testing"
+
+	tasksql "github.com/ncfex/tasks/internal/storage/sql"
+	"github.com/ncfex/tasks/internal/storage/sqlite"
+	"github.com/ncfex/tasks/internal/storage/storagetest"
+	"github.com/ncfex/tasks/internal/task"
+)
+
+func TestRepository(t *testing.T) {
+	storagetest.Run(t, func(t *testing.T) storagetest.Opener {
+		path := filepath.Join(t.TempDir(), "tasks.db")
+		return func(t *testing.T) task.Repository {
+			db, err := sqlite.Connect(path)
+			if err != nil {
+				t.Fatal(err)
+			}
+			t.Cleanup(func() { db.Close() })
+
+			migrator, err := sqlite.NewMigrator(db)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if _, err := migrator.Migrate(context.Background()); err != nil {
+				t.Fatal(err)
+			}
+			return tasksql.NewRepositoryWithDB(db)
+		}
+	})
+}
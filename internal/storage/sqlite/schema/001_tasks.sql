@@ -0,0 +1,30 @@
+CREATE TABLE IF NOT EXISTS tasks (
+    id TEXT PRIMARY KEY,
+    description TEXT NOT NULL,
+    created_at TIMESTAMP NOT NULL,
+    due_date TIMESTAMP NOT NULL,
+    estimate_seconds INTEGER NOT NULL DEFAULT 0,
+    completed_at TIMESTAMP,
+    status TEXT NOT NULL DEFAULT 'todo'
+        CHECK (status IN ('todo', 'in_progress', 'waiting', 'done', 'cancelled')),
+    deleted_at TIMESTAMP
+);
+
+CREATE TABLE IF NOT EXISTS journal_entries (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    summary TEXT NOT NULL,
+    changes TEXT NOT NULL,
+    recorded_at TIMESTAMP NOT NULL,
+    undone BOOLEAN NOT NULL DEFAULT FALSE
+);
+
+CREATE TABLE IF NOT EXISTS task_history (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    task_id TEXT NOT NULL,
+    action TEXT NOT NULL,
+    username TEXT NOT NULL,
+    diffs TEXT NOT NULL,
+    recorded_at TIMESTAMP NOT NULL
+);
+
+CREATE INDEX IF NOT EXISTS task_history_task_id_idx ON task_history (task_id);
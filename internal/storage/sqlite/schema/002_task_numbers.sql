@@ -0,0 +1,44 @@
+-- +goose Up
+CREATE TABLE task_sequences (
+    name TEXT PRIMARY KEY,
+    value INTEGER NOT NULL
+);
+
+ALTER TABLE tasks ADD COLUMN num INTEGER;
+
+UPDATE tasks
+SET num = numbered.num
+FROM (SELECT id, ROW_NUMBER() OVER (ORDER BY created_at, id) AS num FROM tasks) AS numbered
+WHERE tasks.id = numbered.id;
+
+INSERT INTO task_sequences (name, value)
+SELECT 'tasks_num', COALESCE(MAX(num), 0) FROM tasks;
+
+CREATE INDEX tasks_num_idx ON tasks (num);
+
+-- SQLite has no sequences, so new tasks are numbered after the insert from
+-- the task_sequences counter. Tasks copied from another store keep their
+-- number unless it is taken, which is why the index above is not unique.
+CREATE TRIGGER tasks_assign_num AFTER INSERT ON tasks
+WHEN NEW.num IS NULL OR EXISTS (SELECT 1 FROM tasks WHERE num = NEW.num AND id <> NEW.id)
+BEGIN
+    UPDATE task_sequences SET value = value + 1 WHERE name = 'tasks_num';
+    UPDATE tasks SET num = (SELECT value FROM task_sequences WHERE name = 'tasks_num') WHERE id = NEW.id;
+END;
+
+CREATE TRIGGER tasks_track_num AFTER INSERT ON tasks
+WHEN NEW.num > (SELECT value FROM task_sequences WHERE name = 'tasks_num')
+BEGIN
+    UPDATE task_sequences SET value = NEW.num WHERE name = 'tasks_num';
+END;
+
+-- +goose Down
+DROP TRIGGER tasks_track_num;
+
+DROP TRIGGER tasks_assign_num;
+
+DROP INDEX tasks_num_idx;
+
+ALTER TABLE tasks DROP COLUMN num;
+
+DROP TABLE task_sequences;
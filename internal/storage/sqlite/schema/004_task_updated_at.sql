@@ -0,0 +1,8 @@
+-- +goose Up
+ALTER TABLE tasks ADD COLUMN updated_at TIMESTAMP;
+
+UPDATE tasks
+SET updated_at = MAX(created_at, COALESCE(completed_at, created_at), COALESCE(deleted_at, created_at));
+
+-- +goose Down
+ALTER TABLE tasks DROP COLUMN updated_at;
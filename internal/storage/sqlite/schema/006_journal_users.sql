@@ -0,0 +1,7 @@
+-- +goose Up
+ALTER TABLE journal_entries ADD COLUMN username TEXT NOT NULL DEFAULT '';
+CREATE INDEX journal_entries_username_id_idx ON journal_entries (username, id);
+
+-- +goose Down
+DROP INDEX journal_entries_username_id_idx;
+ALTER TABLE journal_entries DROP COLUMN username;
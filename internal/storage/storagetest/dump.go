@@ -0,0 +1,109 @@
+package storagetest
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/ncfex/tasks/internal/task"
+)
+
+// Encoder writes the tasks of a dump one at a time.
+type Encoder interface {
+	Encode(t *task.Task) error
+	Close() error
+}
+
+// Decoder reads the tasks of a dump one at a time, returning io.EOF after
+// the last one.
+type Decoder interface {
+	Decode() (*task.Task, error)
+}
+
+// Dump is the dump format of a file backend: NewEncoder writes tasks in the
+// layout of its tasks file and NewDecoder reads them back. Open returns a
+// repository on the tasks file at path.
+type Dump struct {
+	File       string
+	NewEncoder func(w io.Writer) Encoder
+	NewDecoder func(r io.Reader) Decoder
+	Open       func(path string) task.Repository
+}
+
+// RunDump checks that a dump decodes to the tasks it was written from, and
+// that it can be used as the tasks file of the backend.
+func RunDump(t *testing.T, dump Dump) {
+	now := time.Now().UTC().Truncate(time.Second)
+	done := newTask("done")
+	done.Num = 2
+	done.Status = task.StatusDone
+	done.CompletedAt = now
+	trashed := newTask("trashed")
+	trashed.Num = 5
+	trashed.DeletedAt = now
+	full := newTask("every field")
+	full.Num = 1
+	full.Priority = "A"
+	full.Project = "home"
+	full.Tags = []string{"bills", "monthly"}
+	full.Estimate = 90 * time.Second
+	full.Annotations = []task.Annotation{{CreatedAt: now, Text: "with a, comma and \"quotes\""}}
+
+	tests := []struct {
+		name    string
+		tasks   []task.Task
+		nextNum int
+	}{
+		{"empty", nil, 1},
+		{"tasks", []task.Task{full, done, trashed}, 6},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx := context.Background()
+			var buf bytes.Buffer
+			enc := dump.NewEncoder(&buf)
+			for i := range tt.tasks {
+				if err := enc.Encode(&tt.tasks[i]); err != nil {
+					t.Fatalf("Encode(%s): %v", tt.tasks[i].Description, err)
+				}
+			}
+			if err := enc.Close(); err != nil {
+				t.Fatalf("Close: %v", err)
+			}
+
+			dec := dump.NewDecoder(bytes.NewReader(buf.Bytes()))
+			for i := range tt.tasks {
+				got, err := dec.Decode()
+				if err != nil {
+					t.Fatalf("Decode(%s): %v", tt.tasks[i].Description, err)
+				}
+				assertSameTask(t, got, &tt.tasks[i])
+			}
+			if _, err := dec.Decode(); !errors.Is(err, io.EOF) {
+				t.Errorf("Decode after the last task = %v, want io.EOF", err)
+			}
+
+			path := filepath.Join(t.TempDir(), dump.File)
+			if err := os.WriteFile(path, buf.Bytes(), 0644); err != nil {
+				t.Fatal(err)
+			}
+			repo := dump.Open(path)
+			if got := listAll(t, repo); len(got) != len(tt.tasks) {
+				t.Errorf("the dump opens with %d tasks, want %d", len(got), len(tt.tasks))
+			}
+			added := newTask("added")
+			if err := repo.Save(ctx, &added); err != nil {
+				t.Fatalf("Save: %v", err)
+			}
+			if added.Num != tt.nextNum {
+				t.Errorf("a task added to the dump got #%d, want #%d", added.Num, tt.nextNum)
+			}
+		})
+	}
+}
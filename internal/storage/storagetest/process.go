@@ -0,0 +1,89 @@
+package storagetest
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"regexp"
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/ncfex/tasks/internal/task"
+)
+
+// processEnv tells a re-run of the test binary that it is one of the
+// processes of RunProcesses, and which file to write to.
+const processEnv = "TASKS_STORAGETEST_FILE"
+
+const (
+	processCount    = 4
+	tasksPerProcess = 25
+)
+
+// RunProcesses checks that several processes adding tasks to the same file
+// at once lose none of them. It runs the test binary again as each process,
+// so the calling test must not do anything else. open returns a repository
+// on the file at path.
+func RunProcesses(t *testing.T, file string, open func(path string) task.Repository) {
+	if path := os.Getenv(processEnv); path != "" {
+		addFromProcess(t, open(path))
+		return
+	}
+
+	path := filepath.Join(t.TempDir(), file)
+	cmds := make([]*exec.Cmd, processCount)
+	outputs := make([][]byte, processCount)
+	for i := range cmds {
+		cmd := exec.Command(os.Args[0], "-test.run=^"+regexp.QuoteMeta(t.Name())+"$", "-test.count=1")
+		cmd.Env = append(os.Environ(), processEnv+"="+path)
+		cmds[i] = cmd
+	}
+
+	errs := make(chan error, processCount)
+	for i, cmd := range cmds {
+		go func() {
+			var err error
+			outputs[i], err = cmd.CombinedOutput()
+			errs <- err
+		}()
+	}
+	for range cmds {
+		if err := <-errs; err != nil {
+			t.Errorf("process failed: %v", err)
+		}
+	}
+	if t.Failed() {
+		for i, output := range outputs {
+			t.Logf("process %d:\n%s", i, output)
+		}
+		t.FailNow()
+	}
+
+	tasks, err := open(path).List(context.Background(), task.NewTaskSelector(), &task.TaskFilter{IncludeCompleted: true, Trash: task.TrashInclude})
+	if err != nil {
+		t.Fatalf("List() after concurrent processes: %v", err)
+	}
+	if len(tasks) != processCount*tasksPerProcess {
+		t.Errorf("file has %d tasks, want %d", len(tasks), processCount*tasksPerProcess)
+	}
+
+	ids := make(map[uuid.UUID]bool, len(tasks))
+	nums := make(map[int]bool, len(tasks))
+	for _, tk := range tasks {
+		if ids[tk.ID] || nums[tk.Num] {
+			t.Errorf("task %s (#%d) is in the file twice or shares its number", tk.ID, tk.Num)
+		}
+		ids[tk.ID], nums[tk.Num] = true, true
+	}
+}
+
+func addFromProcess(t *testing.T, repo task.Repository) {
+	for i := 0; i < tasksPerProcess; i++ {
+		tk := newTask(fmt.Sprintf("process %d task %d", os.Getpid(), i))
+		if err := repo.Save(context.Background(), &tk); err != nil {
+			t.Fatalf("Save(): %v", err)
+		}
+	}
+}
@@ -0,0 +1,784 @@
+// Package storagetest is a conformance suite for task.Repository
+// implementations. A backend's tests call Run with a function that creates
+// an empty store.
+package storagetest
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+	"sort"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/ncfex/tasks/internal/task"
+)
+
+// Opener opens a repository on a store. Every call must return a new handle
+// on the same store, the way two tasks processes would see it.
+type Opener func(t *testing.T) task.Repository
+
+// NewStore creates an empty store and returns an Opener for it.
+type NewStore func(t *testing.T) Opener
+
+// Run runs the conformance suite against the backend created by newStore.
+func Run(t *testing.T, newStore NewStore) {
+	tests := []struct {
+		name string
+		run  func(t *testing.T, open Opener)
+	}{
+		{"SaveAssignsID", testSaveAssignsID},
+		{"SaveAndGet", testSaveAndGet},
+		{"SaveDuplicate", testSaveDuplicate},
+		{"GetMissing", testGetMissing},
+		{"Update", testUpdate},
+		{"UpdateMissing", testUpdateMissing},
+		{"UpdateStale", testUpdateStale},
+		{"Delete", testDelete},
+		{"DeleteMissing", testDeleteMissing},
+		{"PartialID", testPartialID},
+		{"PartialIDAmbiguous", testPartialIDAmbiguous},
+		{"PartialIDMissing", testPartialIDMissing},
+		{"PartialIDWildcards", testPartialIDWildcards},
+		{"ListFilters", testListFilters},
+		{"Walk", testWalk},
+		{"Numbers", testNumbers},
+		{"NumberKept", testNumberKept},
+		{"TxCommit", testTxCommit},
+		{"TxRollback", testTxRollback},
+		{"ContextDone", testContextDone},
+		{"Reopen", testReopen},
+		{"ConcurrentSaves", testConcurrentSaves},
+		{"ConcurrentUpdates", testConcurrentUpdates},
+		{"ConcurrentStaleUpdates", testConcurrentStaleUpdates},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.run(t, newStore(t))
+		})
+	}
+}
+
+// newTask returns a valid task with times at whole seconds, the precision
+// every backend stores.
+func newTask(description string) task.Task {
+	now := time.Now().UTC().Truncate(time.Second)
+	return task.Task{
+		ID:          uuid.New(),
+		Description: description,
+		Status:      task.StatusTodo,
+		CreatedAt:   now,
+		UpdatedAt:   now,
+		DueDate:     now.Add(24 * time.Hour),
+	}
+}
+
+func save(t *testing.T, repo task.Repository, tk task.Task) task.Task {
+	t.Helper()
+	if err := repo.Save(context.Background(), &tk); err != nil {
+		t.Fatalf("Save(%s): %v", tk.Description, err)
+	}
+	return tk
+}
+
+func get(t *testing.T, repo task.Repository, id uuid.UUID) *task.Task {
+	t.Helper()
+	got, err := repo.GetByID(context.Background(), id)
+	if err != nil {
+		t.Fatalf("GetByID(%s): %v", id, err)
+	}
+	return got
+}
+
+func listAll(t *testing.T, repo task.Repository) []task.Task {
+	t.Helper()
+	all := &task.TaskFilter{IncludeCompleted: true, Trash: task.TrashInclude}
+	tasks, err := repo.List(context.Background(), task.NewTaskSelector(), all)
+	if err != nil {
+		t.Fatalf("List: %v", err)
+	}
+	return tasks
+}
+
+func assertSameTask(t *testing.T, got, want *task.Task) {
+	t.Helper()
+	sameTime := func(a, b time.Time) bool {
+		return a.Truncate(time.Second).Equal(b.Truncate(time.Second))
+	}
+
+	switch {
+	case got.ID != want.ID:
+		t.Errorf("ID = %s, want %s", got.ID, want.ID)
+	case got.Num != want.Num:
+		t.Errorf("Num = %d, want %d", got.Num, want.Num)
+	case got.Description != want.Description:
+		t.Errorf("Description = %q, want %q", got.Description, want.Description)
+	case got.Status != want.Status:
+		t.Errorf("Status = %q, want %q", got.Status, want.Status)
+	case got.Estimate != want.Estimate:
+		t.Errorf("Estimate = %s, want %s", got.Estimate, want.Estimate)
+	case !sameTime(got.CreatedAt, want.CreatedAt):
+		t.Errorf("CreatedAt = %s, want %s", got.CreatedAt, want.CreatedAt)
+	case !sameTime(got.UpdatedAt, want.UpdatedAt):
+		t.Errorf("UpdatedAt = %s, want %s", got.UpdatedAt, want.UpdatedAt)
+	case !sameTime(got.DueDate, want.DueDate):
+		t.Errorf("DueDate = %s, want %s", got.DueDate, want.DueDate)
+	case !sameTime(got.CompletedAt, want.CompletedAt):
+		t.Errorf("CompletedAt = %s, want %s", got.CompletedAt, want.CompletedAt)
+	case !sameTime(got.DeletedAt, want.DeletedAt):
+		t.Errorf("DeletedAt = %s, want %s", got.DeletedAt, want.DeletedAt)
+	case got.Priority != want.Priority:
+		t.Errorf("Priority = %q, want %q", got.Priority, want.Priority)
+	case got.Project != want.Project:
+		t.Errorf("Project = %q, want %q", got.Project, want.Project)
+	case !slices.Equal(got.Tags, want.Tags):
+		t.Errorf("Tags = %q, want %q", got.Tags, want.Tags)
+	case !slices.EqualFunc(got.Annotations, want.Annotations, sameAnnotation):
+		t.Errorf("Annotations = %+v, want %+v", got.Annotations, want.Annotations)
+	case got.Version != want.Version:
+		t.Errorf("Version = %d, want %d", got.Version, want.Version)
+	}
+}
+
+func sameAnnotation(a, b task.Annotation) bool {
+	return a.Text == b.Text && a.CreatedAt.Equal(b.CreatedAt)
+}
+
+func assertNotFound(t *testing.T, err error) {
+	t.Helper()
+	var notFound *task.NotFoundError
+	if !errors.As(err, &notFound) || !errors.Is(err, task.ErrTaskNotFound) {
+		t.Fatalf("err = %v, want a *task.NotFoundError", err)
+	}
+}
+
+func testSaveAssignsID(t *testing.T, open Opener) {
+	repo := open(t)
+	tk := newTask("no ID")
+	tk.ID = uuid.Nil
+
+	tk = save(t, repo, tk)
+	if tk.ID == uuid.Nil {
+		t.Fatal("Save did not assign an ID")
+	}
+	assertSameTask(t, get(t, repo, tk.ID), &tk)
+}
+
+func testSaveAndGet(t *testing.T, open Opener) {
+	repo := open(t)
+	want := newTask("every field")
+	want.Status = task.StatusDone
+	want.Estimate = 90 * time.Minute
+	want.CompletedAt = want.CreatedAt.Add(time.Hour)
+	want.DeletedAt = want.CreatedAt.Add(2 * time.Hour)
+	want = save(t, repo, want)
+
+	assertSameTask(t, get(t, repo, want.ID), &want)
+}
+
+func testSaveDuplicate(t *testing.T, open Opener) {
+	repo := open(t)
+	tk := save(t, repo, newTask("original"))
+
+	dup := newTask("duplicate")
+	dup.ID = tk.ID
+	err := repo.Save(context.Background(), &dup)
+
+	var conflict *task.ConflictError
+	if !errors.As(err, &conflict) || !errors.Is(err, task.ErrConflict) {
+		t.Fatalf("Save(duplicate) err = %v, want a *task.ConflictError", err)
+	}
+	assertSameTask(t, get(t, repo, tk.ID), &tk)
+}
+
+func testGetMissing(t *testing.T, open Opener) {
+	repo := open(t)
+	save(t, repo, newTask("other"))
+
+	_, err := repo.GetByID(context.Background(), uuid.New())
+	assertNotFound(t, err)
+}
+
+func testUpdate(t *testing.T, open Opener) {
+	repo := open(t)
+	tk := save(t, repo, newTask("before"))
+	other := save(t, repo, newTask("untouched"))
+
+	tk.Description = "after"
+	tk.UpdatedAt = tk.CreatedAt.Add(3 * time.Hour)
+	tk.Status = task.StatusDone
+	tk.DueDate = tk.DueDate.Add(48 * time.Hour)
+	tk.Estimate = 30 * time.Minute
+	tk.Priority = "A"
+	tk.Project = "garden"
+	tk.Tags = []string{"phone", "errands"}
+	tk.Annotations = []task.Annotation{
+		{CreatedAt: tk.CreatedAt.Add(time.Hour), Text: "called, no answer"},
+		{CreatedAt: tk.CreatedAt.Add(2 * time.Hour), Text: "line one\nline two, \"quoted\""},
+	}
+	tk.CompletedAt = tk.CreatedAt.Add(time.Hour)
+	tk.DeletedAt = tk.CreatedAt.Add(2 * time.Hour)
+	if err := repo.Update(context.Background(), &tk); err != nil {
+		t.Fatalf("Update: %v", err)
+	}
+	assertSameTask(t, get(t, repo, tk.ID), &tk)
+
+	// Clearing fields must be stored too.
+	tk.Status = task.StatusInProgress
+	tk.Estimate = 0
+	tk.Priority = ""
+	tk.Project = ""
+	tk.Tags = nil
+	tk.Annotations = nil
+	tk.CompletedAt = time.Time{}
+	tk.DeletedAt = time.Time{}
+	if err := repo.Update(context.Background(), &tk); err != nil {
+		t.Fatalf("Update: %v", err)
+	}
+	assertSameTask(t, get(t, repo, tk.ID), &tk)
+	assertSameTask(t, get(t, repo, other.ID), &other)
+}
+
+func testUpdateMissing(t *testing.T, open Opener) {
+	repo := open(t)
+	tk := newTask("never saved")
+
+	assertNotFound(t, repo.Update(context.Background(), &tk))
+	if tasks := listAll(t, repo); len(tasks) != 0 {
+		t.Fatalf("Update of a missing task stored %d task(s)", len(tasks))
+	}
+}
+
+// testUpdateStale checks that Update refuses a task read before the stored
+// one was last updated.
+func testUpdateStale(t *testing.T, open Opener) {
+	repo := open(t)
+	mine := save(t, repo, newTask("original"))
+	theirs := mine
+
+	theirs.Description = "theirs"
+	if err := open(t).Update(context.Background(), &theirs); err != nil {
+		t.Fatalf("Update: %v", err)
+	}
+	if theirs.Version != mine.Version+1 {
+		t.Fatalf("Version = %d after Update, want %d", theirs.Version, mine.Version+1)
+	}
+
+	mine.Description = "mine"
+	err := repo.Update(context.Background(), &mine)
+	var conflict *task.VersionConflictError
+	if !errors.As(err, &conflict) || !errors.Is(err, task.ErrConflict) {
+		t.Fatalf("err = %v, want a *task.VersionConflictError", err)
+	}
+	assertSameTask(t, &conflict.Current, &theirs)
+	assertSameTask(t, get(t, repo, mine.ID), &theirs)
+}
+
+func testDelete(t *testing.T, open Opener) {
+	repo := open(t)
+	tk := save(t, repo, newTask("doomed"))
+	other := save(t, repo, newTask("survivor"))
+
+	if err := repo.Delete(context.Background(), &tk); err != nil {
+		t.Fatalf("Delete: %v", err)
+	}
+
+	_, err := repo.GetByID(context.Background(), tk.ID)
+	assertNotFound(t, err)
+	assertSameTask(t, get(t, repo, other.ID), &other)
+}
+
+func testDeleteMissing(t *testing.T, open Opener) {
+	repo := open(t)
+	tk := newTask("never saved")
+
+	assertNotFound(t, repo.Delete(context.Background(), &tk))
+}
+
+// withPrefix returns a task whose ID starts with prefix.
+func withPrefix(prefix, description string) task.Task {
+	tk := newTask(description)
+	id := tk.ID.String()
+	tk.ID = uuid.MustParse(prefix + id[len(prefix):])
+	return tk
+}
+
+func testPartialID(t *testing.T, open Opener) {
+	repo := open(t)
+	tk := save(t, repo, withPrefix("aaaa1", "target"))
+	save(t, repo, withPrefix("aaaa2", "neighbour"))
+
+	for _, prefix := range []string{"aaaa1", tk.ID.String()[:8], tk.ID.String()} {
+		got, err := repo.GetTaskByPartialId(context.Background(), prefix)
+		if err != nil {
+			t.Fatalf("GetTaskByPartialId(%q): %v", prefix, err)
+		}
+		assertSameTask(t, got, &tk)
+	}
+}
+
+func testPartialIDAmbiguous(t *testing.T, open Opener) {
+	repo := open(t)
+	first := save(t, repo, withPrefix("bbbb1", "first"))
+	second := save(t, repo, withPrefix("bbbb2", "second"))
+	save(t, repo, withPrefix("cccc1", "unrelated"))
+
+	_, err := repo.GetTaskByPartialId(context.Background(), "bbbb")
+
+	var ambiguous *task.AmbiguousIDError
+	if !errors.As(err, &ambiguous) || !errors.Is(err, task.ErrAmbiguousID) {
+		t.Fatalf("err = %v, want a *task.AmbiguousIDError", err)
+	}
+	if ambiguous.Prefix != "bbbb" {
+		t.Errorf("Prefix = %q, want %q", ambiguous.Prefix, "bbbb")
+	}
+
+	got := make([]string, len(ambiguous.Candidates))
+	for i, c := range ambiguous.Candidates {
+		got[i] = c.ID.String()
+	}
+	sort.Strings(got)
+	want := []string{first.ID.String(), second.ID.String()}
+	if fmt.Sprint(got) != fmt.Sprint(want) {
+		t.Errorf("Candidates = %v, want %v", got, want)
+	}
+}
+
+func testPartialIDMissing(t *testing.T, open Opener) {
+	repo := open(t)
+	save(t, repo, withPrefix("dddd1", "other"))
+
+	_, err := repo.GetTaskByPartialId(context.Background(), "eeee")
+	assertNotFound(t, err)
+}
+
+// Prefixes are matched literally, so LIKE wildcards match no task.
+func testPartialIDWildcards(t *testing.T, open Opener) {
+	repo := open(t)
+	save(t, repo, withPrefix("aaaa1", "one"))
+	save(t, repo, withPrefix("aaaa2", "two"))
+
+	for _, prefix := range []string{"%", "_", "aaaa%", "aaa_1", `aaaa\`} {
+		_, err := repo.GetTaskByPartialId(context.Background(), prefix)
+		if !errors.As(err, new(*task.NotFoundError)) {
+			t.Errorf("GetTaskByPartialId(%q) err = %v, want NotFoundError", prefix, err)
+		}
+	}
+}
+
+func testListFilters(t *testing.T, open Opener) {
+	repo := open(t)
+
+	todo := save(t, repo, newTask("todo"))
+	inProgress := newTask("in progress")
+	inProgress.Status = task.StatusInProgress
+	inProgress = save(t, repo, inProgress)
+	done := newTask("done")
+	done.Status = task.StatusDone
+	done.CompletedAt = done.CreatedAt
+	done = save(t, repo, done)
+	cancelled := newTask("cancelled")
+	cancelled.Status = task.StatusCancelled
+	cancelled = save(t, repo, cancelled)
+	trashed := newTask("trashed")
+	trashed.DeletedAt = trashed.CreatedAt
+	trashed = save(t, repo, trashed)
+	since := todo.UpdatedAt.Add(time.Hour)
+	changed := newTask("changed")
+	changed.UpdatedAt = since.Add(time.Minute)
+	changed = save(t, repo, changed)
+	changedDone := newTask("changed done")
+	changedDone.Status = task.StatusDone
+	changedDone.CompletedAt = since
+	changedDone.UpdatedAt = since
+	changedDone = save(t, repo, changedDone)
+
+	tests := []struct {
+		name   string
+		filter *task.TaskFilter
+		want   []task.Task
+	}{
+		{"default", task.NewTaskFilter(), []task.Task{todo, inProgress, changed}},
+		{"include completed", &task.TaskFilter{IncludeCompleted: true}, []task.Task{todo, inProgress, done, cancelled, changed, changedDone}},
+		{"statuses", &task.TaskFilter{Statuses: []task.Status{task.StatusDone, task.StatusInProgress}}, []task.Task{inProgress, done, changedDone}},
+		{"trash only", &task.TaskFilter{IncludeCompleted: true, Trash: task.TrashOnly}, []task.Task{trashed}},
+		{"trash include", &task.TaskFilter{Trash: task.TrashInclude}, []task.Task{todo, inProgress, trashed, changed}},
+		{"changed since", &task.TaskFilter{IncludeCompleted: true, ChangedSince: since}, []task.Task{changed, changedDone}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tasks, err := repo.List(context.Background(), task.NewTaskSelector(), tt.filter)
+			if err != nil {
+				t.Fatalf("List: %v", err)
+			}
+			if got, want := descriptions(tasks), descriptions(tt.want); fmt.Sprint(got) != fmt.Sprint(want) {
+				t.Errorf("List = %v, want %v", got, want)
+			}
+		})
+	}
+}
+
+func testNumbers(t *testing.T, open Opener) {
+	repo := open(t)
+	first := save(t, repo, newTask("first"))
+	second := save(t, repo, newTask("second"))
+	third := save(t, repo, newTask("third"))
+
+	if first.Num <= 0 || second.Num <= first.Num || third.Num <= second.Num {
+		t.Fatalf("numbers = %d, %d, %d, want increasing positive numbers", first.Num, second.Num, third.Num)
+	}
+
+	got, err := repo.GetByNum(context.Background(), second.Num)
+	if err != nil {
+		t.Fatalf("GetByNum(%d): %v", second.Num, err)
+	}
+	assertSameTask(t, got, &second)
+
+	// Numbers of deleted tasks are not handed out again.
+	if err := repo.Delete(context.Background(), &third); err != nil {
+		t.Fatalf("Delete: %v", err)
+	}
+	fourth := save(t, repo, newTask("fourth"))
+	if fourth.Num <= third.Num {
+		t.Errorf("number after deleting #%d = %d, want a new one", third.Num, fourth.Num)
+	}
+
+	_, err = repo.GetByNum(context.Background(), third.Num)
+	assertNotFound(t, err)
+}
+
+// testNumberKept saves tasks that already have a number, as when they are
+// copied from another store.
+func testNumberKept(t *testing.T, open Opener) {
+	repo := open(t)
+	existing := save(t, repo, newTask("existing"))
+
+	copied := newTask("copied")
+	copied.Num = existing.Num + 10
+	copied = save(t, repo, copied)
+	if copied.Num != existing.Num+10 {
+		t.Errorf("Num = %d, want the free number %d to be kept", copied.Num, existing.Num+10)
+	}
+
+	clash := newTask("clash")
+	clash.Num = existing.Num
+	clash = save(t, repo, clash)
+	if clash.Num == existing.Num || clash.Num == copied.Num {
+		t.Errorf("Num = %d, want a number no other task has", clash.Num)
+	}
+	assertSameTask(t, get(t, repo, existing.ID), &existing)
+
+	next := save(t, repo, newTask("next"))
+	if next.Num <= copied.Num || next.Num == clash.Num {
+		t.Errorf("Num = %d, want one after #%d and not #%d", next.Num, copied.Num, clash.Num)
+	}
+}
+
+func transactor(t *testing.T, repo task.Repository) task.Transactor {
+	t.Helper()
+	transactor, ok := repo.(task.Transactor)
+	if !ok {
+		t.Skip("repository does not implement task.Transactor")
+	}
+	return transactor
+}
+
+// testTxCommit checks that a transaction sees its own writes and that they
+// are all stored once it succeeds.
+func testTxCommit(t *testing.T, open Opener) {
+	ctx := context.Background()
+	repo := open(t)
+	existing := save(t, repo, newTask("existing"))
+	removed := save(t, repo, newTask("removed"))
+
+	added := newTask("added")
+	err := transactor(t, repo).WithTx(ctx, func(tx task.Repository) error {
+		if err := tx.Save(ctx, &added); err != nil {
+			return err
+		}
+		if _, err := tx.GetByID(ctx, added.ID); err != nil {
+			return fmt.Errorf("saved task not visible in transaction: %w", err)
+		}
+
+		existing.Status = task.StatusWaiting
+		if err := tx.Update(ctx, &existing); err != nil {
+			return err
+		}
+		return tx.Delete(ctx, &removed)
+	})
+	if err != nil {
+		t.Fatalf("WithTx: %v", err)
+	}
+
+	other := open(t)
+	assertSameTask(t, get(t, other, added.ID), &added)
+	assertSameTask(t, get(t, other, existing.ID), &existing)
+	_, err = other.GetByID(ctx, removed.ID)
+	assertNotFound(t, err)
+}
+
+// testWalk checks that task.Walk hands out every matching task once, in
+// order of number, across more tasks than a backend reads in one page.
+func testWalk(t *testing.T, open Opener) {
+	ctx := context.Background()
+	repo := open(t)
+
+	const count = 1201
+	err := transactor(t, repo).WithTx(ctx, func(tx task.Repository) error {
+		for i := 0; i < count; i++ {
+			tk := newTask(fmt.Sprintf("task %d", i))
+			if i%100 == 0 {
+				tk.Status = task.StatusDone
+				tk.CompletedAt = tk.CreatedAt
+			}
+			if err := tx.Save(ctx, &tk); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("WithTx: %v", err)
+	}
+
+	walk := func(filter *task.TaskFilter) int {
+		t.Helper()
+		seen := 0
+		last := 0
+		err := task.Walk(ctx, open(t), filter, func(tk *task.Task) error {
+			if tk.Num <= last {
+				return fmt.Errorf("task #%d after #%d", tk.Num, last)
+			}
+			last = tk.Num
+			seen++
+			return nil
+		})
+		if err != nil {
+			t.Fatalf("Walk: %v", err)
+		}
+		return seen
+	}
+
+	if got := walk(&task.TaskFilter{IncludeCompleted: true}); got != count {
+		t.Errorf("Walk(all) = %d tasks, want %d", got, count)
+	}
+	if got, want := walk(&task.TaskFilter{}), count-(count+99)/100; got != want {
+		t.Errorf("Walk(open) = %d tasks, want %d", got, want)
+	}
+
+	errStop := errors.New("stop")
+	calls := 0
+	err = task.Walk(ctx, repo, &task.TaskFilter{IncludeCompleted: true}, func(*task.Task) error {
+		calls++
+		return errStop
+	})
+	if !errors.Is(err, errStop) || calls != 1 {
+		t.Errorf("Walk stopped after %d call(s) with %v, want 1 call and the callback's error", calls, err)
+	}
+}
+
+// testTxRollback checks that a failing transaction leaves the store as it
+// was.
+func testTxRollback(t *testing.T, open Opener) {
+	ctx := context.Background()
+	repo := open(t)
+	existing := save(t, repo, newTask("existing"))
+
+	added := newTask("added")
+	errBoom := errors.New("boom")
+	err := transactor(t, repo).WithTx(ctx, func(tx task.Repository) error {
+		if err := tx.Save(ctx, &added); err != nil {
+			return err
+		}
+		changed := existing
+		changed.Description = "changed"
+		if err := tx.Update(ctx, &changed); err != nil {
+			return err
+		}
+		return errBoom
+	})
+	if !errors.Is(err, errBoom) {
+		t.Fatalf("WithTx err = %v, want %v", err, errBoom)
+	}
+
+	_, err = repo.GetByID(ctx, added.ID)
+	assertNotFound(t, err)
+	assertSameTask(t, get(t, repo, existing.ID), &existing)
+	if got := listAll(t, repo); len(got) != 1 {
+		t.Fatalf("got %d task(s) after rollback, want 1", len(got))
+	}
+}
+
+// A done context fails the call even when nothing else holds the store,
+// and a transaction whose context ends before it commits writes nothing.
+func testContextDone(t *testing.T, open Opener) {
+	repo := open(t)
+	existing := save(t, repo, newTask("existing"))
+
+	done, cancel := context.WithCancel(context.Background())
+	cancel()
+	if _, err := repo.List(done, task.NewTaskSelector(), task.NewTaskFilter()); !errors.Is(err, context.Canceled) {
+		t.Errorf("List with a done context err = %v, want context.Canceled", err)
+	}
+	added := newTask("added")
+	if err := repo.Save(done, &added); !errors.Is(err, context.Canceled) {
+		t.Errorf("Save with a done context err = %v, want context.Canceled", err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	added = newTask("added")
+	err := transactor(t, repo).WithTx(ctx, func(tx task.Repository) error {
+		if err := tx.Save(ctx, &added); err != nil {
+			return err
+		}
+		cancel()
+		return nil
+	})
+	if !errors.Is(err, context.Canceled) {
+		t.Errorf("WithTx cancelled before commit err = %v, want context.Canceled", err)
+	}
+
+	got := listAll(t, repo)
+	if len(got) != 1 || got[0].ID != existing.ID {
+		t.Errorf("got %v after done contexts, want only %q", descriptions(got), existing.Description)
+	}
+}
+
+func descriptions(tasks []task.Task) []string {
+	names := make([]string, len(tasks))
+	for i := range tasks {
+		names[i] = tasks[i].Description
+	}
+	sort.Strings(names)
+	return names
+}
+
+func testReopen(t *testing.T, open Opener) {
+	tk := save(t, open(t), newTask("persisted"))
+
+	assertSameTask(t, get(t, open(t), tk.ID), &tk)
+}
+
+// testConcurrentSaves saves from several goroutines through two handles on
+// the same store, so file backends have to coordinate across processes as
+// well as within one.
+func testConcurrentSaves(t *testing.T, open Opener) {
+	const workers, perWorker = 8, 5
+	handles := []task.Repository{open(t), open(t)}
+
+	var wg sync.WaitGroup
+	errs := make(chan error, workers*perWorker)
+	for w := 0; w < workers; w++ {
+		wg.Add(1)
+		go func(w int) {
+			defer wg.Done()
+			repo := handles[w%len(handles)]
+			for i := 0; i < perWorker; i++ {
+				tk := newTask(fmt.Sprintf("worker %d task %d", w, i))
+				if err := repo.Save(context.Background(), &tk); err != nil {
+					errs <- err
+				}
+			}
+		}(w)
+	}
+	wg.Wait()
+	close(errs)
+	for err := range errs {
+		t.Errorf("Save: %v", err)
+	}
+
+	tasks := listAll(t, open(t))
+	if len(tasks) != workers*perWorker {
+		t.Fatalf("stored %d task(s), want %d", len(tasks), workers*perWorker)
+	}
+
+	nums := make(map[int]bool, len(tasks))
+	for _, tk := range tasks {
+		if tk.Num <= 0 || nums[tk.Num] {
+			t.Errorf("task %q has number %d, want a unique positive number", tk.Description, tk.Num)
+		}
+		nums[tk.Num] = true
+	}
+}
+
+// testConcurrentUpdates updates different tasks at the same time. A backend
+// that rewrites the whole store without holding its lock loses some of them.
+func testConcurrentUpdates(t *testing.T, open Opener) {
+	const count = 20
+	handles := []task.Repository{open(t), open(t)}
+
+	tasks := make([]task.Task, count)
+	for i := range tasks {
+		tasks[i] = save(t, handles[0], newTask(fmt.Sprintf("task %d", i)))
+	}
+
+	var wg sync.WaitGroup
+	errs := make(chan error, count)
+	for i := range tasks {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			tk := tasks[i]
+			tk.Description = fmt.Sprintf("task %d updated", i)
+			if err := handles[i%len(handles)].Update(context.Background(), &tk); err != nil {
+				errs <- err
+			}
+		}(i)
+	}
+	wg.Wait()
+	close(errs)
+	for err := range errs {
+		t.Errorf("Update: %v", err)
+	}
+
+	repo := open(t)
+	for i := range tasks {
+		got := get(t, repo, tasks[i].ID)
+		if want := fmt.Sprintf("task %d updated", i); got.Description != want {
+			t.Errorf("task %d: Description = %q, want %q", i, got.Description, want)
+		}
+	}
+}
+
+// testConcurrentStaleUpdates checks that of several updates made from the
+// same read, exactly one succeeds.
+func testConcurrentStaleUpdates(t *testing.T, open Opener) {
+	const count = 8
+	handles := []task.Repository{open(t), open(t)}
+	original := save(t, handles[0], newTask("original"))
+
+	var wg sync.WaitGroup
+	results := make(chan error, count)
+	for i := 0; i < count; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			tk := original
+			tk.Description = fmt.Sprintf("update %d", i)
+			results <- handles[i%len(handles)].Update(context.Background(), &tk)
+		}(i)
+	}
+	wg.Wait()
+	close(results)
+
+	succeeded := 0
+	for err := range results {
+		switch {
+		case err == nil:
+			succeeded++
+		case !errors.Is(err, task.ErrConflict):
+			t.Errorf("Update: %v", err)
+		}
+	}
+	if succeeded != 1 {
+		t.Errorf("%d updates succeeded, want 1", succeeded)
+	}
+	if got := get(t, open(t), original.ID); got.Version != original.Version+1 {
+		t.Errorf("Version = %d, want %d", got.Version, original.Version+1)
+	}
+}
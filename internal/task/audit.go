@@ -0,0 +1,170 @@
+package task
+
+import (
+	"context"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+type Action string
+
+const (
+	ActionCreate   Action = "create"
+	ActionUpdate   Action = "update"
+	ActionComplete Action = "complete"
+	ActionDelete   Action = "delete"
+	ActionRestore  Action = "restore"
+	ActionPurge    Action = "purge"
+	ActionUndo     Action = "undo"
+	ActionRedo     Action = "redo"
+)
+
+type FieldDiff struct {
+	Field  TaskField `json:"field"`
+	Before string    `json:"before"`
+	After  string    `json:"after"`
+}
+
+type AuditEvent struct {
+	TaskID     uuid.UUID   `json:"task_id"`
+	Action     Action      `json:"action"`
+	User       string      `json:"user"`
+	Diffs      []FieldDiff `json:"diffs"`
+	RecordedAt time.Time   `json:"recorded_at"`
+}
+
+// AuditLog stores the change history of tasks. History returns events oldest
+// first.
+type AuditLog interface {
+	Record(ctx context.Context, event AuditEvent) error
+	History(ctx context.Context, taskID uuid.UUID) ([]AuditEvent, error)
+}
+
+// BatchAuditLog is implemented by audit logs that can look up the history
+// of many tasks at once more cheaply than one at a time, such as a log file
+// that has to be read whole for every lookup.
+type BatchAuditLog interface {
+	Histories(ctx context.Context, taskIDs []uuid.UUID) (map[uuid.UUID][]AuditEvent, error)
+}
+
+// histories returns the history of each of taskIDs, oldest event first,
+// asking log for all of them at once if it supports that.
+func histories(ctx context.Context, log AuditLog, taskIDs []uuid.UUID) (map[uuid.UUID][]AuditEvent, error) {
+	if batch, ok := log.(BatchAuditLog); ok {
+		return batch.Histories(ctx, taskIDs)
+	}
+
+	result := make(map[uuid.UUID][]AuditEvent, len(taskIDs))
+	for _, id := range taskIDs {
+		events, err := log.History(ctx, id)
+		if err != nil {
+			return nil, err
+		}
+		result[id] = events
+	}
+	return result, nil
+}
+
+// TxAuditLog is implemented by audit logs kept in the same database as the
+// tasks, like TxJournal.
+type TxAuditLog interface {
+	BindTx(repo Repository) AuditLog
+}
+
+// Diff lists the fields that differ between two versions of a task. Either
+// side may be nil for tasks that were created or removed.
+func Diff(before, after *Task) []FieldDiff {
+	beforeValues := auditValues(before)
+	afterValues := auditValues(after)
+
+	var diffs []FieldDiff
+	for i, field := range auditFields {
+		if beforeValues[i] == afterValues[i] {
+			continue
+		}
+		diffs = append(diffs, FieldDiff{
+			Field:  field,
+			Before: beforeValues[i],
+			After:  afterValues[i],
+		})
+	}
+
+	return diffs
+}
+
+var auditFields = []TaskField{
+	TaskFieldDescription,
+	TaskFieldStatus,
+	TaskFieldCreatedAt,
+	TaskFieldDueDate,
+	TaskFieldEstimate,
+	TaskFieldCompletedAt,
+	TaskFieldDeletedAt,
+	TaskFieldPriority,
+	TaskFieldProject,
+	TaskFieldTags,
+	TaskFieldAnnotations,
+}
+
+func auditValues(t *Task) []string {
+	values := make([]string, len(auditFields))
+	if t == nil {
+		return values
+	}
+
+	values[0] = t.Description
+	values[1] = string(t.Status)
+	values[2] = formatAuditTime(t.CreatedAt)
+	values[3] = formatAuditTime(t.DueDate)
+	if t.Estimate != 0 {
+		values[4] = t.Estimate.String()
+	}
+	values[5] = formatAuditTime(t.CompletedAt)
+	values[6] = formatAuditTime(t.DeletedAt)
+	values[7] = t.Priority
+	values[8] = t.Project
+	values[9] = FormatTags(t.Tags)
+	values[10] = FormatAnnotations(t.Annotations)
+
+	return values
+}
+
+func formatAuditTime(t time.Time) string {
+	if t.IsZero() {
+		return ""
+	}
+	return t.Format(time.RFC3339)
+}
+
+func (s *service) audit(ctx context.Context, repo Repository, action Action, changes ...Change) error {
+	if s.auditLog == nil {
+		return nil
+	}
+
+	auditLog := s.auditLog
+	if binder, ok := auditLog.(TxAuditLog); ok {
+		auditLog = binder.BindTx(repo)
+	}
+
+	now := time.Now()
+	for _, change := range changes {
+		subject := change.After
+		if subject == nil {
+			subject = change.Before
+		}
+
+		event := AuditEvent{
+			TaskID:     subject.ID,
+			Action:     action,
+			User:       s.user,
+			Diffs:      Diff(change.Before, change.After),
+			RecordedAt: now,
+		}
+		if err := auditLog.Record(ctx, event); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,113 @@
+package task_test
+
+import (
+	"context"
+	"errors"
+	"path/filepath"
+	"testing"
+	"time"
+
+	jsonstore "github.com/ncfex/tasks/internal/storage/json"
+	"github.com/ncfex/tasks/internal/task"
+)
+
+func TestDiff(t *testing.T) {
+	created := time.Date(2024, 5, 1, 9, 0, 0, 0, time.UTC)
+	before := task.Task{Description: "pay rent", Status: task.StatusTodo, CreatedAt: created, Tags: []string{"bills"}}
+	after := before
+	after.Description = "pay the rent"
+	after.Estimate = 90 * time.Minute
+	after.Tags = nil
+	after.UpdatedAt = created.Add(time.Hour)
+
+	want := []task.FieldDiff{
+		{Field: task.TaskFieldDescription, Before: "pay rent", After: "pay the rent"},
+		{Field: task.TaskFieldEstimate, Before: "", After: "1h30m0s"},
+		{Field: task.TaskFieldTags, Before: "bills", After: ""},
+	}
+	assertDiffs(t, task.Diff(&before, &after), want)
+
+	if diffs := task.Diff(&before, &before); len(diffs) != 0 {
+		t.Errorf("Diff(same) = %+v, want none", diffs)
+	}
+
+	annotated := before
+	annotated.Annotations = []task.Annotation{{CreatedAt: created, Text: "landlord wants a bank transfer"}}
+	assertDiffs(t, task.Diff(&before, &annotated), []task.FieldDiff{
+		{Field: task.TaskFieldAnnotations, Before: "", After: task.FormatAnnotations(annotated.Annotations)},
+	})
+
+	fromNothing := task.Diff(nil, &before)
+	if len(fromNothing) != 4 || fromNothing[0].Before != "" || fromNothing[0].After != "pay rent" {
+		t.Errorf("Diff(nil, task) = %+v, want every set field from empty", fromNothing)
+	}
+}
+
+func assertDiffs(t *testing.T, got, want []task.FieldDiff) {
+	t.Helper()
+	if len(got) != len(want) {
+		t.Fatalf("diffs = %+v, want %+v", got, want)
+	}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Errorf("diff %d = %+v, want %+v", i, got[i], want[i])
+		}
+	}
+}
+
+func TestHistory(t *testing.T) {
+	ctx := context.Background()
+	service, _ := newService(t)
+	created, err := service.Create(ctx, "write report", time.Time{}, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, _, err := service.SetStatus(ctx, "#1", task.StatusInProgress); err != nil {
+		t.Fatal(err)
+	}
+	if err := service.Delete(ctx, "#1"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := service.Purge(ctx, time.Now().Add(time.Second)); err != nil {
+		t.Fatal(err)
+	}
+
+	// Purged tasks are looked up by their full ID.
+	events, err := service.History(ctx, created.ID.String())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	wantActions := []task.Action{task.ActionCreate, task.ActionUpdate, task.ActionDelete, task.ActionPurge}
+	if len(events) != len(wantActions) {
+		t.Fatalf("History() = %d events, want %d", len(events), len(wantActions))
+	}
+	for i, event := range events {
+		if event.Action != wantActions[i] || event.User != "tester" || event.TaskID != created.ID {
+			t.Errorf("event %d = %s by %s for %s, want %s by tester", i, event.Action, event.User, event.TaskID, wantActions[i])
+		}
+	}
+	assertDiffs(t, events[1].Diffs, []task.FieldDiff{
+		{Field: task.TaskFieldStatus, Before: "todo", After: "in_progress"},
+	})
+	if diffs := events[3].Diffs; len(diffs) == 0 || diffs[0].Before != "write report" || diffs[0].After != "" {
+		t.Errorf("purge diffs = %+v, want every field cleared", diffs)
+	}
+
+	if _, err := service.History(ctx, "#1"); !errors.Is(err, task.ErrTaskNotFound) {
+		t.Errorf("History(#1 after purge) error = %v, want %v", err, task.ErrTaskNotFound)
+	}
+}
+
+func TestHistoryWithoutAuditLog(t *testing.T) {
+	repo := jsonstore.NewRepository(filepath.Join(t.TempDir(), "tasks.json"))
+	service := task.NewService(repo)
+	created, err := service.Create(context.Background(), "write report", time.Time{}, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := service.History(context.Background(), created.ID.String()); !errors.Is(err, task.ErrAuditLogDisabled) {
+		t.Errorf("History() error = %v, want %v", err, task.ErrAuditLogDisabled)
+	}
+}
@@ -0,0 +1,173 @@
+package task
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// BatchAction is the change Batch makes to every selected task: either a
+// move to Status or, with Delete, a move to the trash.
+type BatchAction struct {
+	Status Status
+	Delete bool
+}
+
+// Select resolves refs, which may be partial IDs, task numbers or number
+// ranges such as "#3-7", to the active tasks they name. Trashed tasks and
+// gaps inside a range are skipped; a ref that names nothing is an error.
+// If filter is set, only matching tasks are kept, and with no refs every
+// task passing it is selected.
+func (s *service) Select(ctx context.Context, refs []string, filter *TaskFilter) ([]Task, error) {
+	ctx, cancel := s.bound(ctx)
+	defer cancel()
+
+	if len(refs) == 0 {
+		if filter == nil {
+			return nil, nil
+		}
+		tasks, err := s.repository.List(ctx, NewTaskSelector(), filter)
+		if err != nil {
+			return nil, &Error{Op: "Select", Err: err}
+		}
+		return tasks, nil
+	}
+
+	var all []Task
+	seen := make(map[uuid.UUID]bool)
+	var selected []Task
+	add := func(t *Task) {
+		if t.IsDeleted() || seen[t.ID] {
+			return
+		}
+		if filter != nil && !filter.Matches(t) {
+			return
+		}
+		seen[t.ID] = true
+		selected = append(selected, *t)
+	}
+
+	for _, ref := range refs {
+		from, to, ok := ParseNumRange(ref)
+		if !ok {
+			t, err := findActive(ctx, s.repository, ref)
+			if err != nil {
+				return nil, &Error{Op: "Select", Err: err}
+			}
+			add(t)
+			continue
+		}
+
+		if all == nil {
+			var err error
+			if all, err = s.allTasks(ctx); err != nil {
+				return nil, &Error{Op: "Select", Err: err}
+			}
+		}
+
+		found := false
+		for i := range all {
+			if all[i].Num >= from && all[i].Num <= to {
+				found = true
+				add(&all[i])
+			}
+		}
+		if !found {
+			return nil, &Error{Op: "Select", Err: &NotFoundError{ID: ref}}
+		}
+	}
+
+	return selected, nil
+}
+
+// BatchResult lists the tasks a Batch changed and those that already had
+// the requested status.
+type BatchResult struct {
+	Changed   []Task
+	Unchanged []Task
+}
+
+// Batch applies action to the tasks with the given IDs, all or nothing,
+// and records it as a single change that one undo reverts.
+func (s *service) Batch(ctx context.Context, ids []uuid.UUID, action BatchAction) (*BatchResult, error) {
+	ctx, cancel := s.bound(ctx)
+	defer cancel()
+
+	if action.Delete == (action.Status != "") {
+		return nil, &Error{Op: "Batch", Err: errors.New("batch action needs exactly one of a status or delete")}
+	}
+	if action.Status != "" && !action.Status.IsValid() {
+		return nil, &Error{Op: "Batch", Err: &ValidationError{Field: "status", Reason: fmt.Sprintf("unknown status %q", action.Status)}}
+	}
+
+	now := time.Now()
+	var result *BatchResult
+	var changes []Change
+	err := s.withTx(ctx, func(repo Repository) error {
+		result, changes = &BatchResult{}, nil
+		for _, id := range ids {
+			t, err := repo.GetByID(ctx, id)
+			if err != nil {
+				return err
+			}
+
+			before := *t
+			switch {
+			case t.IsDeleted():
+				return &NotFoundError{ID: id.String()}
+			case action.Delete:
+				t.DeletedAt = now
+			case t.Status == action.Status:
+				result.Unchanged = append(result.Unchanged, *t)
+				continue
+			default:
+				if err := s.changeStatus(t, action.Status, now); err != nil {
+					return err
+				}
+			}
+
+			t.UpdatedAt = now
+			after := *t
+			result.Changed = append(result.Changed, after)
+			changes = append(changes, Change{Before: &before, After: &after})
+		}
+
+		// Every task is checked before any is written, so that even
+		// without a transaction a rejected batch changes nothing.
+		for _, change := range changes {
+			if err := repo.Update(ctx, change.After); err != nil {
+				return err
+			}
+		}
+
+		if len(changes) == 0 {
+			return nil
+		}
+		return s.record(ctx, repo, batchAuditAction(action), batchSummary(action, len(changes)), changes...)
+	})
+	if err != nil {
+		return nil, &Error{Op: "Batch", Err: err}
+	}
+
+	return result, nil
+}
+
+func batchAuditAction(action BatchAction) Action {
+	switch {
+	case action.Delete:
+		return ActionDelete
+	case action.Status == StatusDone:
+		return ActionComplete
+	}
+	return ActionUpdate
+}
+
+func batchSummary(action BatchAction, count int) string {
+	if action.Delete {
+		return fmt.Sprintf("delete %d task(s)", count)
+	}
+	return fmt.Sprintf("set %d task(s) to %s", count, action.Status)
+}
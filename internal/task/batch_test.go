@@ -0,0 +1,178 @@
+package task_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/ncfex/tasks/internal/task"
+)
+
+// createTasks adds tasks #1 to #n.
+func createTasks(t *testing.T, service task.TaskService, n int) []*task.Task {
+	t.Helper()
+	tasks := make([]*task.Task, n)
+	for i := range tasks {
+		created, err := service.Create(context.Background(), "task", time.Now(), 0)
+		if err != nil {
+			t.Fatal(err)
+		}
+		tasks[i] = created
+	}
+	return tasks
+}
+
+func nums(tasks []task.Task) []int {
+	nums := make([]int, len(tasks))
+	for i := range tasks {
+		nums[i] = tasks[i].Num
+	}
+	return nums
+}
+
+func TestSelect(t *testing.T) {
+	ctx := context.Background()
+	service, _ := newService(t)
+	tasks := createTasks(t, service, 6)
+	if err := service.Delete(ctx, "#4"); err != nil {
+		t.Fatal(err)
+	}
+	if _, _, err := service.Complete(ctx, "#6"); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name    string
+		refs    []string
+		filter  *task.TaskFilter
+		want    []int
+		wantErr error
+	}{
+		{name: "range skips trash", refs: []string{"#3-5"}, want: []int{3, 5}},
+		{name: "duplicates once", refs: []string{"#1", "#1-2", tasks[1].ID.String()[:8]}, want: []int{1, 2}},
+		{name: "filter", refs: []string{"#1-6"}, filter: &task.TaskFilter{Statuses: []task.Status{task.StatusDone}}, want: []int{6}},
+		{name: "filter only", filter: &task.TaskFilter{IncludeCompleted: true}, want: []int{1, 2, 3, 5, 6}},
+		{name: "nothing", want: []int{}},
+		{name: "empty range", refs: []string{"#7-9"}, wantErr: task.ErrTaskNotFound},
+		{name: "trashed number", refs: []string{"#4"}, wantErr: task.ErrTaskNotFound},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			selected, err := service.Select(ctx, tt.refs, tt.filter)
+			if tt.wantErr != nil {
+				if !errors.Is(err, tt.wantErr) {
+					t.Fatalf("Select error = %v, want %v", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			got := nums(selected)
+			if len(got) != len(tt.want) {
+				t.Fatalf("selected %v, want %v", got, tt.want)
+			}
+			for i := range got {
+				if got[i] != tt.want[i] {
+					t.Fatalf("selected %v, want %v", got, tt.want)
+				}
+			}
+		})
+	}
+}
+
+func TestBatchReportsUnchanged(t *testing.T) {
+	ctx := context.Background()
+	service, _ := newService(t)
+	tasks := createTasks(t, service, 3)
+	if _, _, err := service.Complete(ctx, "#2"); err != nil {
+		t.Fatal(err)
+	}
+
+	result, err := service.Batch(ctx, []uuid.UUID{tasks[0].ID, tasks[1].ID, tasks[2].ID}, task.BatchAction{Status: task.StatusDone})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := nums(result.Changed); len(got) != 2 || got[0] != 1 || got[1] != 3 {
+		t.Errorf("changed %v, want [1 3]", got)
+	}
+	if got := nums(result.Unchanged); len(got) != 1 || got[0] != 2 {
+		t.Errorf("unchanged %v, want [2]", got)
+	}
+
+	// One undo reverts the whole batch, and only the tasks it changed.
+	if _, err := service.Undo(ctx); err != nil {
+		t.Fatal(err)
+	}
+	all, err := service.List(ctx, nil, &task.TaskFilter{IncludeCompleted: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, got := range all {
+		want := task.StatusTodo
+		if got.Num == 2 {
+			want = task.StatusDone
+		}
+		if got.Status != want {
+			t.Errorf("after undo, #%d is %s, want %s", got.Num, got.Status, want)
+		}
+	}
+}
+
+func TestBatchAllOrNothing(t *testing.T) {
+	ctx := context.Background()
+	service, _ := newService(t)
+	tasks := createTasks(t, service, 2)
+	if _, _, err := service.Complete(ctx, "#2"); err != nil {
+		t.Fatal(err)
+	}
+
+	_, err := service.Batch(ctx, []uuid.UUID{tasks[0].ID, tasks[1].ID}, task.BatchAction{Status: task.StatusWaiting})
+	var transition *task.TransitionError
+	if !errors.As(err, &transition) {
+		t.Fatalf("Batch error = %v, want a TransitionError", err)
+	}
+	if transition.From != task.StatusDone || transition.To != task.StatusWaiting {
+		t.Errorf("TransitionError = %+v, want done -> waiting", transition)
+	}
+
+	first, err := service.GetByID(ctx, tasks[0].ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if first.Status != task.StatusTodo {
+		t.Errorf("#1 is %s after a rejected batch, want todo", first.Status)
+	}
+}
+
+func TestBatchAction(t *testing.T) {
+	service, _ := newService(t)
+	tasks := createTasks(t, service, 1)
+	ids := []uuid.UUID{tasks[0].ID}
+
+	for name, action := range map[string]task.BatchAction{
+		"neither":        {},
+		"both":           {Status: task.StatusDone, Delete: true},
+		"unknown status": {Status: "blocked"},
+	} {
+		if _, err := service.Batch(context.Background(), ids, action); err == nil {
+			t.Errorf("%s: Batch succeeded, want an error", name)
+		}
+	}
+}
+
+func TestCompleteReportsNoChange(t *testing.T) {
+	ctx := context.Background()
+	service, _ := newService(t)
+	createTasks(t, service, 1)
+
+	if _, changed, err := service.Complete(ctx, "#1"); err != nil || !changed {
+		t.Fatalf("first Complete = %v, %v, want a change", changed, err)
+	}
+	if _, changed, err := service.Complete(ctx, "#1"); err != nil || changed {
+		t.Fatalf("second Complete = %v, %v, want no change", changed, err)
+	}
+}
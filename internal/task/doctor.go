@@ -0,0 +1,213 @@
+package task
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Problem is an invalid or inconsistent stored task. Fix describes what
+// Repair does about it.
+type Problem struct {
+	TaskID  uuid.UUID
+	Message string
+	Fix     string
+}
+
+// Replacer is implemented by repositories that can overwrite every stored
+// task at once, numbering tasks that have no number. Repair needs it to
+// resolve duplicate IDs and numbers, which cannot be addressed one task at a
+// time.
+type Replacer interface {
+	ReplaceAll(ctx context.Context, tasks []Task) error
+}
+
+// diagnose checks tasks for problems and returns them together with the
+// repaired task set.
+func diagnose(tasks []Task, now time.Time) ([]Task, []Problem) {
+	var problems []Problem
+	repaired := make([]Task, 0, len(tasks))
+	seen := make(map[uuid.UUID]Task, len(tasks))
+	nums := make(map[int]string, len(tasks))
+
+	for _, t := range tasks {
+		original := t
+		if t.ID == uuid.Nil {
+			newID := replacementID(original)
+			problems = append(problems, Problem{
+				TaskID:  t.ID,
+				Message: fmt.Sprintf("task %q has no ID", t.Description),
+				Fix:     fmt.Sprintf("assign ID %s", newID),
+			})
+			t.ID = newID
+		}
+
+		if first, ok := seen[t.ID]; ok {
+			if reflect.DeepEqual(first, original) {
+				problems = append(problems, Problem{
+					TaskID:  t.ID,
+					Message: "duplicate ID with identical contents",
+					Fix:     "remove the copy",
+				})
+				continue
+			}
+
+			newID := replacementID(original)
+			problems = append(problems, Problem{
+				TaskID:  t.ID,
+				Message: fmt.Sprintf("duplicate ID shared by %q and %q", first.Description, t.Description),
+				Fix:     fmt.Sprintf("assign ID %s to %q", newID, t.Description),
+			})
+			t.ID = newID
+		}
+		seen[t.ID] = original
+
+		if t.Num <= 0 {
+			problems = append(problems, Problem{
+				TaskID:  t.ID,
+				Message: "missing task number",
+				Fix:     "assign the next number",
+			})
+			t.Num = 0
+		} else if other, ok := nums[t.Num]; ok {
+			problems = append(problems, Problem{
+				TaskID:  t.ID,
+				Message: fmt.Sprintf("number %s shared by %q and %q", FormatNum(t.Num), other, t.Description),
+				Fix:     fmt.Sprintf("assign the next number to %q", t.Description),
+			})
+			t.Num = 0
+		} else {
+			nums[t.Num] = t.Description
+		}
+
+		if t.Description == "" {
+			problems = append(problems, Problem{
+				TaskID:  t.ID,
+				Message: "empty description",
+				Fix:     `set description to "(no description)"`,
+			})
+			t.Description = "(no description)"
+		}
+
+		if !t.Status.IsValid() {
+			status := StatusTodo
+			if !t.CompletedAt.IsZero() {
+				status = StatusDone
+			}
+			problems = append(problems, Problem{
+				TaskID:  t.ID,
+				Message: fmt.Sprintf("invalid status %q", t.Status),
+				Fix:     fmt.Sprintf("set status to %s", status),
+			})
+			t.Status = status
+		}
+
+		if t.Estimate < 0 {
+			problems = append(problems, Problem{
+				TaskID:  t.ID,
+				Message: fmt.Sprintf("negative estimate %s", t.Estimate),
+				Fix:     "clear the estimate",
+			})
+			t.Estimate = 0
+		}
+
+		if !ValidPriority(t.Priority) {
+			problems = append(problems, Problem{
+				TaskID:  t.ID,
+				Message: fmt.Sprintf("invalid priority %q", t.Priority),
+				Fix:     "clear the priority",
+			})
+			t.Priority = ""
+		}
+
+		if t.CreatedAt.IsZero() {
+			problems = append(problems, Problem{
+				TaskID:  t.ID,
+				Message: "missing creation date",
+				Fix:     "set it to the current time",
+			})
+			t.CreatedAt = now
+		}
+
+		repaired = append(repaired, t)
+	}
+
+	return repaired, problems
+}
+
+// replacementID derives a new ID for t from its contents, so that Check
+// names the same ID that Repair assigns.
+func replacementID(t Task) uuid.UUID {
+	data, _ := json.Marshal(t)
+	return uuid.NewSHA1(t.ID, data)
+}
+
+func (s *service) allTasks(ctx context.Context) ([]Task, error) {
+	return s.repository.List(ctx, &TaskSelector{}, &TaskFilter{IncludeCompleted: true, Trash: TrashInclude})
+}
+
+func (s *service) Check(ctx context.Context) ([]Problem, error) {
+	ctx, cancel := s.bound(ctx)
+	defer cancel()
+
+	tasks, err := s.allTasks(ctx)
+	if err != nil {
+		return nil, &Error{Op: "Check", Err: err}
+	}
+
+	_, problems := diagnose(tasks, time.Now())
+	return problems, nil
+}
+
+// Repair fixes what diagnose can fix and returns every problem found. Fixes
+// are not journaled; callers are expected to back up the store first.
+func (s *service) Repair(ctx context.Context) ([]Problem, error) {
+	ctx, cancel := s.bound(ctx)
+	defer cancel()
+
+	tasks, err := s.allTasks(ctx)
+	if err != nil {
+		return nil, &Error{Op: "Repair", Err: err}
+	}
+
+	repaired, problems := diagnose(tasks, time.Now())
+	if len(problems) == 0 {
+		return nil, nil
+	}
+
+	if replacer, ok := s.repository.(Replacer); ok {
+		if err := replacer.ReplaceAll(ctx, repaired); err != nil {
+			return nil, &Error{Op: "Repair", Err: err}
+		}
+		return problems, nil
+	}
+
+	if len(repaired) != len(tasks) {
+		return nil, &Error{Op: "Repair", Err: fmt.Errorf("repository cannot remove duplicate tasks")}
+	}
+	for i := range repaired {
+		if repaired[i].ID != tasks[i].ID {
+			return nil, &Error{Op: "Repair", Err: fmt.Errorf("repository cannot reassign task IDs")}
+		}
+	}
+	err = s.withTx(ctx, func(repo Repository) error {
+		for i := range repaired {
+			if reflect.DeepEqual(repaired[i], tasks[i]) {
+				continue
+			}
+			if err := repo.Update(ctx, &repaired[i]); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, &Error{Op: "Repair", Err: err}
+	}
+
+	return problems, nil
+}
@@ -0,0 +1,53 @@
+package task_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/ncfex/tasks/internal/task"
+)
+
+// A dry run names the same replacement IDs that the repair assigns.
+func TestCheckAndRepairAgreeOnNewIDs(t *testing.T) {
+	ctx := context.Background()
+	service, repo := newService(t)
+
+	shared := uuid.New()
+	now := time.Now().UTC().Truncate(time.Second)
+	tasks := []task.Task{
+		{ID: shared, Num: 1, Description: "first", Status: task.StatusTodo, CreatedAt: now, UpdatedAt: now},
+		{ID: shared, Num: 2, Description: "second", Status: task.StatusTodo, CreatedAt: now, UpdatedAt: now},
+		{Num: 3, Description: "no id", Status: task.StatusTodo, CreatedAt: now, UpdatedAt: now},
+	}
+	if err := repo.(task.Replacer).ReplaceAll(ctx, tasks); err != nil {
+		t.Fatal(err)
+	}
+
+	checked, err := service.Check(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	again, err := service.Check(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	repaired, err := service.Repair(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(checked) != 2 || len(repaired) != 2 {
+		t.Fatalf("Check() = %+v, Repair() = %+v, want two problems each", checked, repaired)
+	}
+	for i := range checked {
+		if checked[i].Fix != again[i].Fix || checked[i].Fix != repaired[i].Fix {
+			t.Errorf("fix %d: Check() says %q then %q, Repair() says %q", i, checked[i].Fix, again[i].Fix, repaired[i].Fix)
+		}
+	}
+
+	if problems, err := service.Check(ctx); err != nil || len(problems) != 0 {
+		t.Errorf("Check() after Repair() = %+v, %v, want no problems", problems, err)
+	}
+}
@@ -3,11 +3,25 @@ package task
 import (
 	"errors"
 	"fmt"
+	"strings"
+
+	"github.com/google/uuid"
 )
 
 var (
 	ErrTaskNotFound = errors.New("task not found")
 	ErrInvalidTask  = errors.New("invalid task")
+	ErrAmbiguousID  = errors.New("ambiguous task ID")
+	ErrConflict     = errors.New("conflicting task")
+
+	ErrInvalidTransition = errors.New("status transition not allowed")
+	ErrTaskNotInTrash    = errors.New("task is not in trash")
+
+	ErrJournalDisabled = errors.New("journal is not enabled")
+	ErrNothingToUndo   = errors.New("nothing to undo")
+	ErrNothingToRedo   = errors.New("nothing to redo")
+
+	ErrAuditLogDisabled = errors.New("audit log is not enabled")
 )
 
 type Error struct {
@@ -18,3 +32,109 @@ type Error struct {
 func (e *Error) Error() string {
 	return fmt.Sprintf("%s: %v", e.Op, e.Err)
 }
+
+func (e *Error) Unwrap() error {
+	return e.Err
+}
+
+// NotFoundError is returned by repositories when no task matches ID, which
+// is either a full ID or a prefix of one.
+type NotFoundError struct {
+	ID string
+}
+
+func (e *NotFoundError) Error() string {
+	return fmt.Sprintf("no task matches ID %q", e.ID)
+}
+
+func (e *NotFoundError) Is(target error) bool {
+	return target == ErrTaskNotFound
+}
+
+// AmbiguousIDError is returned when a partial ID matches more than one task.
+type AmbiguousIDError struct {
+	Prefix     string
+	Candidates []Task
+}
+
+func (e *AmbiguousIDError) Error() string {
+	ids := make([]string, len(e.Candidates))
+	for i := range e.Candidates {
+		ids[i] = e.Candidates[i].ID.String()
+	}
+	return fmt.Sprintf("ID %q matches %d tasks: %s", e.Prefix, len(e.Candidates), strings.Join(ids, ", "))
+}
+
+func (e *AmbiguousIDError) Is(target error) bool {
+	return target == ErrAmbiguousID
+}
+
+// ConflictError is returned when a write clashes with a task already stored
+// under the same ID.
+type ConflictError struct {
+	ID     uuid.UUID
+	Reason string
+}
+
+func (e *ConflictError) Error() string {
+	return fmt.Sprintf("task %s: %s", e.ID, e.Reason)
+}
+
+func (e *ConflictError) Is(target error) bool {
+	return target == ErrConflict
+}
+
+// VersionConflictError is returned by Update when the stored task has been
+// changed since Attempted was read. Current is the task as stored now.
+type VersionConflictError struct {
+	Attempted Task
+	Current   Task
+}
+
+func (e *VersionConflictError) Error() string {
+	return fmt.Sprintf("task %s was changed by someone else (version %d, expected %d)",
+		e.Current.ID, e.Current.Version, e.Attempted.Version)
+}
+
+func (e *VersionConflictError) Is(target error) bool {
+	return target == ErrConflict
+}
+
+// TransitionError is returned when the workflow does not allow a task to
+// move from one status to another. Allowed lists where it may move instead.
+type TransitionError struct {
+	ID      string
+	From    Status
+	To      Status
+	Allowed []Status
+}
+
+func (e *TransitionError) Error() string {
+	msg := fmt.Sprintf("task %s cannot move from %s to %s", e.ID, e.From, e.To)
+	if len(e.Allowed) == 0 {
+		return msg + " (no moves allowed)"
+	}
+	allowed := make([]string, len(e.Allowed))
+	for i, status := range e.Allowed {
+		allowed[i] = string(status)
+	}
+	return fmt.Sprintf("%s (allowed: %s)", msg, strings.Join(allowed, ", "))
+}
+
+func (e *TransitionError) Is(target error) bool {
+	return target == ErrInvalidTransition
+}
+
+// ValidationError describes a task field that fails validation.
+type ValidationError struct {
+	Field  string
+	Reason string
+}
+
+func (e *ValidationError) Error() string {
+	return fmt.Sprintf("invalid task %s: %s", e.Field, e.Reason)
+}
+
+func (e *ValidationError) Is(target error) bool {
+	return target == ErrInvalidTask
+}
@@ -0,0 +1,191 @@
+package task
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+)
+
+// Change captures a single task before and after a mutation. A nil Before
+// means the task was created, a nil After means it was permanently removed.
+type Change struct {
+	Before *Task `json:"before,omitempty"`
+	After  *Task `json:"after,omitempty"`
+}
+
+// JournalEntry is one user-visible operation, which may touch several tasks.
+type JournalEntry struct {
+	Summary    string    `json:"summary"`
+	Changes    []Change  `json:"changes"`
+	RecordedAt time.Time `json:"recorded_at"`
+}
+
+// Journal keeps undo and redo stacks of mutations. Undo and Redo hand the
+// entry to apply and only move it to the other stack when apply succeeds.
+// Discard drops an entry that can no longer be applied from the top of
+// either stack.
+type Journal interface {
+	Append(ctx context.Context, entry JournalEntry) error
+	Undo(ctx context.Context, apply func(JournalEntry) error) (*JournalEntry, error)
+	Redo(ctx context.Context, apply func(JournalEntry) error) (*JournalEntry, error)
+	Discard(ctx context.Context, entry JournalEntry) error
+}
+
+// TxJournal is implemented by journals kept in the same database as the
+// tasks. BindTx returns the journal working in the transaction of repo, a
+// repository handed to a WithTx callback, so that an entry commits or rolls
+// back with the change it describes.
+type TxJournal interface {
+	BindTx(repo Repository) Journal
+}
+
+// record writes a mutation made through repo to the journal and the audit
+// log. It is called inside the transaction that made the mutation.
+func (s *service) record(ctx context.Context, repo Repository, action Action, summary string, changes ...Change) error {
+	if s.journal != nil {
+		entry := JournalEntry{
+			Summary:    summary,
+			Changes:    changes,
+			RecordedAt: time.Now(),
+		}
+		if err := s.journalFor(repo).Append(ctx, entry); err != nil {
+			return err
+		}
+	}
+
+	return s.audit(ctx, repo, action, changes...)
+}
+
+func (s *service) journalFor(repo Repository) Journal {
+	if binder, ok := s.journal.(TxJournal); ok {
+		return binder.BindTx(repo)
+	}
+	return s.journal
+}
+
+func (s *service) Undo(ctx context.Context) (*JournalEntry, error) {
+	ctx, cancel := s.bound(ctx)
+	defer cancel()
+
+	if s.journal == nil {
+		return nil, &Error{Op: "Undo", Err: ErrJournalDisabled}
+	}
+
+	var entry *JournalEntry
+	var failed *JournalEntry
+	err := s.withTx(ctx, func(repo Repository) error {
+		var err error
+		entry, err = s.journalFor(repo).Undo(ctx, func(entry JournalEntry) error {
+			inverse := make([]Change, 0, len(entry.Changes))
+			for i := len(entry.Changes) - 1; i >= 0; i-- {
+				if err := revert(ctx, repo, entry.Changes[i]); err != nil {
+					failed = &entry
+					return err
+				}
+				inverse = append(inverse, Change{Before: entry.Changes[i].After, After: entry.Changes[i].Before})
+			}
+			return s.audit(ctx, repo, ActionUndo, inverse...)
+		})
+		return err
+	})
+	if err != nil {
+		return nil, &Error{Op: "Undo", Err: s.discard(ctx, failed, err)}
+	}
+
+	return entry, nil
+}
+
+func (s *service) Redo(ctx context.Context) (*JournalEntry, error) {
+	ctx, cancel := s.bound(ctx)
+	defer cancel()
+
+	if s.journal == nil {
+		return nil, &Error{Op: "Redo", Err: ErrJournalDisabled}
+	}
+
+	var entry *JournalEntry
+	var failed *JournalEntry
+	err := s.withTx(ctx, func(repo Repository) error {
+		var err error
+		entry, err = s.journalFor(repo).Redo(ctx, func(entry JournalEntry) error {
+			for _, change := range entry.Changes {
+				if err := replay(ctx, repo, change); err != nil {
+					failed = &entry
+					return err
+				}
+			}
+			return s.audit(ctx, repo, ActionRedo, entry.Changes...)
+		})
+		return err
+	})
+	if err != nil {
+		return nil, &Error{Op: "Redo", Err: s.discard(ctx, failed, err)}
+	}
+
+	return entry, nil
+}
+
+// discard drops entry, which failed to apply with err, from the journal if
+// it can never apply because the tasks it touches were changed or removed
+// since, so that it does not block the entries below it.
+func (s *service) discard(ctx context.Context, entry *JournalEntry, err error) error {
+	if entry == nil || !(errors.Is(err, ErrConflict) || errors.Is(err, ErrTaskNotFound)) {
+		return err
+	}
+
+	if discardErr := s.journal.Discard(ctx, *entry); discardErr != nil {
+		return errors.Join(err, discardErr)
+	}
+	return fmt.Errorf("%w; %q was removed from the journal", err, entry.Summary)
+}
+
+// revert and replay put back a task as recorded in the journal. A task
+// that was changed in place is only put back if nobody changed it since:
+// undo expects the version the change left, and redo the version the undo
+// left, one later. Like any other change, this counts as an update of the
+// task.
+func revert(ctx context.Context, repo Repository, change Change) error {
+	switch {
+	case change.Before == nil:
+		return repo.Delete(ctx, change.After)
+	case change.After == nil:
+		before := *change.Before
+		before.UpdatedAt = time.Now()
+		return repo.Save(ctx, &before)
+	default:
+		before := *change.Before
+		return overwrite(ctx, repo, &before, change.After.Version)
+	}
+}
+
+func replay(ctx context.Context, repo Repository, change Change) error {
+	switch {
+	case change.Before == nil:
+		after := *change.After
+		after.UpdatedAt = time.Now()
+		return repo.Save(ctx, &after)
+	case change.After == nil:
+		return repo.Delete(ctx, change.Before)
+	default:
+		after := *change.After
+		return overwrite(ctx, repo, &after, change.After.Version+1)
+	}
+}
+
+// overwrite replaces the stored task with t if it is still at version.
+func overwrite(ctx context.Context, repo Repository, t *Task, version int) error {
+	current, err := repo.GetByID(ctx, t.ID)
+	if err != nil {
+		return err
+	}
+	if current.Version != version {
+		attempted := *t
+		attempted.Version = version
+		return &VersionConflictError{Attempted: attempted, Current: *current}
+	}
+
+	t.Version = current.Version
+	t.UpdatedAt = time.Now()
+	return repo.Update(ctx, t)
+}
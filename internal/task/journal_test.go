@@ -0,0 +1,168 @@
+package task_test
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/ncfex/tasks/internal/storage/audit"
+	"github.com/ncfex/tasks/internal/storage/fileformat"
+	"github.com/ncfex/tasks/internal/storage/journal"
+	"github.com/ncfex/tasks/internal/task"
+)
+
+// memoryCodec keeps the tasks file in memory and fails every write while
+// fail is set.
+type memoryCodec struct {
+	tasks   []task.Task
+	nextNum int
+	fail    bool
+}
+
+func (c *memoryCodec) ReadTasks(path string) ([]task.Task, int, error) {
+	return append([]task.Task(nil), c.tasks...), c.nextNum, nil
+}
+
+func (c *memoryCodec) WriteTasks(path string, tasks []task.Task, nextNum int) error {
+	if c.fail {
+		return errors.New("disk full")
+	}
+	c.tasks, c.nextNum = append([]task.Task(nil), tasks...), nextNum
+	return nil
+}
+
+// The journal and the audit log of the file backends only record changes
+// that reached the tasks file.
+func TestFailedWriteLeavesNoJournalOrHistory(t *testing.T) {
+	ctx := context.Background()
+	dir := t.TempDir()
+	path := filepath.Join(dir, "tasks.json")
+	if err := os.WriteFile(path, nil, 0644); err != nil {
+		t.Fatal(err)
+	}
+	codec := &memoryCodec{fail: true}
+	service := task.NewService(fileformat.NewStore(path, codec),
+		task.WithJournal(journal.NewJournal(filepath.Join(dir, "journal.json"))),
+		task.WithAuditLog(audit.NewAuditLog(filepath.Join(dir, "history.jsonl")), "tester"),
+	)
+
+	if _, err := service.Create(ctx, "write report", time.Time{}, 0); err == nil {
+		t.Fatal("Create() succeeded with a failing tasks file")
+	}
+	if _, err := service.Undo(ctx); !errors.Is(err, task.ErrNothingToUndo) {
+		t.Errorf("Undo() error = %v, want %v", err, task.ErrNothingToUndo)
+	}
+	if _, err := os.Stat(filepath.Join(dir, "history.jsonl")); !errors.Is(err, os.ErrNotExist) {
+		t.Errorf("audit log written for a failed change: %v", err)
+	}
+
+	codec.fail = false
+	created, err := service.Create(ctx, "write report", time.Time{}, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if events, err := service.History(ctx, created.ID.String()); err != nil || len(events) != 1 {
+		t.Errorf("History() = %d events, %v, want the create", len(events), err)
+	}
+
+	// An undo whose write fails stays on the undo stack.
+	codec.fail = true
+	if _, err := service.Undo(ctx); err == nil {
+		t.Fatal("Undo() succeeded with a failing tasks file")
+	}
+	codec.fail = false
+	if _, err := service.Undo(ctx); err != nil {
+		t.Fatalf("Undo() after the failure: %v", err)
+	}
+	if len(codec.tasks) != 0 {
+		t.Errorf("tasks after undo = %d, want 0", len(codec.tasks))
+	}
+}
+
+func TestUndoDiscardsEntryThatNoLongerApplies(t *testing.T) {
+	ctx := context.Background()
+	service, repo := newService(t)
+	tasks := createTasks(t, service, 2)
+
+	// Another process removes #2 behind the journal's back.
+	if err := repo.Delete(ctx, tasks[1]); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := service.Undo(ctx); !errors.Is(err, task.ErrTaskNotFound) {
+		t.Fatalf("Undo() error = %v, want %v", err, task.ErrTaskNotFound)
+	}
+
+	// The stale entry is gone, so undo moves on to the one below it.
+	entry, err := service.Undo(ctx)
+	if err != nil {
+		t.Fatalf("second Undo(): %v", err)
+	}
+	if len(entry.Changes) != 1 || entry.Changes[0].After.ID != tasks[0].ID {
+		t.Errorf("second Undo() = %+v, want the creation of #1", entry)
+	}
+	if _, err := service.Undo(ctx); !errors.Is(err, task.ErrNothingToUndo) {
+		t.Errorf("third Undo() error = %v, want %v", err, task.ErrNothingToUndo)
+	}
+}
+
+func TestUndoRedoRefuseToOverwriteNewerChanges(t *testing.T) {
+	ctx := context.Background()
+	service, repo := newService(t)
+	created := createTasks(t, service, 1)[0]
+
+	// A plain undo and redo of an edit go through.
+	if _, _, err := service.SetStatus(ctx, "#1", task.StatusInProgress); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := service.Undo(ctx); err != nil {
+		t.Fatalf("Undo(): %v", err)
+	}
+	if _, err := service.Redo(ctx); err != nil {
+		t.Fatalf("Redo(): %v", err)
+	}
+
+	// Someone else edits the task after the journaled change.
+	edit := func() {
+		t.Helper()
+		current, err := repo.GetByID(ctx, created.ID)
+		if err != nil {
+			t.Fatal(err)
+		}
+		current.Description += " (edited)"
+		if err := repo.Update(ctx, current); err != nil {
+			t.Fatal(err)
+		}
+	}
+	edit()
+
+	var stale *task.VersionConflictError
+	if _, err := service.Undo(ctx); !errors.As(err, &stale) {
+		t.Fatalf("Undo() over a newer edit error = %v, want a version conflict", err)
+	}
+	got, err := repo.GetByID(ctx, created.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got.Description != "task (edited)" || got.Status != task.StatusInProgress {
+		t.Errorf("task after refused undo = %q, %s, want the edit kept", got.Description, got.Status)
+	}
+
+	// The same holds for redo.
+	if _, _, err := service.SetStatus(ctx, "#1", task.StatusDone); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := service.Undo(ctx); err != nil {
+		t.Fatalf("Undo(): %v", err)
+	}
+	edit()
+	if _, err := service.Redo(ctx); !errors.As(err, &stale) {
+		t.Fatalf("Redo() over a newer edit error = %v, want a version conflict", err)
+	}
+	if got, _ := repo.GetByID(ctx, created.ID); got.Status != task.StatusInProgress {
+		t.Errorf("status after refused redo = %s, want in_progress", got.Status)
+	}
+}
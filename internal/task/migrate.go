@@ -0,0 +1,240 @@
+package task
+
+import (
+	"context"
+	"crypto/sha256"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// ConflictStrategy decides what MigrateTasks does with a task whose ID
+// already exists in the target repository.
+type ConflictStrategy string
+
+const (
+	ConflictFail      ConflictStrategy = "fail"
+	ConflictSkip      ConflictStrategy = "skip"
+	ConflictOverwrite ConflictStrategy = "overwrite"
+	// ConflictNewestWins overwrites a task only if the incoming copy was
+	// changed more recently than the stored one.
+	ConflictNewestWins ConflictStrategy = "newest-wins"
+)
+
+func (c ConflictStrategy) IsValid() bool {
+	switch c {
+	case ConflictFail, ConflictSkip, ConflictOverwrite, ConflictNewestWins:
+		return true
+	}
+	return false
+}
+
+type MigrateOptions struct {
+	OnConflict ConflictStrategy
+	DryRun     bool
+}
+
+// MigrateResult counts what MigrateTasks did, or would do on a dry run.
+// Verified is the number of written tasks read back unchanged from the
+// target.
+type MigrateResult struct {
+	Read        int
+	Created     int
+	Overwritten int
+	Skipped     int
+	Conflicts   []uuid.UUID
+	Verified    int
+}
+
+// MigrateTasks copies every task, including completed and trashed ones, from
+// one repository to another, keeping IDs and timestamps. The source is read
+// a page at a time and the target written in a single transaction, so a
+// failure, or a conflict under ConflictFail, leaves the target untouched.
+func MigrateTasks(ctx context.Context, from, to Repository, opts MigrateOptions) (*MigrateResult, error) {
+	if opts.OnConflict == "" {
+		opts.OnConflict = ConflictFail
+	}
+	if !opts.OnConflict.IsValid() {
+		return nil, fmt.Errorf("invalid conflict strategy: %s", opts.OnConflict)
+	}
+
+	all := &TaskFilter{IncludeCompleted: true, Trash: TrashInclude}
+	return copyTasks(ctx, to, opts, func(fn func(*Task) error) error {
+		err := Walk(ctx, from, all, fn)
+		var copyErr *copyError
+		if err != nil && !errors.As(err, &copyErr) {
+			return fmt.Errorf("failed to read source tasks: %w", err)
+		}
+		return err
+	})
+}
+
+// ImportTasks writes the tasks each yields, read from elsewhere such as a
+// file, to a repository the way MigrateTasks does. Tasks without an ID get
+// a new one, so they are always created, and every task must pass
+// validation; nothing is written otherwise.
+func ImportTasks(ctx context.Context, to Repository, each func(fn func(*Task) error) error, opts MigrateOptions) (*MigrateResult, error) {
+	if opts.OnConflict == "" {
+		opts.OnConflict = ConflictFail
+	}
+	if !opts.OnConflict.IsValid() {
+		return nil, fmt.Errorf("invalid conflict strategy: %s", opts.OnConflict)
+	}
+
+	return copyTasks(ctx, to, opts, func(fn func(*Task) error) error {
+		return each(func(t *Task) error {
+			if t.ID == uuid.Nil {
+				t.ID = uuid.New()
+			}
+			if err := t.Validate(); err != nil {
+				return fmt.Errorf("task %q: %w", t.Description, err)
+			}
+			return fn(t)
+		})
+	})
+}
+
+// copyError is a failure to write to the target, as opposed to one reading
+// the source.
+type copyError struct {
+	err error
+}
+
+func (e *copyError) Error() string { return e.err.Error() }
+func (e *copyError) Unwrap() error { return e.err }
+
+// copyTasks writes the tasks each hands out to the target in one
+// transaction. Written tasks are remembered by a fingerprint only, and
+// checked against the target in a single pass once committed.
+func copyTasks(ctx context.Context, to Repository, opts MigrateOptions, each func(fn func(*Task) error) error) (*MigrateResult, error) {
+	result := &MigrateResult{}
+	var written map[uuid.UUID][sha256.Size]byte
+
+	err := withTx(ctx, to, func(tx Repository) error {
+		*result = MigrateResult{}
+		written = make(map[uuid.UUID][sha256.Size]byte)
+
+		err := each(func(t *Task) error {
+			result.Read++
+			write, err := copyTask(ctx, tx, t, opts, result)
+			if err != nil {
+				return &copyError{err: fmt.Errorf("failed to write task %s: %w", t.ID, err)}
+			}
+			if write {
+				written[t.ID] = fingerprint(t)
+			}
+			return nil
+		})
+		if err != nil {
+			return err
+		}
+
+		if len(result.Conflicts) > 0 && opts.OnConflict == ConflictFail {
+			return fmt.Errorf("%w: %d task(s) already exist in the target", ErrConflict, len(result.Conflicts))
+		}
+		return nil
+	})
+	if err != nil {
+		var copyErr *copyError
+		if errors.As(err, &copyErr) {
+			err = copyErr.err
+		}
+		if errors.Is(err, ErrConflict) {
+			return &MigrateResult{Read: result.Read, Conflicts: result.Conflicts}, err
+		}
+		return nil, err
+	}
+
+	if opts.DryRun || len(written) == 0 {
+		return result, nil
+	}
+
+	all := &TaskFilter{IncludeCompleted: true, Trash: TrashInclude}
+	err = Walk(ctx, to, all, func(copied *Task) error {
+		if want, ok := written[copied.ID]; ok && fingerprint(copied) == want {
+			result.Verified++
+		}
+		return nil
+	})
+	if err != nil {
+		return result, fmt.Errorf("failed to verify target: %w", err)
+	}
+
+	return result, nil
+}
+
+// copyTask resolves a conflict for t, counts the outcome in result and
+// writes t unless this is a dry run. It reports whether t is to be written.
+func copyTask(ctx context.Context, to Repository, t *Task, opts MigrateOptions, result *MigrateResult) (bool, error) {
+	existing, err := to.GetByID(ctx, t.ID)
+	if err != nil && !errors.Is(err, ErrTaskNotFound) {
+		return false, fmt.Errorf("failed to look up task in target: %w", err)
+	}
+
+	if err != nil {
+		result.Created++
+		if opts.DryRun {
+			return true, nil
+		}
+		copied := *t
+		return true, to.Save(ctx, &copied)
+	}
+
+	result.Conflicts = append(result.Conflicts, t.ID)
+	overwrite := opts.OnConflict == ConflictOverwrite ||
+		opts.OnConflict == ConflictNewestWins && newer(t, existing)
+	if !overwrite || opts.OnConflict == ConflictFail {
+		result.Skipped++
+		return false, nil
+	}
+
+	// Tasks read from files may carry no number; keep the stored one.
+	copied := *t
+	if copied.Num == 0 {
+		copied.Num = existing.Num
+	}
+	result.Overwritten++
+	if opts.DryRun {
+		return true, nil
+	}
+
+	// Delete and save rather than update, since not every backend lets
+	// Update change the creation time.
+	if err := to.Delete(ctx, existing); err != nil {
+		return false, err
+	}
+	return true, to.Save(ctx, &copied)
+}
+
+// newer reports whether a was changed after b, at the precision of
+// fingerprint.
+func newer(a, b *Task) bool {
+	return a.UpdatedAt.Truncate(time.Second).After(b.UpdatedAt.Truncate(time.Second))
+}
+
+// fingerprint sums up the fields of t at the precision every backend can
+// store, which is whole seconds for CSV. Tasks that read back with the same
+// fingerprint were copied faithfully.
+func fingerprint(t *Task) [sha256.Size]byte {
+	second := func(x time.Time) int64 {
+		return x.Truncate(time.Second).Unix()
+	}
+
+	h := sha256.New()
+	fmt.Fprintf(h, "%s\x00%q\x00%q\x00%q\x00%q\x00%s\x00%d\x00%q",
+		t.ID, t.Description, t.Priority, t.Project, strings.Join(t.Tags, "\x00"), t.Status, t.Estimate, FormatAnnotations(t.Annotations))
+	for _, x := range []time.Time{t.CreatedAt, t.UpdatedAt, t.DueDate, t.CompletedAt, t.DeletedAt} {
+		if x.IsZero() {
+			fmt.Fprint(h, "\x00-")
+			continue
+		}
+		fmt.Fprintf(h, "\x00%d", second(x))
+	}
+
+	var sum [sha256.Size]byte
+	h.Sum(sum[:0])
+	return sum
+}
@@ -0,0 +1,161 @@
+package task_test
+
+import (
+	"context"
+	"errors"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/ncfex/tasks/internal/storage/csv"
+	jsonstore "github.com/ncfex/tasks/internal/storage/json"
+	"github.com/ncfex/tasks/internal/storage/sqlite"
+	"github.com/ncfex/tasks/internal/task"
+)
+
+func newSQLiteRepository(t *testing.T) task.Repository {
+	t.Helper()
+	repo, err := sqlite.NewRepository(context.Background(), filepath.Join(t.TempDir(), "tasks.db"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return repo
+}
+
+// migrationSource returns a JSON store holding a task that was edited, one
+// in the trash and one done, numbered with a gap.
+func migrationSource(t *testing.T) (task.Repository, []task.Task) {
+	t.Helper()
+	ctx := context.Background()
+	repo := jsonstore.NewRepository(filepath.Join(t.TempDir(), "tasks.json"))
+
+	created := time.Date(2024, 3, 1, 9, 0, 0, 0, time.UTC)
+	tasks := []task.Task{
+		{Num: 1, Description: "edited", Status: task.StatusInProgress, CreatedAt: created, UpdatedAt: created.Add(48 * time.Hour), Estimate: 2 * time.Hour, Priority: "A", Project: "home", Tags: []string{"chores"},
+			Annotations: []task.Annotation{{CreatedAt: created.Add(time.Hour), Text: "ask about the lease"}}},
+		{Num: 4, Description: "trashed", Status: task.StatusTodo, CreatedAt: created, UpdatedAt: created.Add(time.Hour), DeletedAt: created.Add(time.Hour)},
+		{Num: 7, Description: "done", Status: task.StatusDone, CreatedAt: created, UpdatedAt: created.Add(3 * time.Hour), CompletedAt: created.Add(3 * time.Hour), DueDate: created.Add(24 * time.Hour)},
+	}
+	for i := range tasks {
+		tasks[i].ID = uuid.New()
+		if err := repo.Save(ctx, &tasks[i]); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// Two edits bump the version of the first task.
+	for i := 0; i < 2; i++ {
+		tasks[0].UpdatedAt = tasks[0].UpdatedAt.Add(time.Hour)
+		if err := repo.Update(ctx, &tasks[0]); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	return repo, tasks
+}
+
+func TestMigrateTasksAcrossBackends(t *testing.T) {
+	ctx := context.Background()
+	source, want := migrationSource(t)
+
+	targets := []struct {
+		name string
+		repo task.Repository
+	}{
+		{"sqlite", newSQLiteRepository(t)},
+		{"csv", csv.NewRepository(filepath.Join(t.TempDir(), "tasks.csv"))},
+	}
+
+	// Each target is migrated from the one before, so the copy goes
+	// json -> sqlite -> csv.
+	from := source
+	for _, target := range targets {
+		result, err := task.MigrateTasks(ctx, from, target.repo, task.MigrateOptions{})
+		if err != nil {
+			t.Fatalf("migrate to %s: %v", target.name, err)
+		}
+		if result.Read != len(want) || result.Created != len(want) || result.Verified != len(want) {
+			t.Errorf("migrate to %s = %+v, want %d read, created and verified", target.name, result, len(want))
+		}
+
+		for _, w := range want {
+			got, err := target.repo.GetByID(ctx, w.ID)
+			if err != nil {
+				t.Fatalf("%s: GetByID(%s): %v", target.name, w.Description, err)
+			}
+			if got.Num != w.Num || got.Version != w.Version {
+				t.Errorf("%s: %s is #%d version %d, want #%d version %d", target.name, w.Description, got.Num, got.Version, w.Num, w.Version)
+			}
+			if !got.UpdatedAt.Equal(w.UpdatedAt) || !got.DeletedAt.Equal(w.DeletedAt) || !got.CompletedAt.Equal(w.CompletedAt) {
+				t.Errorf("%s: %s has updated %v, deleted %v, completed %v, want %v, %v, %v", target.name, w.Description,
+					got.UpdatedAt, got.DeletedAt, got.CompletedAt, w.UpdatedAt, w.DeletedAt, w.CompletedAt)
+			}
+		}
+		from = target.repo
+	}
+}
+
+// A migration that conflicts writes nothing, even for the tasks read before
+// the conflict.
+func TestMigrateTasksConflictWritesNothing(t *testing.T) {
+	ctx := context.Background()
+	source, tasks := migrationSource(t)
+	target := newSQLiteRepository(t)
+
+	existing := tasks[2]
+	if err := target.Save(ctx, &existing); err != nil {
+		t.Fatal(err)
+	}
+
+	result, err := task.MigrateTasks(ctx, source, target, task.MigrateOptions{OnConflict: task.ConflictFail})
+	if !errors.Is(err, task.ErrConflict) {
+		t.Fatalf("MigrateTasks() error = %v, want ErrConflict", err)
+	}
+	if len(result.Conflicts) != 1 || result.Conflicts[0] != existing.ID || result.Created != 0 {
+		t.Errorf("result = %+v, want one conflict and nothing created", result)
+	}
+
+	stored, err := target.List(ctx, &task.TaskSelector{}, &task.TaskFilter{IncludeCompleted: true, Trash: task.TrashInclude})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(stored) != 1 {
+		t.Errorf("target has %d tasks, want only the one it had", len(stored))
+	}
+}
+
+func TestMigrateTasksSkipAndOverwrite(t *testing.T) {
+	ctx := context.Background()
+	source, tasks := migrationSource(t)
+	target := newSQLiteRepository(t)
+
+	stale := tasks[0]
+	stale.Description = "stale copy"
+	stale.Num = 0
+	if err := target.Save(ctx, &stale); err != nil {
+		t.Fatal(err)
+	}
+
+	result, err := task.MigrateTasks(ctx, source, target, task.MigrateOptions{OnConflict: task.ConflictSkip})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if result.Created != 2 || result.Skipped != 1 {
+		t.Errorf("skip: result = %+v, want 2 created and 1 skipped", result)
+	}
+	if got, _ := target.GetByID(ctx, stale.ID); got.Description != "stale copy" {
+		t.Errorf("skip: description = %q, want the stored one kept", got.Description)
+	}
+
+	result, err = task.MigrateTasks(ctx, source, target, task.MigrateOptions{OnConflict: task.ConflictOverwrite})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if result.Overwritten != 3 || result.Verified != 3 {
+		t.Errorf("overwrite: result = %+v, want 3 overwritten and verified", result)
+	}
+	if got, _ := target.GetByID(ctx, stale.ID); got.Description != "edited" {
+		t.Errorf("overwrite: description = %q, want the source's", got.Description)
+	}
+}
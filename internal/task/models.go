@@ -1,8 +1,11 @@
 package task
 
 import (
-	"errors"
+	"encoding/json"
+	"fmt"
+	"strings"
 	"time"
+	"unicode"
 
 	"github.com/google/uuid"
 )
@@ -11,18 +14,53 @@ type TaskField string
 
 const (
 	TaskFieldID          TaskField = "id"
+	TaskFieldNum         TaskField = "num"
 	TaskFieldDescription TaskField = "description"
 	TaskFieldIsCompleted TaskField = "is_completed"
+	TaskFieldStatus      TaskField = "status"
 	TaskFieldCreatedAt   TaskField = "created_at"
 	TaskFieldDueDate     TaskField = "due_date"
+	TaskFieldEstimate    TaskField = "estimate"
+	TaskFieldCompletedAt TaskField = "completed_at"
+	TaskFieldDeletedAt   TaskField = "deleted_at"
+	TaskFieldUpdatedAt   TaskField = "updated_at"
+	TaskFieldPriority    TaskField = "priority"
+	TaskFieldProject     TaskField = "project"
+	TaskFieldTags        TaskField = "tags"
+	TaskFieldAnnotations TaskField = "annotations"
 )
 
+// Task is a single todo item. Num is a short sequential number assigned by
+// the repository when the task is first saved; it is unique within a store
+// but, unlike ID, not across stores. Version counts the updates made to the
+// task and guards against lost updates: Update only succeeds if it still
+// matches the stored task, and increments it. UpdatedAt is set by
+// TaskService whenever it changes the task.
+//
+// Priority is a letter from A (highest) to Z, or empty. Project and Tags
+// are single words used to group tasks, like +project and @context in
+// todo.txt. Annotations are timestamped notes, as Taskwarrior keeps them.
 type Task struct {
-	ID          uuid.UUID `json:"id"`
-	Description string    `json:"description"`
-	IsCompleted bool      `json:"is_completed"`
-	CreatedAt   time.Time `json:"created_at"`
-	DueDate     time.Time `json:"due_date"`
+	ID          uuid.UUID     `json:"id"`
+	Num         int           `json:"num,omitempty"`
+	Description string        `json:"description"`
+	Status      Status        `json:"status"`
+	CreatedAt   time.Time     `json:"created_at"`
+	UpdatedAt   time.Time     `json:"updated_at"`
+	DueDate     time.Time     `json:"due_date"`
+	Estimate    time.Duration `json:"estimate,omitempty"`
+	CompletedAt time.Time     `json:"completed_at"`
+	DeletedAt   time.Time     `json:"deleted_at"`
+	Version     int           `json:"version,omitempty"`
+	Priority    string        `json:"priority,omitempty"`
+	Project     string        `json:"project,omitempty"`
+	Tags        []string      `json:"tags,omitempty"`
+	Annotations []Annotation  `json:"annotations,omitempty"`
+}
+
+type Annotation struct {
+	CreatedAt time.Time `json:"created_at"`
+	Text      string    `json:"text"`
 }
 
 type TaskSelector struct {
@@ -31,8 +69,27 @@ type TaskSelector struct {
 
 type TaskFilter struct {
 	IncludeCompleted bool
+	Statuses         []Status
+	Trash            TrashFilter
+
+	// Search keeps tasks whose description contains it, ignoring case.
+	Search string
+	// DueBefore and DueAfter, when set, bound the due date.
+	DueBefore time.Time
+	DueAfter  time.Time
+	// ChangedSince, when set, keeps tasks updated at or after it.
+	ChangedSince time.Time
 }
 
+// TrashFilter controls whether soft-deleted tasks are part of a listing.
+type TrashFilter int
+
+const (
+	TrashExclude TrashFilter = iota
+	TrashOnly
+	TrashInclude
+)
+
 func NewTaskSelector(fields ...TaskField) *TaskSelector {
 	selector := &TaskSelector{
 		Fields: make(map[TaskField]bool),
@@ -58,9 +115,156 @@ func NewTaskFilter() *TaskFilter {
 	}
 }
 
+// UnmarshalJSON reads tasks written before statuses existed, mapping the
+// legacy is_completed flag onto StatusDone or StatusTodo.
+func (t *Task) UnmarshalJSON(data []byte) error {
+	type plain Task
+	aux := struct {
+		*plain
+		IsCompleted *bool `json:"is_completed"`
+	}{plain: (*plain)(t)}
+
+	if err := json.Unmarshal(data, &aux); err != nil {
+		return err
+	}
+
+	if t.Status == "" {
+		t.Status = StatusTodo
+		if aux.IsCompleted != nil && *aux.IsCompleted {
+			t.Status = StatusDone
+		}
+	}
+	t.FillUpdatedAt()
+
+	return nil
+}
+
+// FillUpdatedAt sets a missing UpdatedAt, as on tasks stored before it was
+// tracked, to the latest time recorded on the task.
+func (t *Task) FillUpdatedAt() {
+	if !t.UpdatedAt.IsZero() {
+		return
+	}
+
+	t.UpdatedAt = t.CreatedAt
+	for _, at := range []time.Time{t.CompletedAt, t.DeletedAt} {
+		if at.After(t.UpdatedAt) {
+			t.UpdatedAt = at
+		}
+	}
+}
+
+func (t *Task) IsCompleted() bool {
+	return t.Status == StatusDone
+}
+
+func (t *Task) IsDeleted() bool {
+	return !t.DeletedAt.IsZero()
+}
+
+// Matches reports whether the task passes the filter. Explicitly requested
+// statuses take precedence over IncludeCompleted.
+func (f *TaskFilter) Matches(t *Task) bool {
+	if f.Search != "" && !strings.Contains(strings.ToLower(t.Description), strings.ToLower(f.Search)) {
+		return false
+	}
+	if !f.DueBefore.IsZero() && !t.DueDate.Before(f.DueBefore) {
+		return false
+	}
+	if !f.DueAfter.IsZero() && !t.DueDate.After(f.DueAfter) {
+		return false
+	}
+	if !f.ChangedSince.IsZero() && t.UpdatedAt.Before(f.ChangedSince) {
+		return false
+	}
+
+	switch f.Trash {
+	case TrashExclude:
+		if t.IsDeleted() {
+			return false
+		}
+	case TrashOnly:
+		if !t.IsDeleted() {
+			return false
+		}
+	}
+
+	if len(f.Statuses) > 0 {
+		for _, s := range f.Statuses {
+			if t.Status == s {
+				return true
+			}
+		}
+		return false
+	}
+
+	return f.IncludeCompleted || !t.Status.IsClosed()
+}
+
 func (t *Task) Validate() error {
 	if t.Description == "" {
-		return errors.New("task description cannot be empty")
+		return &ValidationError{Field: "description", Reason: "cannot be empty"}
+	}
+	if !t.Status.IsValid() {
+		return &ValidationError{Field: "status", Reason: fmt.Sprintf("unknown status %q", t.Status)}
+	}
+	if t.Estimate < 0 {
+		return &ValidationError{Field: "estimate", Reason: "cannot be negative"}
+	}
+	if !ValidPriority(t.Priority) {
+		return &ValidationError{Field: "priority", Reason: fmt.Sprintf("%q is not a letter from A to Z", t.Priority)}
+	}
+	if strings.ContainsFunc(t.Project, unicode.IsSpace) {
+		return &ValidationError{Field: "project", Reason: "cannot contain spaces"}
+	}
+	for _, tag := range t.Tags {
+		if tag == "" || strings.ContainsFunc(tag, unicode.IsSpace) {
+			return &ValidationError{Field: "tags", Reason: fmt.Sprintf("invalid tag %q", tag)}
+		}
 	}
 	return nil
 }
+
+// ValidPriority reports whether p is empty or a letter from A to Z.
+func ValidPriority(p string) bool {
+	return p == "" || len(p) == 1 && p[0] >= 'A' && p[0] <= 'Z'
+}
+
+// FormatTags joins tags with spaces, the way the storage backends keep
+// them in a single column.
+func FormatTags(tags []string) string {
+	return strings.Join(tags, " ")
+}
+
+// ParseTags splits a value written by FormatTags. It returns nil rather
+// than an empty slice when there are no tags.
+func ParseTags(s string) []string {
+	tags := strings.Fields(s)
+	if len(tags) == 0 {
+		return nil
+	}
+	return tags
+}
+
+// FormatAnnotations encodes annotations as JSON, the way the storage
+// backends keep them in a single column, or returns "" if there are none.
+func FormatAnnotations(annotations []Annotation) string {
+	if len(annotations) == 0 {
+		return ""
+	}
+	data, _ := json.Marshal(annotations)
+	return string(data)
+}
+
+// ParseAnnotations decodes a value written by FormatAnnotations.
+func ParseAnnotations(s string) ([]Annotation, error) {
+	if s == "" {
+		return nil, nil
+	}
+
+	var annotations []Annotation
+	if err := json.Unmarshal([]byte(s), &annotations); err != nil {
+		return nil, fmt.Errorf("invalid annotations: %w", err)
+	}
+	return annotations, nil
+}
@@ -0,0 +1,52 @@
+package task
+
+import (
+	"strconv"
+	"strings"
+)
+
+// ParseNum parses a task number written as "#42".
+func ParseNum(s string) (int, bool) {
+	digits, ok := strings.CutPrefix(s, "#")
+	if !ok {
+		return 0, false
+	}
+
+	num, err := strconv.Atoi(digits)
+	if err != nil || num <= 0 {
+		return 0, false
+	}
+
+	return num, true
+}
+
+// ParseNumRange parses a range of task numbers written as "#3-7" or
+// "#3-#7".
+func ParseNumRange(s string) (from, to int, ok bool) {
+	start, end, found := strings.Cut(s, "-")
+	if !found {
+		return 0, 0, false
+	}
+	if !strings.HasPrefix(end, "#") {
+		end = "#" + end
+	}
+
+	from, ok = ParseNum(start)
+	if !ok {
+		return 0, 0, false
+	}
+	to, ok = ParseNum(end)
+	if !ok || to < from {
+		return 0, 0, false
+	}
+
+	return from, to, true
+}
+
+// FormatNum returns num as "#42", or "-" for a task without a number.
+func FormatNum(num int) string {
+	if num <= 0 {
+		return "-"
+	}
+	return "#" + strconv.Itoa(num)
+}
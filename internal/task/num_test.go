@@ -0,0 +1,64 @@
+package task_test
+
+import (
+	"testing"
+
+	"github.com/ncfex/tasks/internal/task"
+)
+
+func TestParseNum(t *testing.T) {
+	tests := []struct {
+		in   string
+		want int
+		ok   bool
+	}{
+		{"#1", 1, true},
+		{"#42", 42, true},
+		{"42", 0, false},
+		{"#0", 0, false},
+		{"#-1", 0, false},
+		{"#x", 0, false},
+		{"#", 0, false},
+	}
+
+	for _, tt := range tests {
+		got, ok := task.ParseNum(tt.in)
+		if got != tt.want || ok != tt.ok {
+			t.Errorf("ParseNum(%q) = %d, %v, want %d, %v", tt.in, got, ok, tt.want, tt.ok)
+		}
+	}
+}
+
+func TestParseNumRange(t *testing.T) {
+	tests := []struct {
+		in       string
+		from, to int
+		ok       bool
+	}{
+		{"#3-7", 3, 7, true},
+		{"#3-#7", 3, 7, true},
+		{"#5-5", 5, 5, true},
+		{"#7-3", 0, 0, false},
+		{"3-7", 0, 0, false},
+		{"#3", 0, 0, false},
+		{"#3-", 0, 0, false},
+		{"#3-x", 0, 0, false},
+		{"abcd-ef", 0, 0, false},
+	}
+
+	for _, tt := range tests {
+		from, to, ok := task.ParseNumRange(tt.in)
+		if from != tt.from || to != tt.to || ok != tt.ok {
+			t.Errorf("ParseNumRange(%q) = %d, %d, %v, want %d, %d, %v", tt.in, from, to, ok, tt.from, tt.to, tt.ok)
+		}
+	}
+}
+
+func TestFormatNum(t *testing.T) {
+	if got := task.FormatNum(42); got != "#42" {
+		t.Errorf("FormatNum(42) = %q", got)
+	}
+	if got := task.FormatNum(0); got != "-" {
+		t.Errorf("FormatNum(0) = %q", got)
+	}
+}
@@ -0,0 +1,90 @@
+package task
+
+import (
+	"sort"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// EstimateEntry compares the estimate of a completed task with the time it
+// took from its first move to in_progress until completion. FromCreation
+// is set when the task was never seen in progress, in which case Actual is
+// its lead time from creation instead.
+type EstimateEntry struct {
+	Task         Task
+	Estimate     time.Duration
+	Actual       time.Duration
+	FromCreation bool
+}
+
+// Ratio reports actual time over estimated time, so 1.5 means the task
+// took 50% longer than planned.
+func (e EstimateEntry) Ratio() float64 {
+	return float64(e.Actual) / float64(e.Estimate)
+}
+
+type EstimateReport struct {
+	Entries        []EstimateEntry
+	TotalEstimate  time.Duration
+	TotalActual    time.Duration
+	MedianRatio    float64
+	SkippedNoData  int
+	SkippedPending int
+}
+
+// NewEstimateReport compares estimates against the time taken by every
+// completed task that carries an estimate. started holds when tasks were
+// first moved to in_progress, as far as it is known.
+func NewEstimateReport(tasks []Task, started map[uuid.UUID]time.Time) *EstimateReport {
+	report := &EstimateReport{}
+
+	ratios := make([]float64, 0, len(tasks))
+	for _, t := range tasks {
+		if t.Estimate <= 0 {
+			report.SkippedNoData++
+			continue
+		}
+		if !t.IsCompleted() || t.CompletedAt.IsZero() {
+			report.SkippedPending++
+			continue
+		}
+
+		entry := EstimateEntry{Task: t, Estimate: t.Estimate}
+		start, ok := started[t.ID]
+		if !ok || start.After(t.CompletedAt) {
+			start = t.CreatedAt
+			entry.FromCreation = true
+		}
+		entry.Actual = t.CompletedAt.Sub(start)
+		report.Entries = append(report.Entries, entry)
+		report.TotalEstimate += entry.Estimate
+		report.TotalActual += entry.Actual
+		ratios = append(ratios, entry.Ratio())
+	}
+
+	if len(ratios) > 0 {
+		sort.Float64s(ratios)
+		mid := len(ratios) / 2
+		if len(ratios)%2 == 0 {
+			report.MedianRatio = (ratios[mid-1] + ratios[mid]) / 2
+		} else {
+			report.MedianRatio = ratios[mid]
+		}
+	}
+
+	return report
+}
+
+// firstStart returns when events first show the task moving to
+// in_progress.
+func firstStart(events []AuditEvent) (time.Time, bool) {
+	for _, event := range events {
+		for _, diff := range event.Diffs {
+			if diff.Field == TaskFieldStatus && diff.After == string(StatusInProgress) {
+				return event.RecordedAt, true
+			}
+		}
+	}
+	return time.Time{}, false
+}
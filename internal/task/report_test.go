@@ -0,0 +1,77 @@
+package task_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/ncfex/tasks/internal/task"
+)
+
+func TestEstimateReportMeasuresFromStart(t *testing.T) {
+	created := time.Date(2024, 5, 1, 9, 0, 0, 0, time.UTC)
+	started := created.Add(24 * time.Hour)
+	done := task.Task{
+		ID:          uuid.New(),
+		Status:      task.StatusDone,
+		CreatedAt:   created,
+		CompletedAt: started.Add(3 * time.Hour),
+		Estimate:    2 * time.Hour,
+	}
+	quick := task.Task{
+		ID:          uuid.New(),
+		Status:      task.StatusDone,
+		CreatedAt:   created,
+		CompletedAt: created.Add(time.Hour),
+		Estimate:    2 * time.Hour,
+	}
+	open := task.Task{ID: uuid.New(), Status: task.StatusTodo, CreatedAt: created, Estimate: time.Hour}
+	unestimated := task.Task{ID: uuid.New(), Status: task.StatusDone, CreatedAt: created, CompletedAt: created}
+
+	report := task.NewEstimateReport(
+		[]task.Task{done, quick, open, unestimated},
+		map[uuid.UUID]time.Time{done.ID: started},
+	)
+
+	if len(report.Entries) != 2 {
+		t.Fatalf("entries = %d, want 2", len(report.Entries))
+	}
+	if e := report.Entries[0]; e.Actual != 3*time.Hour || e.FromCreation {
+		t.Errorf("started task: Actual = %v, FromCreation = %v, want 3h from its start", e.Actual, e.FromCreation)
+	}
+	if e := report.Entries[1]; e.Actual != time.Hour || !e.FromCreation {
+		t.Errorf("unstarted task: Actual = %v, FromCreation = %v, want 1h lead time", e.Actual, e.FromCreation)
+	}
+	if report.SkippedPending != 1 || report.SkippedNoData != 1 {
+		t.Errorf("skipped = %d pending, %d without estimate, want 1 and 1", report.SkippedPending, report.SkippedNoData)
+	}
+	if report.MedianRatio != 1 {
+		t.Errorf("MedianRatio = %v, want 1 (1.5 and 0.5)", report.MedianRatio)
+	}
+}
+
+func TestServiceEstimateReportUsesAuditLog(t *testing.T) {
+	ctx := context.Background()
+	service, _ := newService(t)
+
+	created, err := service.Create(ctx, "write report", time.Now(), 2*time.Hour)
+	if err != nil {
+		t.Fatal(err)
+	}
+	id := created.ID.String()
+	if _, _, err := service.SetStatus(ctx, id, task.StatusInProgress); err != nil {
+		t.Fatal(err)
+	}
+	if _, _, err := service.Complete(ctx, id); err != nil {
+		t.Fatal(err)
+	}
+
+	report, err := service.EstimateReport(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(report.Entries) != 1 || report.Entries[0].FromCreation {
+		t.Fatalf("entries = %+v, want one measured from its start", report.Entries)
+	}
+}
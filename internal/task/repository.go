@@ -1,12 +1,65 @@
 package task
 
-import "github.com/google/uuid"
+import (
+	"context"
+	"sort"
+
+	"github.com/google/uuid"
+)
 
 type Repository interface {
-	Save(*Task) error
-	GetByID(id uuid.UUID) (*Task, error)
-	GetTaskByPartialId(id string) (*Task, error)
-	List(*TaskSelector, *TaskFilter) ([]Task, error)
-	Update(*Task) error
-	Delete(*Task) error
+	Save(ctx context.Context, task *Task) error
+	GetByID(ctx context.Context, id uuid.UUID) (*Task, error)
+	GetTaskByPartialId(ctx context.Context, id string) (*Task, error)
+	GetByNum(ctx context.Context, num int) (*Task, error)
+	List(ctx context.Context, selector *TaskSelector, filter *TaskFilter) ([]Task, error)
+	Update(ctx context.Context, task *Task) error
+	Delete(ctx context.Context, task *Task) error
+}
+
+// Transactor is implemented by repositories that can run several operations
+// as one unit of work. WithTx calls fn with a Repository whose changes are
+// kept only if fn returns nil. fn must only use that Repository, since the
+// original one may be locked until WithTx returns.
+type Transactor interface {
+	WithTx(ctx context.Context, fn func(Repository) error) error
+}
+
+// Walker is implemented by repositories that can hand out tasks a page at a
+// time rather than all at once. Walk calls fn with each task filter
+// matches, in order of task number, and stops at the first error.
+type Walker interface {
+	Walk(ctx context.Context, filter *TaskFilter, fn func(*Task) error) error
+}
+
+// Walk calls fn with each task of repo that filter matches, in order of task
+// number. Repositories that are not Walkers are read with List.
+func Walk(ctx context.Context, repo Repository, filter *TaskFilter, fn func(*Task) error) error {
+	if walker, ok := repo.(Walker); ok {
+		return walker.Walk(ctx, filter, fn)
+	}
+
+	tasks, err := repo.List(ctx, &TaskSelector{}, filter)
+	if err != nil {
+		return err
+	}
+	sort.SliceStable(tasks, func(i, j int) bool {
+		return tasks[i].Num < tasks[j].Num
+	})
+
+	for i := range tasks {
+		if err := fn(&tasks[i]); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// withTx runs fn as one unit of work if repo supports it, and directly
+// against repo otherwise.
+func withTx(ctx context.Context, repo Repository, fn func(Repository) error) error {
+	if transactor, ok := repo.(Transactor); ok {
+		return transactor.WithTx(ctx, fn)
+	}
+	return fn(repo)
 }
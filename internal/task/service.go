@@ -1,66 +1,147 @@
 package task
 
 import (
+	"context"
+	"fmt"
 	"time"
 
 	"github.com/google/uuid"
 )
 
 type TaskService interface {
-	Create(description string, dueDate time.Time) (*Task, error)
-	GetByID(id uuid.UUID) (*Task, error)
-	GetTaskByPartialId(id string) (*Task, error)
-	List(selector *TaskSelector, filter *TaskFilter) ([]Task, error)
-	Complete(id string) error
-	Delete(id string) error
+	Create(ctx context.Context, description string, dueDate time.Time, estimate time.Duration) (*Task, error)
+	GetByID(ctx context.Context, id uuid.UUID) (*Task, error)
+	GetTaskByPartialId(ctx context.Context, id string) (*Task, error)
+	List(ctx context.Context, selector *TaskSelector, filter *TaskFilter) ([]Task, error)
+	Complete(ctx context.Context, id string) (*Task, bool, error)
+	SetStatus(ctx context.Context, id string, status Status) (*Task, bool, error)
+	Delete(ctx context.Context, id string) error
+	Restore(ctx context.Context, id string) (*Task, error)
+	Purge(ctx context.Context, deletedBefore time.Time) (int, error)
+	Undo(ctx context.Context) (*JournalEntry, error)
+	Redo(ctx context.Context) (*JournalEntry, error)
+	History(ctx context.Context, id string) ([]AuditEvent, error)
+	EstimateReport(ctx context.Context) (*EstimateReport, error)
+	Select(ctx context.Context, refs []string, filter *TaskFilter) ([]Task, error)
+	Batch(ctx context.Context, ids []uuid.UUID, action BatchAction) (*BatchResult, error)
+	Check(ctx context.Context) ([]Problem, error)
+	Repair(ctx context.Context) ([]Problem, error)
 }
 
 type service struct {
-	repository Repository
+	repository  Repository
+	transitions Transitions
+	journal     Journal
+	auditLog    AuditLog
+	user        string
+	timeout     time.Duration
 }
 
-func NewService(repository Repository) TaskService {
-	return &service{
-		repository: repository,
+type ServiceOption func(*service)
+
+// WithTransitions replaces the default status workflow.
+func WithTransitions(transitions Transitions) ServiceOption {
+	return func(s *service) {
+		s.transitions = transitions
+	}
+}
+
+// WithJournal records every mutation so it can be undone and redone.
+func WithJournal(journal Journal) ServiceOption {
+	return func(s *service) {
+		s.journal = journal
+	}
+}
+
+// WithAuditLog records the change history of every task, attributed to user.
+func WithAuditLog(auditLog AuditLog, user string) ServiceOption {
+	return func(s *service) {
+		s.auditLog = auditLog
+		s.user = user
+	}
+}
+
+// WithTimeout bounds every call to the service, and the storage operations
+// it makes, to timeout. Time spent between calls, such as waiting for the
+// user, does not count.
+func WithTimeout(timeout time.Duration) ServiceOption {
+	return func(s *service) {
+		s.timeout = timeout
+	}
+}
+
+func NewService(repository Repository, opts ...ServiceOption) TaskService {
+	s := &service{
+		repository:  repository,
+		transitions: DefaultTransitions(),
+	}
+
+	for _, opt := range opts {
+		opt(s)
 	}
+
+	return s
 }
 
-func (s *service) Create(description string, dueDate time.Time) (*Task, error) {
+func (s *service) Create(ctx context.Context, description string, dueDate time.Time, estimate time.Duration) (*Task, error) {
+	ctx, cancel := s.bound(ctx)
+	defer cancel()
+
+	now := time.Now()
 	task := &Task{
 		Description: description,
-		IsCompleted: false,
-		CreatedAt:   time.Now(),
+		Status:      StatusTodo,
+		CreatedAt:   now,
+		UpdatedAt:   now,
 		DueDate:     dueDate,
+		Estimate:    estimate,
 	}
 
 	if err := task.Validate(); err != nil {
 		return nil, &Error{Op: "Create", Err: err}
 	}
 
-	if err := s.repository.Save(task); err != nil {
+	err := s.withTx(ctx, func(repo Repository) error {
+		if err := repo.Save(ctx, task); err != nil {
+			return err
+		}
+
+		after := *task
+		return s.record(ctx, repo, ActionCreate, fmt.Sprintf("add %q", task.Description), Change{After: &after})
+	})
+	if err != nil {
 		return nil, &Error{Op: "Create", Err: err}
 	}
 
 	return task, nil
 }
 
-func (s *service) GetByID(id uuid.UUID) (*Task, error) {
-	task, err := s.repository.GetByID(id)
+func (s *service) GetByID(ctx context.Context, id uuid.UUID) (*Task, error) {
+	ctx, cancel := s.bound(ctx)
+	defer cancel()
+
+	task, err := s.repository.GetByID(ctx, id)
 	if err != nil {
 		return nil, &Error{Op: "GetByID", Err: err}
 	}
 	return task, nil
 }
 
-func (s *service) GetTaskByPartialId(id string) (*Task, error) {
-	task, err := s.repository.GetTaskByPartialId(id)
+func (s *service) GetTaskByPartialId(ctx context.Context, id string) (*Task, error) {
+	ctx, cancel := s.bound(ctx)
+	defer cancel()
+
+	task, err := lookup(ctx, s.repository, id)
 	if err != nil {
 		return nil, &Error{Op: "GetByID", Err: err}
 	}
 	return task, nil
 }
 
-func (s *service) List(selector *TaskSelector, filter *TaskFilter) ([]Task, error) {
+func (s *service) List(ctx context.Context, selector *TaskSelector, filter *TaskFilter) ([]Task, error) {
+	ctx, cancel := s.bound(ctx)
+	defer cancel()
+
 	if selector == nil {
 		selector = NewTaskSelector()
 	}
@@ -68,36 +149,273 @@ func (s *service) List(selector *TaskSelector, filter *TaskFilter) ([]Task, erro
 		filter = NewTaskFilter()
 	}
 
-	tasks, err := s.repository.List(selector, filter)
+	tasks, err := s.repository.List(ctx, selector, filter)
 	if err != nil {
 		return nil, &Error{Op: "List", Err: err}
 	}
 	return tasks, nil
 }
 
-func (s *service) Complete(id string) error {
-	task, err := s.repository.GetTaskByPartialId(id)
+// Complete marks the task as done. It reports whether the task changed,
+// which it does not if it was done already.
+func (s *service) Complete(ctx context.Context, id string) (*Task, bool, error) {
+	return s.setStatus(ctx, "Complete", ActionComplete, id, StatusDone)
+}
+
+// SetStatus moves the task to status. It reports whether the task changed,
+// which it does not if it was in status already.
+func (s *service) SetStatus(ctx context.Context, id string, status Status) (*Task, bool, error) {
+	return s.setStatus(ctx, "SetStatus", ActionUpdate, id, status)
+}
+
+func (s *service) setStatus(ctx context.Context, op string, action Action, id string, status Status) (*Task, bool, error) {
+	ctx, cancel := s.bound(ctx)
+	defer cancel()
+
+	var task *Task
+	var changed bool
+	err := s.withTx(ctx, func(repo Repository) error {
+		var err error
+		if task, err = findActive(ctx, repo, id); err != nil {
+			return err
+		}
+
+		before := *task
+		if changed = task.Status != status; !changed {
+			return nil
+		}
+		now := time.Now()
+		if err := s.changeStatus(task, status, now); err != nil {
+			return err
+		}
+		task.UpdatedAt = now
+		if err := repo.Update(ctx, task); err != nil {
+			return err
+		}
+
+		after := *task
+		summary := fmt.Sprintf("set %s to %s", shortID(task), status)
+		return s.record(ctx, repo, action, summary, Change{Before: &before, After: &after})
+	})
 	if err != nil {
-		return &Error{Op: "Complete", Err: err}
+		return nil, false, &Error{Op: op, Err: err}
+	}
+
+	return task, changed, nil
+}
+
+// changeStatus moves t to status if the workflow allows it.
+func (s *service) changeStatus(t *Task, status Status, now time.Time) error {
+	if !s.transitions.Allows(t.Status, status) {
+		return &TransitionError{ID: shortID(t), From: t.Status, To: status, Allowed: s.transitions[t.Status]}
 	}
 
-	task.IsCompleted = true
-	if err := s.repository.Update(task); err != nil {
-		return &Error{Op: "Complete", Err: err}
+	t.Status = status
+	if status == StatusDone {
+		t.CompletedAt = now
+	} else {
+		t.CompletedAt = time.Time{}
 	}
 
 	return nil
 }
 
-func (s *service) Delete(id string) error {
-	task, err := s.repository.GetTaskByPartialId(id)
+// Delete moves the task to the trash. Trashed tasks are hidden from listings
+// until they are restored or purged.
+func (s *service) Delete(ctx context.Context, id string) error {
+	ctx, cancel := s.bound(ctx)
+	defer cancel()
+
+	err := s.withTx(ctx, func(repo Repository) error {
+		task, err := findActive(ctx, repo, id)
+		if err != nil {
+			return err
+		}
+
+		before := *task
+		task.DeletedAt = time.Now()
+		task.UpdatedAt = task.DeletedAt
+		if err := repo.Update(ctx, task); err != nil {
+			return err
+		}
+
+		after := *task
+		return s.record(ctx, repo, ActionDelete, "delete "+shortID(task), Change{Before: &before, After: &after})
+	})
 	if err != nil {
 		return &Error{Op: "Delete", Err: err}
 	}
 
-	if err := s.repository.Delete(task); err != nil {
-		return &Error{Op: "Delete", Err: err}
+	return nil
+}
+
+func (s *service) Restore(ctx context.Context, id string) (*Task, error) {
+	ctx, cancel := s.bound(ctx)
+	defer cancel()
+
+	var task *Task
+	err := s.withTx(ctx, func(repo Repository) error {
+		var err error
+		if task, err = lookup(ctx, repo, id); err != nil {
+			return err
+		}
+
+		if !task.IsDeleted() {
+			return ErrTaskNotInTrash
+		}
+
+		before := *task
+		task.DeletedAt = time.Time{}
+		task.UpdatedAt = time.Now()
+		if err := repo.Update(ctx, task); err != nil {
+			return err
+		}
+
+		after := *task
+		return s.record(ctx, repo, ActionRestore, "restore "+shortID(task), Change{Before: &before, After: &after})
+	})
+	if err != nil {
+		return nil, &Error{Op: "Restore", Err: err}
 	}
 
-	return nil
+	return task, nil
+}
+
+// Purge permanently removes trashed tasks deleted before the given time and
+// returns how many were removed. Either every such task is removed or none.
+func (s *service) Purge(ctx context.Context, deletedBefore time.Time) (int, error) {
+	ctx, cancel := s.bound(ctx)
+	defer cancel()
+
+	var changes []Change
+	err := s.withTx(ctx, func(repo Repository) error {
+		changes = nil
+		trashed, err := repo.List(ctx, NewTaskSelector(), &TaskFilter{IncludeCompleted: true, Trash: TrashOnly})
+		if err != nil {
+			return err
+		}
+
+		for i := range trashed {
+			if !trashed[i].DeletedAt.Before(deletedBefore) {
+				continue
+			}
+			if err := repo.Delete(ctx, &trashed[i]); err != nil {
+				return err
+			}
+			changes = append(changes, Change{Before: &trashed[i]})
+		}
+
+		if len(changes) == 0 {
+			return nil
+		}
+		return s.record(ctx, repo, ActionPurge, fmt.Sprintf("purge %d task(s)", len(changes)), changes...)
+	})
+	if err != nil {
+		return 0, &Error{Op: "Purge", Err: err}
+	}
+
+	return len(changes), nil
+}
+
+// History returns the audit trail of a task. Tasks that were purged can
+// still be looked up by their full ID.
+func (s *service) History(ctx context.Context, id string) ([]AuditEvent, error) {
+	ctx, cancel := s.bound(ctx)
+	defer cancel()
+
+	if s.auditLog == nil {
+		return nil, &Error{Op: "History", Err: ErrAuditLogDisabled}
+	}
+
+	taskID, err := uuid.Parse(id)
+	if err != nil {
+		task, err := lookup(ctx, s.repository, id)
+		if err != nil {
+			return nil, &Error{Op: "History", Err: err}
+		}
+		taskID = task.ID
+	}
+
+	events, err := s.auditLog.History(ctx, taskID)
+	if err != nil {
+		return nil, &Error{Op: "History", Err: err}
+	}
+
+	return events, nil
+}
+
+// EstimateReport compares estimates with the time completed tasks took,
+// measured from their first move to in_progress in the audit log. Without
+// an audit log every task is measured from its creation.
+func (s *service) EstimateReport(ctx context.Context) (*EstimateReport, error) {
+	ctx, cancel := s.bound(ctx)
+	defer cancel()
+
+	tasks, err := s.repository.List(ctx, NewTaskSelector(), &TaskFilter{IncludeCompleted: true})
+	if err != nil {
+		return nil, &Error{Op: "EstimateReport", Err: err}
+	}
+
+	started := make(map[uuid.UUID]time.Time)
+	if s.auditLog != nil {
+		var ids []uuid.UUID
+		for _, t := range tasks {
+			if t.Estimate > 0 && t.IsCompleted() {
+				ids = append(ids, t.ID)
+			}
+		}
+
+		events, err := histories(ctx, s.auditLog, ids)
+		if err != nil {
+			return nil, &Error{Op: "EstimateReport", Err: err}
+		}
+		for _, id := range ids {
+			if start, ok := firstStart(events[id]); ok {
+				started[id] = start
+			}
+		}
+	}
+
+	return NewEstimateReport(tasks, started), nil
+}
+
+// bound limits ctx to the timeout of the service for a single call.
+func (s *service) bound(ctx context.Context) (context.Context, context.CancelFunc) {
+	if s.timeout <= 0 {
+		return ctx, func() {}
+	}
+	return context.WithTimeout(ctx, s.timeout)
+}
+
+// withTx runs fn as one unit of work if the repository supports it, and
+// directly against the repository otherwise.
+func (s *service) withTx(ctx context.Context, fn func(Repository) error) error {
+	return withTx(ctx, s.repository, fn)
+}
+
+// lookup resolves id, which is either a task number such as "#42" or a
+// prefix of a task ID.
+func lookup(ctx context.Context, repo Repository, id string) (*Task, error) {
+	if num, ok := ParseNum(id); ok {
+		return repo.GetByNum(ctx, num)
+	}
+	return repo.GetTaskByPartialId(ctx, id)
+}
+
+// findActive resolves id to a task that is not in the trash.
+func findActive(ctx context.Context, repo Repository, id string) (*Task, error) {
+	task, err := lookup(ctx, repo, id)
+	if err != nil {
+		return nil, err
+	}
+
+	if task.IsDeleted() {
+		return nil, &NotFoundError{ID: id}
+	}
+
+	return task, nil
+}
+
+func shortID(t *Task) string {
+	return t.ID.String()[0:8]
 }
@@ -0,0 +1,109 @@
+package task_test
+
+import (
+	"context"
+	"errors"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/ncfex/tasks/internal/storage/audit"
+	"github.com/ncfex/tasks/internal/storage/journal"
+	jsonstore "github.com/ncfex/tasks/internal/storage/json"
+	"github.com/ncfex/tasks/internal/task"
+)
+
+// newService returns a service with a journal and an audit log over an
+// empty JSON store.
+func newService(t *testing.T, opts ...task.ServiceOption) (task.TaskService, task.Repository) {
+	t.Helper()
+	dir := t.TempDir()
+	repo := jsonstore.NewRepository(filepath.Join(dir, "tasks.json"))
+	opts = append([]task.ServiceOption{
+		task.WithJournal(journal.NewJournal(filepath.Join(dir, "journal.json"))),
+		task.WithAuditLog(audit.NewAuditLog(filepath.Join(dir, "history.jsonl")), "tester"),
+	}, opts...)
+	return task.NewService(repo, opts...), repo
+}
+
+func TestChangesBumpUpdatedAt(t *testing.T) {
+	ctx := context.Background()
+	service, _ := newService(t)
+	createTasks(t, service, 3)
+	before := time.Now()
+	time.Sleep(10 * time.Millisecond)
+
+	if _, _, err := service.SetStatus(ctx, "#1", task.StatusInProgress); err != nil {
+		t.Fatal(err)
+	}
+	if err := service.Delete(ctx, "#2"); err != nil {
+		t.Fatal(err)
+	}
+	// A status the task already has changes nothing.
+	if _, changed, err := service.SetStatus(ctx, "#3", task.StatusTodo); err != nil || changed {
+		t.Fatalf("SetStatus(same) = %v, %v, want no change", changed, err)
+	}
+
+	changed, err := service.List(ctx, nil, &task.TaskFilter{IncludeCompleted: true, Trash: task.TrashInclude, ChangedSince: before})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := nums(changed); len(got) != 2 || got[0] != 1 || got[1] != 2 {
+		t.Errorf("changed since = %v, want [1 2]", got)
+	}
+	for _, tk := range changed {
+		if tk.UpdatedAt.Before(before) {
+			t.Errorf("#%d UpdatedAt = %s, want after %s", tk.Num, tk.UpdatedAt, before)
+		}
+	}
+
+	all, err := service.List(ctx, nil, &task.TaskFilter{IncludeCompleted: true, ChangedSince: before.Add(-time.Hour)})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := nums(all); len(got) != 2 {
+		t.Errorf("changed in the last hour = %v, want #1 and #3 outside the trash", got)
+	}
+}
+
+// deadlineRepository records the deadline of each List call.
+type deadlineRepository struct {
+	task.Repository
+	deadlines []time.Time
+}
+
+func (r *deadlineRepository) List(ctx context.Context, selector *task.TaskSelector, filter *task.TaskFilter) ([]task.Task, error) {
+	deadline, ok := ctx.Deadline()
+	if !ok {
+		return nil, errors.New("no deadline")
+	}
+	r.deadlines = append(r.deadlines, deadline)
+	return r.Repository.List(ctx, selector, filter)
+}
+
+func TestTimeoutAppliesToEachCall(t *testing.T) {
+	ctx := context.Background()
+	repo := &deadlineRepository{Repository: jsonstore.NewRepository(filepath.Join(t.TempDir(), "tasks.json"))}
+	service := task.NewService(repo, task.WithTimeout(time.Minute))
+
+	if _, err := service.List(ctx, nil, nil); err != nil {
+		t.Fatal(err)
+	}
+	time.Sleep(10 * time.Millisecond)
+	if _, err := service.List(ctx, nil, nil); err != nil {
+		t.Fatal(err)
+	}
+
+	if len(repo.deadlines) != 2 {
+		t.Fatalf("expected 2 calls, got %d", len(repo.deadlines))
+	}
+	if !repo.deadlines[1].After(repo.deadlines[0]) {
+		t.Errorf("expected the second call to get its own deadline, got %v and %v", repo.deadlines[0], repo.deadlines[1])
+	}
+
+	expired, cancel := context.WithDeadline(ctx, time.Now().Add(-time.Second))
+	defer cancel()
+	if _, err := service.List(expired, nil, nil); !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("expected the deadline of the caller to still apply, got %v", err)
+	}
+}
@@ -0,0 +1,55 @@
+package task
+
+import (
+	"sort"
+
+	"github.com/google/uuid"
+)
+
+// DefaultShortIDLength is the minimum length of the IDs shown in listings.
+const DefaultShortIDLength = 4
+
+// ShortIDs returns, for every task, the shortest prefix of its ID that no
+// other task in tasks starts with, like git's abbreviated commit hashes.
+// Prefixes are at least minLength characters long, or DefaultShortIDLength
+// when minLength is not positive.
+func ShortIDs(tasks []Task, minLength int) map[uuid.UUID]string {
+	if minLength <= 0 {
+		minLength = DefaultShortIDLength
+	}
+
+	ids := make([]string, len(tasks))
+	for i := range tasks {
+		ids[i] = tasks[i].ID.String()
+	}
+	sort.Strings(ids)
+
+	// After sorting, the longest prefix an ID shares with any other is the
+	// one it shares with a neighbour.
+	prefixes := make(map[uuid.UUID]string, len(ids))
+	for i, id := range ids {
+		length := minLength
+		if i > 0 {
+			length = max(length, commonPrefix(id, ids[i-1])+1)
+		}
+		if i < len(ids)-1 {
+			length = max(length, commonPrefix(id, ids[i+1])+1)
+		}
+		length = min(length, len(id))
+		if id[length-1] == '-' && length < len(id) {
+			length++
+		}
+
+		prefixes[uuid.MustParse(id)] = id[:length]
+	}
+
+	return prefixes
+}
+
+func commonPrefix(a, b string) int {
+	n := 0
+	for n < len(a) && n < len(b) && a[n] == b[n] {
+		n++
+	}
+	return n
+}
@@ -0,0 +1,57 @@
+package task_test
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/ncfex/tasks/internal/task"
+)
+
+func TestShortIDs(t *testing.T) {
+	tasks := []task.Task{
+		{ID: uuid.MustParse("abcd1234-0000-0000-0000-000000000000")},
+		{ID: uuid.MustParse("abcd1299-0000-0000-0000-000000000000")},
+		{ID: uuid.MustParse("abce0000-0000-0000-0000-000000000000")},
+		{ID: uuid.MustParse("ffff0000-0000-0000-0000-000000000000")},
+		{ID: uuid.MustParse("12345678-aaaa-0000-0000-000000000000")},
+		{ID: uuid.MustParse("12345678-bbbb-0000-0000-000000000000")},
+	}
+
+	want := map[string]string{
+		"abcd1234-0000-0000-0000-000000000000": "abcd123",
+		"abcd1299-0000-0000-0000-000000000000": "abcd129",
+		"abce0000-0000-0000-0000-000000000000": "abce",
+		"ffff0000-0000-0000-0000-000000000000": "ffff",
+		// A prefix never ends on a dash.
+		"12345678-aaaa-0000-0000-000000000000": "12345678-a",
+		"12345678-bbbb-0000-0000-000000000000": "12345678-b",
+	}
+
+	got := task.ShortIDs(tasks, 0)
+	for id, prefix := range want {
+		if got[uuid.MustParse(id)] != prefix {
+			t.Errorf("ShortIDs[%s] = %q, want %q", id, got[uuid.MustParse(id)], prefix)
+		}
+	}
+
+	// Every prefix picks out exactly one task.
+	for id, prefix := range got {
+		matches := 0
+		for i := range tasks {
+			if strings.HasPrefix(tasks[i].ID.String(), prefix) {
+				matches++
+			}
+		}
+		if matches != 1 {
+			t.Errorf("prefix %q of %s matches %d tasks", prefix, id, matches)
+		}
+	}
+}
+
+func TestShortIDsMinLength(t *testing.T) {
+	tasks := []task.Task{{ID: uuid.MustParse("abcd1234-0000-0000-0000-000000000000")}}
+	if got := task.ShortIDs(tasks, 8)[tasks[0].ID]; got != "abcd1234" {
+		t.Errorf("ShortIDs with minimum 8 = %q, want %q", got, "abcd1234")
+	}
+}
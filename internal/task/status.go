@@ -0,0 +1,91 @@
+package task
+
+import (
+	"fmt"
+	"strings"
+)
+
+type Status string
+
+const (
+	StatusTodo       Status = "todo"
+	StatusInProgress Status = "in_progress"
+	StatusWaiting    Status = "waiting"
+	StatusDone       Status = "done"
+	StatusCancelled  Status = "cancelled"
+)
+
+// Statuses lists every known status in workflow order.
+var Statuses = []Status{
+	StatusTodo,
+	StatusInProgress,
+	StatusWaiting,
+	StatusDone,
+	StatusCancelled,
+}
+
+// ParseStatus accepts a status name case-insensitively, with dashes or
+// spaces in place of underscores.
+func ParseStatus(s string) (Status, error) {
+	normalized := strings.ToLower(strings.TrimSpace(s))
+	normalized = strings.NewReplacer("-", "_", " ", "_").Replace(normalized)
+
+	for _, status := range Statuses {
+		if string(status) == normalized {
+			return status, nil
+		}
+	}
+
+	return "", fmt.Errorf("unknown status: %s", s)
+}
+
+func (s Status) IsValid() bool {
+	for _, status := range Statuses {
+		if status == s {
+			return true
+		}
+	}
+	return false
+}
+
+// IsClosed reports whether tasks in this status are finished one way or
+// another and should be hidden from the default task list.
+func (s Status) IsClosed() bool {
+	return s == StatusDone || s == StatusCancelled
+}
+
+// Transitions maps a status to the statuses a task may move to from it.
+type Transitions map[Status][]Status
+
+func DefaultTransitions() Transitions {
+	return Transitions{
+		StatusTodo:       {StatusInProgress, StatusWaiting, StatusDone, StatusCancelled},
+		StatusInProgress: {StatusTodo, StatusWaiting, StatusDone, StatusCancelled},
+		StatusWaiting:    {StatusTodo, StatusInProgress, StatusDone, StatusCancelled},
+		StatusDone:       {StatusTodo},
+		StatusCancelled:  {StatusTodo},
+	}
+}
+
+func (tr Transitions) Allows(from, to Status) bool {
+	for _, allowed := range tr[from] {
+		if allowed == to {
+			return true
+		}
+	}
+	return false
+}
+
+func (tr Transitions) Validate() error {
+	for from, targets := range tr {
+		if !from.IsValid() {
+			return fmt.Errorf("unknown status in transitions: %s", from)
+		}
+		for _, to := range targets {
+			if !to.IsValid() {
+				return fmt.Errorf("unknown status in transitions from %s: %s", from, to)
+			}
+		}
+	}
+	return nil
+}
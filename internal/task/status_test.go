@@ -0,0 +1,68 @@
+package task_test
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/ncfex/tasks/internal/task"
+)
+
+func TestDefaultTransitions(t *testing.T) {
+	tests := []struct {
+		from, to task.Status
+		want     bool
+	}{
+		{task.StatusTodo, task.StatusInProgress, true},
+		{task.StatusTodo, task.StatusDone, true},
+		{task.StatusInProgress, task.StatusWaiting, true},
+		{task.StatusWaiting, task.StatusCancelled, true},
+		{task.StatusDone, task.StatusTodo, true},
+		{task.StatusDone, task.StatusInProgress, false},
+		{task.StatusCancelled, task.StatusDone, false},
+		{task.StatusTodo, task.StatusTodo, false},
+	}
+
+	transitions := task.DefaultTransitions()
+	for _, tt := range tests {
+		if got := transitions.Allows(tt.from, tt.to); got != tt.want {
+			t.Errorf("Allows(%s, %s) = %v, want %v", tt.from, tt.to, got, tt.want)
+		}
+	}
+	if err := transitions.Validate(); err != nil {
+		t.Errorf("Validate: %v", err)
+	}
+}
+
+func TestTransitionsValidate(t *testing.T) {
+	for name, transitions := range map[string]task.Transitions{
+		"unknown source": {"blocked": {task.StatusTodo}},
+		"unknown target": {task.StatusTodo: {"blocked"}},
+	} {
+		if err := transitions.Validate(); err == nil {
+			t.Errorf("%s: Validate succeeded, want an error", name)
+		}
+	}
+}
+
+func TestParseStatus(t *testing.T) {
+	for _, name := range []string{"todo", "in_progress", "waiting", "done", "cancelled"} {
+		status, err := task.ParseStatus(name)
+		if err != nil || string(status) != name {
+			t.Errorf("ParseStatus(%q) = %q, %v", name, status, err)
+		}
+	}
+	if _, err := task.ParseStatus("blocked"); err == nil {
+		t.Error("ParseStatus(\"blocked\") succeeded, want an error")
+	}
+}
+
+func TestTransitionError(t *testing.T) {
+	err := error(&task.TransitionError{ID: "abcd1234", From: task.StatusDone, To: task.StatusWaiting, Allowed: []task.Status{task.StatusTodo}})
+	if !errors.Is(err, task.ErrInvalidTransition) {
+		t.Error("TransitionError is not ErrInvalidTransition")
+	}
+	want := "task abcd1234 cannot move from done to waiting (allowed: todo)"
+	if err.Error() != want {
+		t.Errorf("Error() = %q, want %q", err.Error(), want)
+	}
+}
@@ -0,0 +1,69 @@
+package task_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/ncfex/tasks/internal/task"
+)
+
+func TestRestoreAndPurge(t *testing.T) {
+	ctx := context.Background()
+	service, _ := newService(t)
+	tasks := createTasks(t, service, 3)
+	for _, ref := range []string{"#1", "#2"} {
+		if err := service.Delete(ctx, ref); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	trash := &task.TaskFilter{IncludeCompleted: true, Trash: task.TrashOnly}
+	trashed, err := service.List(ctx, nil, trash)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := nums(trashed); len(got) != 2 || got[0] != 1 || got[1] != 2 {
+		t.Fatalf("trash = %v, want [1 2]", got)
+	}
+	active, err := service.List(ctx, nil, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := nums(active); len(got) != 1 || got[0] != 3 {
+		t.Fatalf("List() = %v, want only #3 outside the trash", got)
+	}
+	if _, _, err := service.Complete(ctx, "#1"); !errors.Is(err, task.ErrTaskNotFound) {
+		t.Errorf("Complete(trashed) error = %v, want %v", err, task.ErrTaskNotFound)
+	}
+
+	restored, err := service.Restore(ctx, "#1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if restored.IsDeleted() || restored.ID != tasks[0].ID {
+		t.Errorf("Restore() = %+v, want #1 out of the trash", restored)
+	}
+	if _, err := service.Restore(ctx, "#3"); !errors.Is(err, task.ErrTaskNotInTrash) {
+		t.Errorf("Restore(active) error = %v, want %v", err, task.ErrTaskNotInTrash)
+	}
+
+	// Only tasks deleted before the cutoff are purged.
+	if n, err := service.Purge(ctx, time.Now().Add(-time.Hour)); err != nil || n != 0 {
+		t.Errorf("Purge(an hour ago) = %d, %v, want nothing purged", n, err)
+	}
+	n, err := service.Purge(ctx, time.Now().Add(time.Second))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if n != 1 {
+		t.Errorf("Purge() = %d, want 1", n)
+	}
+	if _, err := service.GetByID(ctx, tasks[1].ID); !errors.Is(err, task.ErrTaskNotFound) {
+		t.Errorf("GetByID(purged) error = %v, want %v", err, task.ErrTaskNotFound)
+	}
+	if _, err := service.GetByID(ctx, tasks[0].ID); err != nil {
+		t.Errorf("GetByID(restored): %v", err)
+	}
+}
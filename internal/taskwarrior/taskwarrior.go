@@ -0,0 +1,339 @@
+// Package taskwarrior reads and writes tasks in the JSON format of
+// Taskwarrior's `task export` and `task import`.
+//
+// Taskwarrior statuses map onto the closest task status: pending is todo,
+// in_progress once started, or waiting while its wait time is ahead;
+// completed is done and deleted is cancelled. Priorities H, M and L become
+// A, B and C, and annotations are kept as task annotations. The estimate is
+// kept as a user-defined attribute so that exported files import back
+// unchanged.
+package taskwarrior
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/ncfex/tasks/internal/task"
+	"github.com/ncfex/tasks/internal/utils"
+)
+
+const timeLayout = "20060102T150405Z"
+
+// record is a task as Taskwarrior exports it. Attributes this package does
+// not use, such as id and urgency, are ignored.
+type record struct {
+	UUID        string       `json:"uuid"`
+	Description string       `json:"description"`
+	Status      string       `json:"status"`
+	Entry       string       `json:"entry,omitempty"`
+	Modified    string       `json:"modified,omitempty"`
+	Start       string       `json:"start,omitempty"`
+	Due         string       `json:"due,omitempty"`
+	Wait        string       `json:"wait,omitempty"`
+	End         string       `json:"end,omitempty"`
+	Priority    string       `json:"priority,omitempty"`
+	Project     string       `json:"project,omitempty"`
+	Tags        []string     `json:"tags,omitempty"`
+	Annotations []annotation `json:"annotations,omitempty"`
+	Estimate    string       `json:"estimate,omitempty"`
+}
+
+type annotation struct {
+	Entry       string `json:"entry,omitempty"`
+	Description string `json:"description"`
+}
+
+var priorities = map[string]string{"H": "A", "M": "B", "L": "C"}
+
+// Decoder reads a Taskwarrior export one task at a time, either a JSON
+// array or the older one-object-per-line form. Recurring templates are
+// skipped, since their pending instances are exported as tasks of their own.
+type Decoder struct {
+	reader *bufio.Reader
+	dec    *json.Decoder
+	now    time.Time
+	n      int
+
+	started bool
+	array   bool
+}
+
+func NewDecoder(r io.Reader) *Decoder {
+	br := bufio.NewReader(r)
+	return &Decoder{reader: br, dec: json.NewDecoder(br), now: time.Now()}
+}
+
+// Decode returns the next task, or io.EOF after the last one.
+func (d *Decoder) Decode() (*task.Task, error) {
+	if !d.started {
+		if err := d.start(); err != nil {
+			return nil, fmt.Errorf("failed to read Taskwarrior export: %w", err)
+		}
+	}
+
+	for {
+		if d.array && !d.dec.More() {
+			return nil, io.EOF
+		}
+
+		d.n++
+		var rec record
+		err := d.dec.Decode(&rec)
+		if !d.array && errors.Is(err, io.EOF) {
+			return nil, io.EOF
+		}
+		if err != nil {
+			return nil, fmt.Errorf("task %d: %w", d.n, err)
+		}
+		if rec.Status == "recurring" {
+			continue
+		}
+
+		t, err := rec.toTask(d.now)
+		if err != nil {
+			return nil, fmt.Errorf("task %d: %w", d.n, err)
+		}
+		return &t, nil
+	}
+}
+
+// start finds out which form the export is in and, for an array, reads
+// its opening bracket.
+func (d *Decoder) start() error {
+	d.started = true
+
+	array, err := startsWithArray(d.reader)
+	if err != nil {
+		return err
+	}
+	d.array = array
+	if array {
+		if _, err := d.dec.Token(); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// startsWithArray reports whether the first non-space byte in br opens a
+// JSON array, leaving it unread.
+func startsWithArray(br *bufio.Reader) (bool, error) {
+	for {
+		b, err := br.ReadByte()
+		if errors.Is(err, io.EOF) {
+			return false, nil
+		}
+		if err != nil {
+			return false, err
+		}
+		if strings.ContainsRune(" \t\r\n", rune(b)) {
+			continue
+		}
+		return b == '[', br.UnreadByte()
+	}
+}
+
+// Encoder writes tasks as a JSON array with one task per line, the way
+// `task export` does. Close ends the array and flushes the output.
+type Encoder struct {
+	writer *bufio.Writer
+	count  int
+}
+
+func NewEncoder(w io.Writer) *Encoder {
+	return &Encoder{writer: bufio.NewWriter(w)}
+}
+
+func (e *Encoder) Encode(t *task.Task) error {
+	data, err := json.Marshal(fromTask(t))
+	if err != nil {
+		return err
+	}
+
+	sep := ",\n"
+	if e.count == 0 {
+		sep = "[\n"
+	}
+	if _, err := e.writer.WriteString(sep); err != nil {
+		return err
+	}
+	if _, err := e.writer.Write(data); err != nil {
+		return err
+	}
+
+	e.count++
+	return nil
+}
+
+func (e *Encoder) Close() error {
+	end := "\n]\n"
+	if e.count == 0 {
+		end = "[\n]\n"
+	}
+	if _, err := e.writer.WriteString(end); err != nil {
+		return err
+	}
+	return e.writer.Flush()
+}
+
+func (rec *record) toTask(now time.Time) (task.Task, error) {
+	var t task.Task
+	var err error
+
+	if t.ID, err = uuid.Parse(rec.UUID); err != nil {
+		return t, fmt.Errorf("invalid uuid %q", rec.UUID)
+	}
+
+	t.Description = rec.Description
+	for _, a := range rec.Annotations {
+		annotation := task.Annotation{Text: a.Description}
+		if a.Entry != "" {
+			if annotation.CreatedAt, err = time.Parse(timeLayout, a.Entry); err != nil {
+				return t, fmt.Errorf("invalid annotation entry time %q", a.Entry)
+			}
+		}
+		t.Annotations = append(t.Annotations, annotation)
+	}
+
+	var end, wait time.Time
+	for _, field := range []struct {
+		name  string
+		value string
+		dst   *time.Time
+	}{
+		{"entry", rec.Entry, &t.CreatedAt},
+		{"modified", rec.Modified, &t.UpdatedAt},
+		{"due", rec.Due, &t.DueDate},
+		{"wait", rec.Wait, &wait},
+		{"end", rec.End, &end},
+	} {
+		if field.value == "" {
+			continue
+		}
+		if *field.dst, err = time.Parse(timeLayout, field.value); err != nil {
+			return t, fmt.Errorf("invalid %s time %q", field.name, field.value)
+		}
+	}
+
+	switch rec.Status {
+	case "pending", "":
+		// Taskwarrior 2.6 and later export waiting tasks as pending ones
+		// with a wait time.
+		switch {
+		case wait.After(now):
+			t.Status = task.StatusWaiting
+		case rec.Start != "":
+			t.Status = task.StatusInProgress
+		default:
+			t.Status = task.StatusTodo
+		}
+	case "waiting":
+		t.Status = task.StatusWaiting
+	case "completed":
+		t.Status = task.StatusDone
+		t.CompletedAt = end
+		if t.CompletedAt.IsZero() {
+			t.CompletedAt = now
+		}
+	case "deleted":
+		t.Status = task.StatusCancelled
+	default:
+		return t, fmt.Errorf("unknown status %q", rec.Status)
+	}
+
+	if rec.Priority != "" {
+		priority, ok := priorities[rec.Priority]
+		if !ok {
+			return t, fmt.Errorf("unknown priority %q", rec.Priority)
+		}
+		t.Priority = priority
+	}
+
+	// Taskwarrior allows spaces in project names, tasks do not.
+	t.Project = strings.Join(strings.Fields(rec.Project), "-")
+	t.Tags = rec.Tags
+
+	if rec.Estimate != "" {
+		if t.Estimate, err = utils.ParseExactDuration(rec.Estimate); err != nil {
+			return t, fmt.Errorf("invalid estimate %q", rec.Estimate)
+		}
+	}
+
+	if t.CreatedAt.IsZero() {
+		t.CreatedAt = now
+	}
+	t.FillUpdatedAt()
+
+	if err := t.Validate(); err != nil {
+		return t, err
+	}
+
+	return t, nil
+}
+
+func fromTask(t *task.Task) record {
+	rec := record{
+		UUID:        t.ID.String(),
+		Description: t.Description,
+		Entry:       formatTime(t.CreatedAt),
+		Modified:    formatTime(t.UpdatedAt),
+		Due:         formatTime(t.DueDate),
+		Project:     t.Project,
+		Tags:        t.Tags,
+	}
+
+	switch t.Status {
+	case task.StatusInProgress:
+		rec.Status = "pending"
+		rec.Start = rec.Modified
+	case task.StatusWaiting:
+		// Tasks don't keep a wait time, so Taskwarrior is left to treat
+		// the task as waiting without one.
+		rec.Status = "waiting"
+	case task.StatusDone:
+		rec.Status = "completed"
+		rec.End = formatTime(t.CompletedAt)
+	case task.StatusCancelled:
+		rec.Status = "deleted"
+		rec.End = rec.Modified
+	default:
+		rec.Status = "pending"
+	}
+
+	// Taskwarrior has three priorities; anything below C counts as low.
+	switch {
+	case t.Priority == "":
+	case t.Priority <= "A":
+		rec.Priority = "H"
+	case t.Priority == "B":
+		rec.Priority = "M"
+	default:
+		rec.Priority = "L"
+	}
+
+	for _, a := range t.Annotations {
+		rec.Annotations = append(rec.Annotations, annotation{
+			Entry:       formatTime(a.CreatedAt),
+			Description: a.Text,
+		})
+	}
+
+	if t.Estimate > 0 {
+		rec.Estimate = t.Estimate.String()
+	}
+
+	return rec
+}
+
+func formatTime(t time.Time) string {
+	if t.IsZero() {
+		return ""
+	}
+	return t.UTC().Format(timeLayout)
+}
@@ -0,0 +1,213 @@
+package taskwarrior_test
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/ncfex/tasks/internal/task"
+	"github.com/ncfex/tasks/internal/taskwarrior"
+)
+
+// export is the output of `task export` from Taskwarrior 2.6, with a
+// recurring template, one of its instances, and a waiting task exported as
+// pending with a wait time.
+const export = `[
+{"id":1,"description":"Pay rent","due":"20240601T000000Z","entry":"20240520T091500Z","modified":"20240521T101010Z","priority":"H","project":"Home Finance","status":"pending","uuid":"0f6a4b1e-2c1d-4d8e-9a3b-5c6d7e8f9a0b","tags":["bills"],"annotations":[{"entry":"20240521T101010Z","description":"landlord wants a bank transfer"},{"entry":"20240521T101500Z","description":"IBAN is in the lease"}],"urgency":14.2},
+{"id":2,"description":"Write report","entry":"20240520T091600Z","modified":"20240521T080000Z","start":"20240521T080000Z","status":"pending","uuid":"1a2b3c4d-5e6f-4a7b-8c9d-0e1f2a3b4c5d","urgency":5},
+{"id":3,"description":"Renew passport","entry":"20240520T091700Z","modified":"20240520T091700Z","status":"pending","wait":"20991231T000000Z","uuid":"2b3c4d5e-6f7a-4b8c-9d0e-1f2a3b4c5d6e","urgency":-2.1},
+{"id":4,"description":"Call the bank","entry":"20240520T091800Z","modified":"20240520T091800Z","status":"pending","wait":"20200101T000000Z","uuid":"3c4d5e6f-7a8b-4c9d-8e1f-2a3b4c5d6e7f","urgency":0.5},
+{"id":0,"description":"Buy milk","end":"20240521T120000Z","entry":"20240520T091900Z","modified":"20240521T120000Z","status":"completed","uuid":"4d5e6f7a-8b9c-4d0e-9f2a-3b4c5d6e7f8a","urgency":0},
+{"id":0,"description":"Old idea","end":"20240521T130000Z","entry":"20240520T092000Z","modified":"20240521T130000Z","status":"deleted","uuid":"5e6f7a8b-9c0d-4e1f-8a3b-4c5d6e7f8a9b","urgency":0},
+{"id":0,"description":"Water plants","due":"20240522T000000Z","entry":"20240520T092100Z","mask":"-","modified":"20240520T092100Z","recur":"weekly","status":"recurring","uuid":"6f7a8b9c-0d1e-4f2a-9b4c-5d6e7f8a9b0c","urgency":8.9},
+{"id":5,"description":"Water plants","due":"20240522T000000Z","entry":"20240520T092100Z","imask":0,"modified":"20240520T092100Z","parent":"6f7a8b9c-0d1e-4f2a-9b4c-5d6e7f8a9b0c","recur":"weekly","status":"pending","uuid":"7a8b9c0d-1e2f-4a3b-8c5d-6e7f8a9b0c1d","urgency":8.9}
+]
+`
+
+func at(s string) time.Time {
+	t, err := time.Parse("20060102T150405Z", s)
+	if err != nil {
+		panic(err)
+	}
+	return t
+}
+
+func TestDecodeExport(t *testing.T) {
+	tasks, err := decodeAll(taskwarrior.NewDecoder(strings.NewReader(export)))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var descriptions []string
+	for _, tk := range tasks {
+		descriptions = append(descriptions, tk.Description)
+	}
+	want := []string{"Pay rent", "Write report", "Renew passport", "Call the bank", "Buy milk", "Old idea", "Water plants"}
+	if !slices.Equal(descriptions, want) {
+		t.Fatalf("Decode() = %q, want %q", descriptions, want)
+	}
+
+	rent := tasks[0]
+	if rent.ID != uuid.MustParse("0f6a4b1e-2c1d-4d8e-9a3b-5c6d7e8f9a0b") || rent.Status != task.StatusTodo ||
+		rent.Priority != "A" || rent.Project != "Home-Finance" || !slices.Equal(rent.Tags, []string{"bills"}) ||
+		!rent.DueDate.Equal(at("20240601T000000Z")) || !rent.CreatedAt.Equal(at("20240520T091500Z")) || !rent.UpdatedAt.Equal(at("20240521T101010Z")) {
+		t.Errorf("Pay rent = %+v", rent)
+	}
+	wantAnnotations := []task.Annotation{
+		{CreatedAt: at("20240521T101010Z"), Text: "landlord wants a bank transfer"},
+		{CreatedAt: at("20240521T101500Z"), Text: "IBAN is in the lease"},
+	}
+	if !slices.EqualFunc(rent.Annotations, wantAnnotations, sameAnnotation) {
+		t.Errorf("Pay rent annotations = %+v, want %+v", rent.Annotations, wantAnnotations)
+	}
+
+	for i, status := range []task.Status{task.StatusTodo, task.StatusInProgress, task.StatusWaiting, task.StatusTodo, task.StatusDone, task.StatusCancelled, task.StatusTodo} {
+		if tasks[i].Status != status {
+			t.Errorf("%s status = %s, want %s", tasks[i].Description, tasks[i].Status, status)
+		}
+	}
+	if done := tasks[4]; !done.CompletedAt.Equal(at("20240521T120000Z")) {
+		t.Errorf("Buy milk completed at %s, want its end time", done.CompletedAt)
+	}
+}
+
+// Taskwarrior before 2.5 exported one object per line, without an array.
+func TestDecodeLineFormat(t *testing.T) {
+	lines := strings.TrimSuffix(strings.TrimPrefix(export, "[\n"), "]\n")
+	lines = strings.ReplaceAll(lines, "},\n", "}\n")
+
+	tasks, err := decodeAll(taskwarrior.NewDecoder(strings.NewReader(lines)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(tasks) != 7 {
+		t.Errorf("Decode() = %d tasks, want 7", len(tasks))
+	}
+}
+
+func sameAnnotation(a, b task.Annotation) bool {
+	return a.Text == b.Text && a.CreatedAt.Equal(b.CreatedAt)
+}
+
+func TestEncodeDecodeRoundTrip(t *testing.T) {
+	created := at("20240520T091500Z")
+	base := task.Task{
+		Description: "pay rent",
+		Status:      task.StatusTodo,
+		CreatedAt:   created,
+		UpdatedAt:   created.Add(time.Hour),
+	}
+
+	tests := []struct {
+		name   string
+		change func(t *task.Task)
+	}{
+		{"todo", func(t *task.Task) {}},
+		{"fields", func(t *task.Task) {
+			t.Priority = "B"
+			t.Project = "home"
+			t.Tags = []string{"bills", "monthly"}
+			t.DueDate = created.Add(48 * time.Hour)
+			t.Estimate = 90 * time.Minute
+		}},
+		{"estimate under a minute", func(t *task.Task) { t.Estimate = 10 * time.Second }},
+		{"estimate between minutes", func(t *task.Task) { t.Estimate = 90 * time.Second }},
+		{"annotations", func(t *task.Task) {
+			t.Annotations = []task.Annotation{
+				{CreatedAt: created.Add(time.Minute), Text: "landlord wants a bank transfer"},
+				{CreatedAt: created.Add(2 * time.Minute), Text: "IBAN -- in the lease"},
+			}
+		}},
+		{"in progress", func(t *task.Task) { t.Status = task.StatusInProgress }},
+		{"waiting", func(t *task.Task) {
+			t.Status = task.StatusWaiting
+			t.DueDate = created.Add(48 * time.Hour)
+		}},
+		{"done", func(t *task.Task) {
+			t.Status = task.StatusDone
+			t.CompletedAt = created.Add(time.Hour)
+		}},
+		{"cancelled", func(t *task.Task) { t.Status = task.StatusCancelled }},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			want := base
+			want.ID = uuid.New()
+			tt.change(&want)
+
+			var buf bytes.Buffer
+			if err := encodeAll(taskwarrior.NewEncoder(&buf), []task.Task{want}); err != nil {
+				t.Fatal(err)
+			}
+			if strings.Contains(buf.String(), `"wait"`) {
+				t.Errorf("Encode() wrote a wait time the task doesn't have:\n%s", buf.String())
+			}
+
+			tasks, err := decodeAll(taskwarrior.NewDecoder(&buf))
+			if err != nil {
+				t.Fatalf("Decode(%s): %v", buf.String(), err)
+			}
+			if len(tasks) != 1 {
+				t.Fatalf("Decode() = %d tasks, want 1", len(tasks))
+			}
+
+			got := tasks[0]
+			switch {
+			case got.ID != want.ID:
+				t.Errorf("ID = %s, want %s", got.ID, want.ID)
+			case got.Description != want.Description:
+				t.Errorf("Description = %q, want %q", got.Description, want.Description)
+			case got.Status != want.Status:
+				t.Errorf("Status = %q, want %q", got.Status, want.Status)
+			case got.Priority != want.Priority:
+				t.Errorf("Priority = %q, want %q", got.Priority, want.Priority)
+			case got.Project != want.Project:
+				t.Errorf("Project = %q, want %q", got.Project, want.Project)
+			case !slices.Equal(got.Tags, want.Tags):
+				t.Errorf("Tags = %v, want %v", got.Tags, want.Tags)
+			case !slices.EqualFunc(got.Annotations, want.Annotations, sameAnnotation):
+				t.Errorf("Annotations = %+v, want %+v", got.Annotations, want.Annotations)
+			case got.Estimate != want.Estimate:
+				t.Errorf("Estimate = %s, want %s", got.Estimate, want.Estimate)
+			case !got.CreatedAt.Equal(want.CreatedAt):
+				t.Errorf("CreatedAt = %s, want %s", got.CreatedAt, want.CreatedAt)
+			case !got.UpdatedAt.Equal(want.UpdatedAt):
+				t.Errorf("UpdatedAt = %s, want %s", got.UpdatedAt, want.UpdatedAt)
+			case !got.DueDate.Equal(want.DueDate):
+				t.Errorf("DueDate = %s, want %s", got.DueDate, want.DueDate)
+			case !got.CompletedAt.Equal(want.CompletedAt):
+				t.Errorf("CompletedAt = %s, want %s", got.CompletedAt, want.CompletedAt)
+			}
+		})
+	}
+}
+
+func encodeAll(enc *taskwarrior.Encoder, tasks []task.Task) error {
+	for i := range tasks {
+		if err := enc.Encode(&tasks[i]); err != nil {
+			return err
+		}
+	}
+	return enc.Close()
+}
+
+// decodeAll reads every task dec hands out.
+func decodeAll(dec *taskwarrior.Decoder) ([]task.Task, error) {
+	var tasks []task.Task
+	for {
+		t, err := dec.Decode()
+		if errors.Is(err, io.EOF) {
+			return tasks, nil
+		}
+		if err != nil {
+			return nil, err
+		}
+		tasks = append(tasks, *t)
+	}
+}
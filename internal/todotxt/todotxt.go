@@ -0,0 +1,321 @@
+// Package todotxt reads and writes tasks in the todo.txt format, one task
+// per line:
+//
+//	x 2024-05-02 2024-05-01 (A) call the plumber +house @phone due:2024-05-03
+//
+// Priority, +project, @context and due: map onto the task fields of the same
+// meaning, and a leading x marks a closed task. Fields todo.txt has no place
+// for are kept as key:value tags (id:, status:, estimate:, and pri: on
+// closed tasks) so that exported files import back unchanged, apart from
+// times being cut to the day.
+//
+// Descriptions are written as they are, except that a backslash escapes
+// what todo.txt would read differently: a word that looks like a +project,
+// @context or key:value tag is text if it starts with a backslash, and \\,
+// \n, \r and \t stand for a backslash and the line break and tab
+// characters, and \s for a space the description starts with.
+package todotxt
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"regexp"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/ncfex/tasks/internal/task"
+	"github.com/ncfex/tasks/internal/utils"
+)
+
+const dateLayout = "2006-01-02"
+
+var priorityPattern = regexp.MustCompile(`^\([A-Z]\)$`)
+
+// Decoder reads tasks one line at a time. Blank lines are skipped; a line
+// that does not make a valid task is an error naming its line number. Tasks
+// without an id: tag are returned without an ID.
+type Decoder struct {
+	scanner *bufio.Scanner
+	line    int
+	now     time.Time
+}
+
+func NewDecoder(r io.Reader) *Decoder {
+	return &Decoder{scanner: bufio.NewScanner(r), now: time.Now()}
+}
+
+// Decode returns the next task, or io.EOF after the last one.
+func (d *Decoder) Decode() (*task.Task, error) {
+	for d.scanner.Scan() {
+		d.line++
+		text := strings.TrimSpace(d.scanner.Text())
+		if text == "" {
+			continue
+		}
+
+		t, err := parseLine(text, d.now)
+		if err != nil {
+			return nil, fmt.Errorf("line %d: %w", d.line, err)
+		}
+		return &t, nil
+	}
+	if err := d.scanner.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read todo.txt: %w", err)
+	}
+
+	return nil, io.EOF
+}
+
+// Encoder writes one line per task. Close flushes the output.
+type Encoder struct {
+	writer *bufio.Writer
+}
+
+func NewEncoder(w io.Writer) *Encoder {
+	return &Encoder{writer: bufio.NewWriter(w)}
+}
+
+func (e *Encoder) Encode(t *task.Task) error {
+	_, err := fmt.Fprintln(e.writer, formatLine(t))
+	return err
+}
+
+func (e *Encoder) Close() error {
+	return e.writer.Flush()
+}
+
+func parseLine(line string, now time.Time) (task.Task, error) {
+	t := task.Task{Status: task.StatusTodo}
+	// Words are split on single spaces so that the description keeps its
+	// spacing; the markers before it may be separated by several.
+	words := skipBlank(strings.Split(line, " "))
+
+	closed := words[0] == "x"
+	var completedAt time.Time
+	if closed {
+		words = skipBlank(words[1:])
+		if date, ok := parseDate(words); ok {
+			completedAt = date
+			words = skipBlank(words[1:])
+			// A creation date is only allowed after a completion date.
+			if date, ok := parseDate(words); ok {
+				t.CreatedAt = date
+				words = skipBlank(words[1:])
+			}
+		}
+	} else {
+		if len(words) > 0 && priorityPattern.MatchString(words[0]) {
+			t.Priority = words[0][1:2]
+			words = skipBlank(words[1:])
+		}
+		if date, ok := parseDate(words); ok {
+			t.CreatedAt = date
+			words = skipBlank(words[1:])
+		}
+	}
+
+	status := task.StatusTodo
+	if closed {
+		status = task.StatusDone
+	}
+
+	// A task has a single project, so only the last +project is taken,
+	// which is where Encode writes it; earlier ones stay in the text.
+	var description []string
+	projectAt := -1
+	for _, word := range words {
+		switch {
+		case strings.HasPrefix(word, `\`) && isMarkup(word[1:]):
+			description = append(description, unescape(word[1:]))
+		case strings.HasPrefix(word, `\`):
+			description = append(description, unescape(word))
+		case len(word) > 1 && word[0] == '+':
+			projectAt = len(description)
+			description = append(description, word)
+		case len(word) > 1 && word[0] == '@':
+			if !slices.Contains(t.Tags, word[1:]) {
+				t.Tags = append(t.Tags, word[1:])
+			}
+		default:
+			handled, err := parseTag(&t, &status, word)
+			if err != nil {
+				return t, err
+			}
+			if !handled {
+				description = append(description, unescape(word))
+			}
+		}
+	}
+
+	if projectAt >= 0 {
+		t.Project = description[projectAt][1:]
+		description = slices.Delete(description, projectAt, projectAt+1)
+	}
+	t.Description = strings.Join(description, " ")
+	t.Status = status
+	if t.CreatedAt.IsZero() {
+		t.CreatedAt = now
+	}
+	if status == task.StatusDone {
+		t.CompletedAt = completedAt
+		if t.CompletedAt.IsZero() {
+			t.CompletedAt = now
+		}
+	}
+	t.FillUpdatedAt()
+
+	if err := t.Validate(); err != nil {
+		return t, err
+	}
+
+	return t, nil
+}
+
+// parseTag applies a key:value tag this package understands and reports
+// whether word was one. Other tags stay part of the description.
+func parseTag(t *task.Task, status *task.Status, word string) (bool, error) {
+	if !isTag(word) {
+		return false, nil
+	}
+	key, value, _ := strings.Cut(word, ":")
+
+	var err error
+	switch key {
+	case "due":
+		if t.DueDate, err = time.ParseInLocation(dateLayout, value, time.Local); err != nil {
+			return false, fmt.Errorf("invalid due date %q", value)
+		}
+	case "id":
+		if t.ID, err = uuid.Parse(value); err != nil {
+			return false, fmt.Errorf("invalid id %q", value)
+		}
+	case "status":
+		if *status, err = task.ParseStatus(value); err != nil {
+			return false, err
+		}
+	case "estimate":
+		if t.Estimate, err = utils.ParseExactDuration(value); err != nil {
+			return false, fmt.Errorf("invalid estimate %q", value)
+		}
+	case "pri":
+		t.Priority = strings.ToUpper(value)
+	}
+
+	return true, nil
+}
+
+// isTag reports whether word is a key:value tag parseTag applies.
+func isTag(word string) bool {
+	key, value, ok := strings.Cut(word, ":")
+	if !ok || value == "" {
+		return false
+	}
+
+	switch key {
+	case "due", "id", "status", "estimate", "pri":
+		return true
+	}
+	return false
+}
+
+// isMarkup reports whether Decode would take word for a +project,
+// @context or tag rather than text.
+func isMarkup(word string) bool {
+	return len(word) > 1 && (word[0] == '+' || word[0] == '@') || isTag(word)
+}
+
+// skipBlank drops the empty words left by repeated spaces at the start of
+// words.
+func skipBlank(words []string) []string {
+	for len(words) > 0 && words[0] == "" {
+		words = words[1:]
+	}
+	return words
+}
+
+func parseDate(words []string) (time.Time, bool) {
+	if len(words) == 0 {
+		return time.Time{}, false
+	}
+	date, err := time.ParseInLocation(dateLayout, words[0], time.Local)
+	return date, err == nil
+}
+
+func formatLine(t *task.Task) string {
+	var words []string
+
+	closed := t.Status.IsClosed()
+	if closed {
+		completedAt := t.CompletedAt
+		if completedAt.IsZero() {
+			completedAt = t.UpdatedAt
+		}
+		words = append(words, "x", formatDate(completedAt), formatDate(t.CreatedAt))
+	} else {
+		if t.Priority != "" {
+			words = append(words, "("+t.Priority+")")
+		}
+		words = append(words, formatDate(t.CreatedAt))
+	}
+
+	if t.Description != "" {
+		words = append(words, escape(t.Description))
+	}
+	if t.Project != "" {
+		words = append(words, "+"+t.Project)
+	}
+	for _, tag := range t.Tags {
+		words = append(words, "@"+tag)
+	}
+
+	if !t.DueDate.IsZero() {
+		words = append(words, "due:"+formatDate(t.DueDate))
+	}
+	if t.Estimate > 0 {
+		words = append(words, "estimate:"+t.Estimate.String())
+	}
+	if t.Status != task.StatusTodo && t.Status != task.StatusDone {
+		words = append(words, "status:"+string(t.Status))
+	}
+	if closed && t.Priority != "" {
+		words = append(words, "pri:"+t.Priority)
+	}
+	words = append(words, "id:"+t.ID.String())
+
+	return strings.Join(words, " ")
+}
+
+var escaper = strings.NewReplacer(`\`, `\\`, "\n", `\n`, "\r", `\r`, "\t", `\t`)
+
+// escape writes description on one line, marking the words Decode would
+// not take as text.
+func escape(description string) string {
+	escaped := escaper.Replace(description)
+	// Spaces before the first word would be taken for separators.
+	if strings.HasPrefix(escaped, " ") {
+		escaped = `\s` + escaped[1:]
+	}
+
+	words := strings.Split(escaped, " ")
+	for i, word := range words {
+		if isMarkup(word) {
+			words[i] = `\` + word
+		}
+	}
+	return strings.Join(words, " ")
+}
+
+var unescaper = strings.NewReplacer(`\\`, `\`, `\n`, "\n", `\r`, "\r", `\t`, "\t", `\s`, " ")
+
+// unescape reverses escaper for one word. Other backslashes, as in a path
+// written by hand, are kept.
+func unescape(word string) string {
+	return unescaper.Replace(word)
+}
+
+func formatDate(t time.Time) string {
+	return t.Local().Format(dateLayout)
+}
@@ -0,0 +1,178 @@
+package todotxt_test
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/ncfex/tasks/internal/task"
+	"github.com/ncfex/tasks/internal/todotxt"
+)
+
+func day(s string) time.Time {
+	t, err := time.ParseInLocation("2006-01-02", s, time.Local)
+	if err != nil {
+		panic(err)
+	}
+	return t
+}
+
+func TestEncodeDecodeRoundTrip(t *testing.T) {
+	base := task.Task{
+		Status:    task.StatusTodo,
+		CreatedAt: day("2024-05-01"),
+		UpdatedAt: day("2024-05-01"),
+	}
+
+	tests := []struct {
+		name   string
+		change func(t *task.Task)
+	}{
+		{"plain", func(t *task.Task) { t.Description = "call the plumber" }},
+		{"fields", func(t *task.Task) {
+			t.Description = "call the plumber"
+			t.Priority = "A"
+			t.Project = "house"
+			t.Tags = []string{"phone", "errands"}
+			t.DueDate = day("2024-05-03")
+			t.Estimate = 90 * time.Minute
+		}},
+		{"estimate under a minute", func(t *task.Task) {
+			t.Description = "water the plants"
+			t.Estimate = 10 * time.Second
+		}},
+		{"estimate between minutes", func(t *task.Task) {
+			t.Description = "water the plants"
+			t.Estimate = 90 * time.Second
+		}},
+		{"in progress", func(t *task.Task) {
+			t.Description = "paint the fence"
+			t.Status = task.StatusInProgress
+		}},
+		{"done", func(t *task.Task) {
+			t.Description = "paint the fence"
+			t.Status = task.StatusDone
+			t.Priority = "B"
+			t.CompletedAt = day("2024-05-02")
+			t.UpdatedAt = day("2024-05-02")
+		}},
+		{"project and context words", func(t *task.Task) { t.Description = "email +1 reviewers @ noon about C++ and @home" }},
+		{"project word with a project", func(t *task.Task) {
+			t.Description = "move +house"
+			t.Project = "garden"
+		}},
+		{"tag words", func(t *task.Task) { t.Description = "due:soon status:blocked id:42 pri:high estimate:lots" }},
+		{"spacing", func(t *task.Task) { t.Description = "  two\tcolumns  and a\nsecond line " }},
+		{"backslashes", func(t *task.Task) { t.Description = `C:\tmp\new \+x \\ \s trailing\` }},
+		{"markers first", func(t *task.Task) { t.Description = "x (A) 2024-01-01 first" }},
+		{"closed with markers first", func(t *task.Task) {
+			t.Description = "x 2024-01-01 first"
+			t.Status = task.StatusCancelled
+			t.UpdatedAt = day("2024-05-02")
+		}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			want := base
+			want.ID = uuid.New()
+			tt.change(&want)
+
+			var buf bytes.Buffer
+			if err := encodeAll(todotxt.NewEncoder(&buf), []task.Task{want}); err != nil {
+				t.Fatal(err)
+			}
+			if lines := strings.Count(buf.String(), "\n"); lines != 1 {
+				t.Fatalf("Encode() wrote %d lines, want 1:\n%s", lines, buf.String())
+			}
+
+			tasks, err := decodeAll(todotxt.NewDecoder(&buf))
+			if err != nil {
+				t.Fatalf("Decode(%q): %v", buf.String(), err)
+			}
+			if len(tasks) != 1 {
+				t.Fatalf("Decode() = %d tasks, want 1", len(tasks))
+			}
+			assertSame(t, &tasks[0], &want)
+		})
+	}
+}
+
+func assertSame(t *testing.T, got, want *task.Task) {
+	t.Helper()
+	switch {
+	case got.ID != want.ID:
+		t.Errorf("ID = %s, want %s", got.ID, want.ID)
+	case got.Description != want.Description:
+		t.Errorf("Description = %q, want %q", got.Description, want.Description)
+	case got.Status != want.Status:
+		t.Errorf("Status = %q, want %q", got.Status, want.Status)
+	case got.Priority != want.Priority:
+		t.Errorf("Priority = %q, want %q", got.Priority, want.Priority)
+	case got.Project != want.Project:
+		t.Errorf("Project = %q, want %q", got.Project, want.Project)
+	case !slices.Equal(got.Tags, want.Tags):
+		t.Errorf("Tags = %v, want %v", got.Tags, want.Tags)
+	case got.Estimate != want.Estimate:
+		t.Errorf("Estimate = %s, want %s", got.Estimate, want.Estimate)
+	case !got.CreatedAt.Equal(want.CreatedAt):
+		t.Errorf("CreatedAt = %s, want %s", got.CreatedAt, want.CreatedAt)
+	case !got.DueDate.Equal(want.DueDate):
+		t.Errorf("DueDate = %s, want %s", got.DueDate, want.DueDate)
+	case !got.CompletedAt.Equal(want.CompletedAt):
+		t.Errorf("CompletedAt = %s, want %s", got.CompletedAt, want.CompletedAt)
+	}
+}
+
+func TestDecodeHandWritten(t *testing.T) {
+	input := "(A)  2024-05-01 call mom +family @phone due:2024-05-03\n" +
+		`x  2024-05-02 2024-05-01 back up C:\work +it` + "\n"
+
+	tasks, err := decodeAll(todotxt.NewDecoder(strings.NewReader(input)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(tasks) != 2 {
+		t.Fatalf("Decode() = %d tasks, want 2", len(tasks))
+	}
+
+	call := tasks[0]
+	if call.Description != "call mom" || call.Priority != "A" || call.Project != "family" ||
+		!slices.Equal(call.Tags, []string{"phone"}) || !call.CreatedAt.Equal(day("2024-05-01")) || !call.DueDate.Equal(day("2024-05-03")) {
+		t.Errorf("first task = %+v", call)
+	}
+
+	backup := tasks[1]
+	if backup.Description != `back up C:\work` || backup.Status != task.StatusDone || !backup.CompletedAt.Equal(day("2024-05-02")) {
+		t.Errorf("second task = %+v", backup)
+	}
+}
+
+func encodeAll(enc *todotxt.Encoder, tasks []task.Task) error {
+	for i := range tasks {
+		if err := enc.Encode(&tasks[i]); err != nil {
+			return err
+		}
+	}
+	return enc.Close()
+}
+
+// decodeAll reads every task dec hands out.
+func decodeAll(dec *todotxt.Decoder) ([]task.Task, error) {
+	var tasks []task.Task
+	for {
+		t, err := dec.Decode()
+		if errors.Is(err, io.EOF) {
+			return tasks, nil
+		}
+		if err != nil {
+			return nil, err
+		}
+		tasks = append(tasks, *t)
+	}
+}
@@ -2,6 +2,7 @@ package utils
 
 import (
 	"fmt"
+	"math"
 	"regexp"
 	"strconv"
 	"strings"
@@ -80,3 +81,97 @@ func ParseHumanToTime(humanTime string) (time.Time, error) {
 
 	return time.Time{}, fmt.Errorf("unable to parse time string: %s", humanTime)
 }
+
+var durationUnits = map[string]time.Duration{
+	"w": 7 * 24 * time.Hour,
+	"d": 24 * time.Hour,
+	"h": time.Hour,
+	"m": time.Minute,
+	"s": time.Second,
+}
+
+var durationUnitAliases = map[string]string{
+	"week": "w", "weeks": "w",
+	"day": "d", "days": "d",
+	"hour": "h", "hours": "h",
+	"min": "m", "mins": "m", "minute": "m", "minutes": "m",
+	"sec": "s", "secs": "s", "second": "s", "seconds": "s",
+}
+
+var (
+	durationPattern     = regexp.MustCompile(`^((\d+)([a-z]+))+$`)
+	durationPartPattern = regexp.MustCompile(`(\d+)([a-z]+)`)
+)
+
+// ParseDuration parses compact durations such as "2h", "1d4h" or "30 days".
+func ParseDuration(humanDuration string) (time.Duration, error) {
+	humanDuration = strings.ToLower(strings.ReplaceAll(humanDuration, " ", ""))
+	if humanDuration == "" {
+		return 0, fmt.Errorf("empty duration")
+	}
+
+	if !durationPattern.MatchString(humanDuration) {
+		return 0, fmt.Errorf("unable to parse duration: %s", humanDuration)
+	}
+
+	var total time.Duration
+	for _, part := range durationPartPattern.FindAllStringSubmatch(humanDuration, -1) {
+		unit := part[2]
+		if alias, ok := durationUnitAliases[unit]; ok {
+			unit = alias
+		}
+		size, ok := durationUnits[unit]
+		if !ok {
+			return 0, fmt.Errorf("unknown duration unit: %s", part[2])
+		}
+
+		quantity, err := strconv.ParseInt(part[1], 10, 64)
+		if err != nil || quantity > int64(math.MaxInt64/size) {
+			return 0, fmt.Errorf("duration too long: %s", humanDuration)
+		}
+		add := time.Duration(quantity) * size
+		if total > math.MaxInt64-add {
+			return 0, fmt.Errorf("duration too long: %s", humanDuration)
+		}
+		total += add
+	}
+
+	return total, nil
+}
+
+// ParseExactDuration parses durations written by time.Duration.String, as
+// files meant to be read back store them, and falls back to ParseDuration
+// for compact ones such as "1d4h".
+func ParseExactDuration(s string) (time.Duration, error) {
+	if d, err := time.ParseDuration(s); err == nil {
+		return d, nil
+	}
+	return ParseDuration(s)
+}
+
+func FormatDuration(d time.Duration) string {
+	if d == 0 {
+		return "-"
+	}
+
+	var b strings.Builder
+	if d < 0 {
+		b.WriteString("-")
+		d = -d
+	}
+
+	d = d.Round(time.Minute)
+	if d == 0 {
+		return "<1m"
+	}
+
+	for _, unit := range []string{"d", "h", "m"} {
+		size := durationUnits[unit]
+		if d >= size {
+			fmt.Fprintf(&b, "%d%s", d/size, unit)
+			d %= size
+		}
+	}
+
+	return b.String()
+}
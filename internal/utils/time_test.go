@@ -0,0 +1,102 @@
+package utils_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/ncfex/tasks/internal/utils"
+)
+
+func TestParseDuration(t *testing.T) {
+	tests := []struct {
+		in      string
+		want    time.Duration
+		wantErr bool
+	}{
+		{in: "2h", want: 2 * time.Hour},
+		{in: "1d4h", want: 28 * time.Hour},
+		{in: "30 days", want: 30 * 24 * time.Hour},
+		{in: "1w", want: 7 * 24 * time.Hour},
+		{in: "90 mins", want: 90 * time.Minute},
+		{in: "1H30M", want: 90 * time.Minute},
+		{in: "45s", want: 45 * time.Second},
+		{in: "", wantErr: true},
+		{in: "2", wantErr: true},
+		{in: "h", wantErr: true},
+		{in: "2x", wantErr: true},
+		{in: "-2h", wantErr: true},
+		{in: "2.5h", wantErr: true},
+		{in: "99999999999999999999h", wantErr: true},
+		{in: "3000000h", wantErr: true},
+		{in: "2000000h2000000h", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		got, err := utils.ParseDuration(tt.in)
+		if tt.wantErr {
+			if err == nil {
+				t.Errorf("ParseDuration(%q) = %v, want an error", tt.in, got)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("ParseDuration(%q): %v", tt.in, err)
+			continue
+		}
+		if got != tt.want {
+			t.Errorf("ParseDuration(%q) = %v, want %v", tt.in, got, tt.want)
+		}
+	}
+}
+
+func TestFormatDuration(t *testing.T) {
+	tests := []struct {
+		in   time.Duration
+		want string
+	}{
+		{in: 0, want: "-"},
+		{in: 20 * time.Second, want: "<1m"},
+		{in: 90 * time.Minute, want: "1h30m"},
+		{in: 28 * time.Hour, want: "1d4h"},
+		{in: 24 * time.Hour, want: "1d"},
+		{in: -2 * time.Hour, want: "-2h"},
+		{in: 59*time.Minute + 40*time.Second, want: "1h"},
+	}
+
+	for _, tt := range tests {
+		if got := utils.FormatDuration(tt.in); got != tt.want {
+			t.Errorf("FormatDuration(%v) = %q, want %q", tt.in, got, tt.want)
+		}
+	}
+}
+
+// Formatted durations read back as the same duration, to the minute.
+func TestFormatDurationRoundTrip(t *testing.T) {
+	for _, d := range []time.Duration{time.Minute, 90 * time.Minute, 28 * time.Hour, 15*24*time.Hour + 3*time.Minute} {
+		got, err := utils.ParseDuration(utils.FormatDuration(d))
+		if err != nil {
+			t.Errorf("ParseDuration(FormatDuration(%v)): %v", d, err)
+			continue
+		}
+		if got != d {
+			t.Errorf("ParseDuration(FormatDuration(%v)) = %v", d, got)
+		}
+	}
+}
+
+func TestParseExactDuration(t *testing.T) {
+	for _, d := range []time.Duration{10 * time.Second, 90 * time.Second, 90 * time.Minute, 1500 * time.Millisecond, 28 * time.Hour} {
+		got, err := utils.ParseExactDuration(d.String())
+		if err != nil {
+			t.Errorf("ParseExactDuration(%q): %v", d.String(), err)
+			continue
+		}
+		if got != d {
+			t.Errorf("ParseExactDuration(%q) = %v, want %v", d.String(), got, d)
+		}
+	}
+
+	if got, err := utils.ParseExactDuration("1d4h"); err != nil || got != 28*time.Hour {
+		t.Errorf("ParseExactDuration(%q) = %v, %v, want %v", "1d4h", got, err, 28*time.Hour)
+	}
+}